@@ -0,0 +1,104 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ResetHandle is the handle of a reset running in background, started by
+// ResetAsync.
+type ResetHandle struct {
+	run *asyncReset
+}
+
+// asyncReset is the state of a reset running in background. It's separated
+// from the ResetHandle, that the background goroutine doesn't reference, so
+// that an abandoned handle can be garbage collected and its reset canceled.
+type asyncReset struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	res    *ResetResult
+	err    error
+
+	mux sync.Mutex
+	// taken tells if the result has been retrieved by Result.
+	taken bool
+}
+
+// ResetAsync starts ResetWithOptions in background and returns immediately,
+// so that GUI tools can keep their UI responsive during the reset.
+func ResetAsync(portToTouch string, opts *ResetOptions) *ResetHandle {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &asyncReset{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	go func() {
+		defer close(run.done)
+		defer cancel()
+		run.res, run.err = resetWithContext(ctx, portToTouch, opts)
+	}()
+	h := &ResetHandle{run: run}
+	// Safety net for the handles abandoned without calling Close
+	runtime.SetFinalizer(h, func(h *ResetHandle) { go h.run.close() })
+	return h
+}
+
+// Done returns a channel that is closed when the reset is completed.
+func (h *ResetHandle) Done() <-chan struct{} {
+	return h.run.done
+}
+
+// Result waits for the reset to complete and returns its outcome, as
+// returned by ResetWithOptions. If the reset has been canceled the error is
+// context.Canceled.
+func (h *ResetHandle) Result() (*ResetResult, error) {
+	r := h.run
+	<-r.done
+	r.mux.Lock()
+	r.taken = true
+	r.mux.Unlock()
+	return r.res, r.err
+}
+
+// Cancel interrupts the wait for the bootloader and waits for the reset to
+// terminate. A reset already in progress (the touch itself) is completed
+// before returning.
+func (h *ResetHandle) Cancel() {
+	h.run.cancel()
+	<-h.run.done
+}
+
+// Close cancels the reset, as Cancel, and releases the Monitor lease of its
+// result if the result has not been retrieved with Result. The background
+// goroutine is terminated when Close returns.
+func (h *ResetHandle) Close() {
+	h.run.close()
+}
+
+func (r *asyncReset) close() {
+	r.cancel()
+	<-r.done
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if !r.taken && r.res != nil {
+		r.res.MonitorLease.Done("")
+	}
+	r.taken = true
+}
@@ -0,0 +1,52 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+// DefaultResetProfile is the profile used by AutoReset for the boards not
+// matching any registered profile: the 1200-bps touch followed by the wait
+// for the bootloader port.
+var DefaultResetProfile = &ResetProfile{
+	Name: "1200-bps touch",
+	Wait: true,
+}
+
+// AutoReset resets the board connected to the port with the strategy of the
+// reset profile matching its USB VID/PID, see AutoResetWithOptions.
+func AutoReset(port string) (*ResetResult, error) {
+	return AutoResetWithOptions(port, nil)
+}
+
+// AutoResetWithOptions looks up the USB VID/PID of the port, picks the reset
+// profile matching it from the registry (DefaultResetProfile if there are
+// none) and resets the board accordingly. The settings of the profile
+// override the corresponding ResetOptions (see ResetProfile.Apply). The name
+// of the profile used is reported in the Profile field of the result.
+func AutoResetWithOptions(port string, opts *ResetOptions) (*ResetResult, error) {
+	var resetOpts ResetOptions
+	if opts != nil {
+		resetOpts = *opts
+	}
+	profile := FindResetProfileForPort(resetOpts.lookupPortDetails(port))
+	if profile == nil {
+		profile = DefaultResetProfile
+	}
+	profile.Apply(&resetOpts)
+	res, err := ResetWithOptions(port, &resetOpts)
+	if res != nil {
+		res.Profile = profile.Name
+	}
+	return res, err
+}
@@ -0,0 +1,59 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "time"
+
+// PollBackoff defines how the interval between two polls of the port list
+// grows while waiting for the bootloader port: the first polls are done
+// quickly, to catch well-behaved boards early, then the interval grows up to
+// Max, to reduce the enumeration churn (that is expensive on Windows).
+type PollBackoff struct {
+	// Initial is the interval before the second poll, if zero the Initial
+	// interval of DefaultPollBackoff is used.
+	Initial time.Duration
+	// Max is the upper bound of the interval, zero for no bound.
+	Max time.Duration
+	// Factor is the multiplier applied to the interval after each poll, values
+	// lower than 1 are treated as 1 (constant interval).
+	Factor float64
+}
+
+// DefaultPollBackoff is the PollBackoff used if none is specified.
+var DefaultPollBackoff = PollBackoff{
+	Initial: 100 * time.Millisecond,
+	Max:     time.Second,
+	Factor:  1.5,
+}
+
+// initial returns the interval before the second poll.
+func (b *PollBackoff) initial() time.Duration {
+	if b.Initial <= 0 {
+		return DefaultPollBackoff.Initial
+	}
+	return b.Initial
+}
+
+// next returns the interval following the given one.
+func (b *PollBackoff) next(interval time.Duration) time.Duration {
+	if b.Factor > 1 {
+		interval = time.Duration(float64(interval) * b.Factor)
+	}
+	if b.Max > 0 && interval > b.Max {
+		interval = b.Max
+	}
+	return interval
+}
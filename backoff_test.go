@@ -0,0 +1,95 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils_test
+
+import (
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"github.com/arduino/go-serial-utils/serialutilstest"
+)
+
+// pollIntervals runs a reset whose bootloader port never shows up and returns
+// the intervals between the polls of the wait.
+func pollIntervals(t *testing.T, backoff *serialutils.PollBackoff) []time.Duration {
+	env := serialutilstest.NewEnvironment("/dev/ttyACM0").
+		RemovePortAt(0, "/dev/ttyACM0")
+	mapper := env.PortsMapper()
+	var polls []time.Time
+	opts := env.ResetOptions()
+	opts.Wait = true
+	opts.Timeout = 5 * time.Second
+	opts.PollBackoff = backoff
+	opts.PortsMapper = func() (map[string]bool, error) {
+		polls = append(polls, env.Clock().Now())
+		return mapper()
+	}
+	if _, err := serialutils.ResetWithOptions("/dev/ttyACM0", opts); err != nil {
+		t.Fatal(err)
+	}
+	// The first call lists the ports before the reset
+	var intervals []time.Duration
+	for i := 2; i < len(polls); i++ {
+		intervals = append(intervals, polls[i].Sub(polls[i-1]))
+	}
+	return intervals
+}
+
+func TestPollBackoff(t *testing.T) {
+	intervals := pollIntervals(t, &serialutils.PollBackoff{
+		Initial: 100 * time.Millisecond,
+		Max:     400 * time.Millisecond,
+		Factor:  2,
+	})
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond}
+	if len(intervals) < len(expected) {
+		t.Fatalf("too few polls: %v", intervals)
+	}
+	// The interval stays at Max until the timeout, the last one may be cut
+	// short by the timeout
+	for i, interval := range intervals[:len(intervals)-1] {
+		want := expected[len(expected)-1]
+		if i < len(expected) {
+			want = expected[i]
+		}
+		if interval != want {
+			t.Fatalf("poll intervals %v, want %v then %v", intervals, expected, want)
+		}
+	}
+}
+
+func TestPollBackoffDefaults(t *testing.T) {
+	// A zero Initial uses the default one, a zero Max doesn't cap the
+	// interval and a Factor lower than 1 keeps it constant
+	for _, test := range []struct {
+		backoff  *serialutils.PollBackoff
+		expected []time.Duration
+	}{
+		{&serialutils.PollBackoff{Factor: 2}, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 1600 * time.Millisecond}},
+		{&serialutils.PollBackoff{Initial: 300 * time.Millisecond, Factor: 0.5}, []time.Duration{300 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}},
+	} {
+		intervals := pollIntervals(t, test.backoff)
+		if len(intervals) < len(test.expected) {
+			t.Fatalf("%+v: too few polls: %v", test.backoff, intervals)
+		}
+		for i, want := range test.expected {
+			if intervals[i] != want {
+				t.Fatalf("%+v: poll intervals %v, want %v", test.backoff, intervals, test.expected)
+			}
+		}
+	}
+}
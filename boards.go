@@ -0,0 +1,121 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"strings"
+	"sync"
+)
+
+// BoardInfo is the board using a USB VID/PID, as returned by GuessBoard.
+type BoardInfo struct {
+	Name string `json:"name"`
+	// Bootloader tells if the VID/PID is the one of the bootloader of the
+	// board.
+	Bootloader bool `json:"bootloader,omitempty"`
+}
+
+func (b *BoardInfo) String() string {
+	if b.Bootloader {
+		return b.Name + " (bootloader)"
+	}
+	return b.Name
+}
+
+var boardsMux sync.RWMutex
+var boards = builtinBoards()
+
+// RegisterBoard adds the board using the USB VID/PID to the database used
+// by GuessBoard, replacing the board already known for it, if any.
+func RegisterBoard(id USBID, board BoardInfo) {
+	boardsMux.Lock()
+	defer boardsMux.Unlock()
+	boards[normalizeUSBID(id)] = board
+}
+
+// GuessBoard returns the board of the port, looked up by its USB VID/PID, or
+// nil if the board is not known.
+func GuessBoard(details PortDetails) *BoardInfo {
+	if !details.IsUSB {
+		return nil
+	}
+	boardsMux.RLock()
+	defer boardsMux.RUnlock()
+	board, ok := boards[normalizeUSBID(USBID{VID: details.VID, PID: details.PID})]
+	if !ok {
+		return nil
+	}
+	return &board
+}
+
+func normalizeUSBID(id USBID) USBID {
+	return USBID{VID: strings.ToUpper(id.VID), PID: strings.ToUpper(id.PID)}
+}
+
+func builtinBoards() map[USBID]BoardInfo {
+	res := map[USBID]BoardInfo{}
+	add := func(name string, ids ...USBID) {
+		for _, id := range ids {
+			res[id] = BoardInfo{Name: name}
+		}
+	}
+	addBootloader := func(name string, ids ...USBID) {
+		for _, id := range ids {
+			res[id] = BoardInfo{Name: name, Bootloader: true}
+		}
+	}
+	add("Arduino Uno", USBID{"2341", "0001"}, USBID{"2341", "0043"}, USBID{"2A03", "0043"}, USBID{"2341", "0243"})
+	add("Arduino Mega 2560", USBID{"2341", "0010"}, USBID{"2341", "0042"}, USBID{"2A03", "0042"}, USBID{"2341", "0242"})
+	add("Arduino Due (Programming Port)", USBID{"2341", "003D"})
+	add("Arduino Due (Native USB Port)", USBID{"2341", "003E"})
+	add("Arduino Nano Every", USBID{"2341", "0058"})
+	add("Arduino Uno R4 Minima", USBID{"2341", "0069"})
+	add("Arduino Uno R4 WiFi", USBID{"2341", "1002"})
+	add("Arduino Leonardo", USBID{"2341", "8036"})
+	addBootloader("Arduino Leonardo", USBID{"2341", "0036"})
+	add("Arduino Micro", USBID{"2341", "8037"})
+	addBootloader("Arduino Micro", USBID{"2341", "0037"})
+	add("Arduino Yún", USBID{"2341", "8041"})
+	addBootloader("Arduino Yún", USBID{"2341", "0041"})
+	add("Arduino Zero", USBID{"2341", "804D"})
+	addBootloader("Arduino Zero", USBID{"2341", "004D"})
+	add("Arduino MKR1000", USBID{"2341", "804E"})
+	addBootloader("Arduino MKR1000", USBID{"2341", "004E"})
+	add("Arduino MKR Zero", USBID{"2341", "804F"})
+	addBootloader("Arduino MKR Zero", USBID{"2341", "004F"})
+	add("Arduino MKR FOX 1200", USBID{"2341", "8050"})
+	addBootloader("Arduino MKR FOX 1200", USBID{"2341", "0050"})
+	add("Arduino MKR GSM 1400", USBID{"2341", "8052"})
+	addBootloader("Arduino MKR GSM 1400", USBID{"2341", "0052"})
+	add("Arduino MKR WAN 1300", USBID{"2341", "8053"})
+	addBootloader("Arduino MKR WAN 1300", USBID{"2341", "0053"})
+	add("Arduino MKR WiFi 1010", USBID{"2341", "8054"})
+	addBootloader("Arduino MKR WiFi 1010", USBID{"2341", "0054"})
+	add("Arduino MKR NB 1500", USBID{"2341", "8055"})
+	addBootloader("Arduino MKR NB 1500", USBID{"2341", "0055"})
+	add("Arduino Nano 33 IoT", USBID{"2341", "8057"})
+	addBootloader("Arduino Nano 33 IoT", USBID{"2341", "0057"})
+	add("Arduino Nano 33 BLE", USBID{"2341", "805A"})
+	addBootloader("Arduino Nano 33 BLE", USBID{"2341", "005A"})
+	add("Arduino Nano RP2040 Connect", USBID{"2341", "005E"}, USBID{"2341", "805E"})
+	add("Arduino Portenta H7", USBID{"2341", "025B"})
+	addBootloader("Arduino Portenta H7", USBID{"2341", "035B"})
+	add("Raspberry Pi Pico", USBID{"2E8A", "000A"})
+	addBootloader("RP2040", USBID{"2E8A", "0003"})
+	add("Teensy", USBID{"16C0", "0483"})
+	addBootloader("Teensy", USBID{"16C0", "0478"})
+	return res
+}
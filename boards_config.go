@@ -0,0 +1,211 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BoardDefinition describes a board: its name and the USB VID/PIDs of the
+// board and of its bootloader.
+type BoardDefinition struct {
+	Name          string  `json:"name"`
+	USBIDs        []USBID `json:"usbIDs"`
+	BootloaderIDs []USBID `json:"bootloaderIDs"`
+}
+
+// registeredBootloaderIDs are the bootloader IDs of the boards registered
+// with RegisterBoardDefinition.
+var registeredBootloaderIDs []USBID
+
+// RegisterBoardDefinition adds the board to the database used by
+// GuessBoard. Its bootloader IDs are used, along with the
+// KnownBootloaderIDs, to rank the candidate bootloader ports of the resets
+// whose ResetOptions don't specify any BootloaderIDs.
+func RegisterBoardDefinition(def *BoardDefinition) {
+	for _, id := range def.USBIDs {
+		RegisterBoard(id, BoardInfo{Name: def.Name})
+	}
+	for _, id := range def.BootloaderIDs {
+		RegisterBoard(id, BoardInfo{Name: def.Name, Bootloader: true})
+	}
+	boardsMux.Lock()
+	defer boardsMux.Unlock()
+	registeredBootloaderIDs = append(registeredBootloaderIDs, def.BootloaderIDs...)
+}
+
+// defaultBootloaderIDs returns the KnownBootloaderIDs along with the ones
+// of the registered boards.
+func defaultBootloaderIDs() []USBID {
+	boardsMux.RLock()
+	defer boardsMux.RUnlock()
+	if len(registeredBootloaderIDs) == 0 {
+		return KnownBootloaderIDs
+	}
+	return append(append([]USBID{}, KnownBootloaderIDs...), registeredBootloaderIDs...)
+}
+
+// boardsConfig is the format of the JSON boards definition files, for
+// example:
+//
+//	{
+//	  "boards": [
+//	    {
+//	      "name": "My board",
+//	      "usbIDs": ["1234:0001"],
+//	      "bootloaderIDs": ["1234:0002"]
+//	    }
+//	  ]
+//	}
+type boardsConfig struct {
+	Boards []*BoardDefinition `json:"boards"`
+}
+
+// ParseBoards parses a boards definition in JSON format.
+func ParseBoards(data []byte) ([]*BoardDefinition, error) {
+	var config boardsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("decoding boards: %w", err)
+	}
+	for i, def := range config.Boards {
+		if def.Name == "" {
+			return nil, fmt.Errorf("board %d: missing name", i)
+		}
+	}
+	return config.Boards, nil
+}
+
+// ParseBoardsTxt parses the boards definition of an Arduino core, in the
+// boards.txt format. The USB IDs of a board are taken from the "vid.N"/"pid.N"
+// and "upload_port.N.vid"/"upload_port.N.pid" properties: if the board
+// declares its own ID in "build.vid"/"build.pid", the other IDs are taken
+// as the IDs of its bootloader. The boards without USB IDs are skipped.
+func ParseBoardsTxt(data []byte) ([]*BoardDefinition, error) {
+	props := map[string]map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		board, key, ok := strings.Cut(strings.TrimSpace(key), ".")
+		if !ok || board == "menu" {
+			continue
+		}
+		if props[board] == nil {
+			props[board] = map[string]string{}
+		}
+		props[board][key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading boards.txt: %w", err)
+	}
+
+	res := []*BoardDefinition{}
+	for _, board := range sortedKeys(props) {
+		p := props[board]
+		name := p["name"]
+		if name == "" {
+			name = board
+		}
+		buildID, hasBuildID := boardsTxtUSBID(p["build.vid"], p["build.pid"])
+		def := &BoardDefinition{Name: name}
+		seen := map[USBID]bool{}
+		for _, keys := range boardsTxtUSBIDKeys(p) {
+			id, ok := boardsTxtUSBID(p[keys[0]], p[keys[1]])
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			if hasBuildID && id != buildID {
+				def.BootloaderIDs = append(def.BootloaderIDs, id)
+			} else {
+				def.USBIDs = append(def.USBIDs, id)
+			}
+		}
+		if len(def.USBIDs) > 0 || len(def.BootloaderIDs) > 0 {
+			res = append(res, def)
+		}
+	}
+	return res, nil
+}
+
+// boardsTxtUSBIDKeys returns the pairs of VID/PID property keys of a board,
+// in order.
+func boardsTxtUSBIDKeys(p map[string]string) [][2]string {
+	keys := []string{}
+	for key := range p {
+		if strings.HasPrefix(key, "vid.") || strings.HasPrefix(key, "upload_port.") && strings.HasSuffix(key, ".vid") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return NaturalLess(keys[i], keys[j]) })
+	res := [][2]string{}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "vid.") {
+			res = append(res, [2]string{key, "pid." + strings.TrimPrefix(key, "vid.")})
+		} else {
+			res = append(res, [2]string{key, strings.TrimSuffix(key, ".vid") + ".pid"})
+		}
+	}
+	return res
+}
+
+// boardsTxtUSBID parses a VID/PID pair in the boards.txt format (e.g.
+// "0x2341", "0x8036").
+func boardsTxtUSBID(vid, pid string) (USBID, bool) {
+	id, err := ParseUSBID(strings.TrimPrefix(strings.ToLower(vid), "0x") + ":" + strings.TrimPrefix(strings.ToLower(pid), "0x"))
+	return id, err == nil
+}
+
+// LoadBoards reads the boards definition from a file, in JSON format if its
+// extension is ".json" (see ParseBoards), in the boards.txt format
+// otherwise (see ParseBoardsTxt).
+func LoadBoards(path string) ([]*BoardDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading boards: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseBoards(data)
+	}
+	return ParseBoardsTxt(data)
+}
+
+// RegisterBoardsFromFile loads the boards definition from a file (see
+// LoadBoards) and adds the boards to the database, where they take
+// precedence over the built-in boards.
+func RegisterBoardsFromFile(path string) error {
+	defs, err := LoadBoards(path)
+	if err != nil {
+		return err
+	}
+	for _, def := range defs {
+		RegisterBoardDefinition(def)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BootloaderKind is the kind of bootloader identified on a port.
+type BootloaderKind int
+
+const (
+	// UnknownBootloader means that no known bootloader answered on the port.
+	UnknownBootloader BootloaderKind = iota
+	// AVR109Bootloader is an AVR109 (butterfly) bootloader, like Caterina on
+	// the Leonardo and Micro.
+	AVR109Bootloader
+	// STK500v1Bootloader is an STK500v1 bootloader, like Optiboot on the Uno.
+	STK500v1Bootloader
+	// STK500v2Bootloader is an STK500v2 bootloader, like the one of the Mega
+	// 2560.
+	STK500v2Bootloader
+	// SAMBABootloader is an Atmel SAM-BA bootloader, like the ROM bootloader
+	// of the SAM3X on the Due.
+	SAMBABootloader
+	// BOSSABootloader is the Arduino extended SAM-BA bootloader of the Zero
+	// and MKR boards, meant to be used with the BOSSA uploader.
+	BOSSABootloader
+)
+
+func (k BootloaderKind) String() string {
+	switch k {
+	case AVR109Bootloader:
+		return "avr109"
+	case STK500v1Bootloader:
+		return "stk500v1"
+	case STK500v2Bootloader:
+		return "stk500v2"
+	case SAMBABootloader:
+		return "sam-ba"
+	case BOSSABootloader:
+		return "bossa"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k BootloaderKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *BootloaderKind) UnmarshalText(text []byte) error {
+	for _, kind := range []BootloaderKind{UnknownBootloader, AVR109Bootloader, STK500v1Bootloader, STK500v2Bootloader, SAMBABootloader, BOSSABootloader} {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid bootloader kind: %s", text)
+}
+
+// BootloaderInfo is the result of the probe of a bootloader port.
+type BootloaderInfo struct {
+	// Kind is the kind of bootloader that answered on the port,
+	// UnknownBootloader if the port could not be verified.
+	Kind BootloaderKind `json:"kind"`
+	// Identifier is the identification string reported by the bootloader,
+	// if any (e.g. "CATERIN" for Caterina).
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// bootloaderHandshake is a minimal exchange identifying a bootloader
+// protocol.
+type bootloaderHandshake struct {
+	kind  BootloaderKind
+	probe *ExchangeProbe
+}
+
+var bootloaderHandshakes = []*bootloaderHandshake{
+	{
+		// SAM-BA answers "V#" with its version, terminated by "\n\r"
+		kind: SAMBABootloader,
+		probe: &ExchangeProbe{
+			BaudRate: 115200,
+			Request:  []byte("V#"),
+			Match: func(response []byte) (string, bool) {
+				end := bytes.Index(response, []byte("\n\r"))
+				if end == -1 {
+					return "", false
+				}
+				version := bytes.TrimSpace(response[:end])
+				if !bytes.HasPrefix(version, []byte("v")) && !bytes.Contains(version, []byte("SAM-BA")) {
+					return "", false
+				}
+				return string(version), true
+			},
+		},
+	},
+	{
+		// AVR109 answers "S" with its 7 characters software identifier
+		kind: AVR109Bootloader,
+		probe: &ExchangeProbe{
+			BaudRate: 57600,
+			Request:  []byte("S"),
+			Match: func(response []byte) (string, bool) {
+				if len(response) < 7 {
+					return "", false
+				}
+				for _, c := range response[:7] {
+					if c < 0x20 || c > 0x7E {
+						return "", false
+					}
+				}
+				return string(response[:7]), true
+			},
+		},
+	},
+	{
+		// STK500v1 answers GET_SYNC, CRC_EOP with STK_INSYNC, STK_OK
+		kind: STK500v1Bootloader,
+		probe: &ExchangeProbe{
+			BaudRate: 115200,
+			Request:  []byte{0x30, 0x20},
+			Match: func(response []byte) (string, bool) {
+				return "", bytes.Contains(response, []byte{0x14, 0x10})
+			},
+		},
+	},
+	{
+		// STK500v2 answers CMD_SIGN_ON with STATUS_CMD_OK and its name
+		kind: STK500v2Bootloader,
+		probe: &ExchangeProbe{
+			BaudRate: 115200,
+			Request:  stk500v2Message(0x01),
+			Match:    parseSTK500v2SignOn,
+		},
+	},
+}
+
+// stk500v2Message returns an STK500v2 message with the given body.
+func stk500v2Message(body ...byte) []byte {
+	msg := []byte{0x1B, 0x01, byte(len(body) >> 8), byte(len(body)), 0x0E}
+	msg = append(msg, body...)
+	checksum := byte(0)
+	for _, b := range msg {
+		checksum ^= b
+	}
+	return append(msg, checksum)
+}
+
+// parseSTK500v2SignOn parses the answer to the STK500v2 CMD_SIGN_ON, that is
+// MESSAGE_START, SEQ, SIZE, TOKEN, CMD_SIGN_ON, STATUS_CMD_OK, name length,
+// name and checksum.
+func parseSTK500v2SignOn(response []byte) (string, bool) {
+	start := bytes.IndexByte(response, 0x1B)
+	if start == -1 {
+		return "", false
+	}
+	msg := response[start:]
+	if len(msg) < 5 {
+		return "", false
+	}
+	size := int(msg[2])<<8 | int(msg[3])
+	if len(msg) < 5+size+1 {
+		return "", false
+	}
+	body := msg[5 : 5+size]
+	if msg[4] != 0x0E || size < 3 || body[0] != 0x01 || body[1] != 0x00 {
+		return "", false
+	}
+	nameLen := int(body[2])
+	if 3+nameLen > size {
+		return "", false
+	}
+	return string(body[3 : 3+nameLen]), true
+}
+
+// IdentifyBootloader speaks the minimal handshakes of the known bootloader
+// protocols (SAM-BA and its Arduino extended version used with BOSSA,
+// AVR109, STK500v1 and STK500v2) on the port to classify the bootloader
+// on the other end. UnknownBootloader is returned if none of them answered.
+// This is useful to pick the right port when many new ports appear after a
+// reset.
+func IdentifyBootloader(port string) (BootloaderKind, error) {
+	info, err := identifyBootloader(port, nil)
+	if err != nil {
+		return UnknownBootloader, err
+	}
+	return info.Kind, nil
+}
+
+// identifyBootloader tries the known bootloader handshakes on the port,
+// until one of them succeeds. An error is returned only if the port could
+// not be used at all.
+func identifyBootloader(port string, debug func(string)) (*BootloaderInfo, error) {
+	var lastErr error
+	failures := 0
+	for _, h := range bootloaderHandshakes {
+		identifier, ok, err := RunProbe(port, h.probe, time.Second)
+		if err != nil {
+			if debug != nil {
+				debug(fmt.Sprintf("PROBE %s: %v", h.kind, err))
+			}
+			lastErr = err
+			failures++
+			continue
+		}
+		if ok {
+			info := &BootloaderInfo{Kind: h.kind, Identifier: identifier}
+			// The Arduino extended SAM-BA reports itself as "Arduino
+			// Bootloader (SAM-BA extended)" followed by its capabilities
+			if h.kind == SAMBABootloader && (strings.Contains(identifier, "SAM-BA extended") || strings.Contains(identifier, "[Arduino:")) {
+				info.Kind = BOSSABootloader
+			}
+			if debug != nil {
+				debug(fmt.Sprintf("PROBE: %s bootloader found on %s", info.Kind, port))
+			}
+			return info, nil
+		}
+	}
+	if failures == len(bootloaderHandshakes) {
+		return nil, lastErr
+	}
+	if debug != nil {
+		debug(fmt.Sprintf("PROBE: no known bootloader found on %s", port))
+	}
+	return &BootloaderInfo{Kind: UnknownBootloader}, nil
+}
+
+// probeBootloader is like identifyBootloader but reports the errors as an
+// UnknownBootloader.
+func probeBootloader(port string, debug func(string)) *BootloaderInfo {
+	info, err := identifyBootloader(port, debug)
+	if err != nil {
+		return &BootloaderInfo{Kind: UnknownBootloader}
+	}
+	return info
+}
@@ -0,0 +1,151 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+func TestSTK500v2Message(t *testing.T) {
+	expected := []byte{0x1B, 0x01, 0x00, 0x01, 0x0E, 0x01, 0x1B ^ 0x01 ^ 0x01 ^ 0x0E ^ 0x01}
+	if msg := stk500v2Message(0x01); !bytes.Equal(msg, expected) {
+		t.Fatalf("got %x, want %x", msg, expected)
+	}
+}
+
+func TestParseSTK500v2SignOn(t *testing.T) {
+	signOn := stk500v2Message(0x01, 0x00, 0x08, 'A', 'V', 'R', 'I', 'S', 'P', '_', '2')
+	for _, test := range []struct {
+		name     string
+		response []byte
+		id       string
+		ok       bool
+	}{
+		{"valid", signOn, "AVRISP_2", true},
+		{"garbage before", append([]byte{0x00, 0xFF}, signOn...), "AVRISP_2", true},
+		{"truncated", signOn[:len(signOn)-2], "", false},
+		{"header only", signOn[:4], "", false},
+		{"no message start", []byte("AVRISP_2"), "", false},
+		{"wrong token", stk500v2MessageWithToken(0x0F, 0x01, 0x00, 0x01, 'A'), "", false},
+		{"failed status", stk500v2Message(0x01, 0xC0, 0x01, 'A'), "", false},
+		{"other command", stk500v2Message(0x02, 0x00, 0x01, 'A'), "", false},
+		{"name too long", stk500v2Message(0x01, 0x00, 0x09, 'A'), "", false},
+		{"empty name", stk500v2Message(0x01, 0x00, 0x00), "", true},
+	} {
+		id, ok := parseSTK500v2SignOn(test.response)
+		if id != test.id || ok != test.ok {
+			t.Errorf("%s: got %q, %v, want %q, %v", test.name, id, ok, test.id, test.ok)
+		}
+	}
+}
+
+// stk500v2MessageWithToken is stk500v2Message with a different token.
+func stk500v2MessageWithToken(token byte, body ...byte) []byte {
+	msg := stk500v2Message(body...)
+	msg[4] = token
+	return msg
+}
+
+func TestBootloaderHandshakesMatch(t *testing.T) {
+	match := map[BootloaderKind]func([]byte) (string, bool){}
+	for _, h := range bootloaderHandshakes {
+		match[h.kind] = h.probe.Match
+	}
+	for _, test := range []struct {
+		kind     BootloaderKind
+		response string
+		id       string
+		ok       bool
+	}{
+		{SAMBABootloader, "v1.1 Dec 15 2010 19:25:04\n\r", "v1.1 Dec 15 2010 19:25:04", true},
+		{SAMBABootloader, "Arduino Bootloader (SAM-BA extended) 2.0 [Arduino:IXV]\n\r", "Arduino Bootloader (SAM-BA extended) 2.0 [Arduino:IXV]", true},
+		{SAMBABootloader, "v1.1 Dec 15", "", false},
+		{SAMBABootloader, "hello world\n\r", "", false},
+		{AVR109Bootloader, "CATERIN", "CATERIN", true},
+		{AVR109Bootloader, "CATER", "", false},
+		{AVR109Bootloader, "CAT\x00RIN", "", false},
+		{STK500v1Bootloader, "\x14\x10", "", true},
+		{STK500v1Bootloader, "\x14", "", false},
+	} {
+		id, ok := match[test.kind]([]byte(test.response))
+		if id != test.id || ok != test.ok {
+			t.Errorf("%s %q: got %q, %v, want %q, %v", test.kind, test.response, id, ok, test.id, test.ok)
+		}
+	}
+}
+
+// bootloaderPort is a serial.Port answering the requests in replies.
+type bootloaderPort struct {
+	mux     sync.Mutex
+	replies map[string]string
+	pending []byte
+}
+
+func (p *bootloaderPort) Write(buff []byte) (int, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.pending = append(p.pending, p.replies[string(buff)]...)
+	return len(buff), nil
+}
+
+func (p *bootloaderPort) Read(buff []byte) (int, error) {
+	p.mux.Lock()
+	n := copy(buff, p.pending)
+	p.pending = p.pending[n:]
+	p.mux.Unlock()
+	if n == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return n, nil
+}
+
+func (p *bootloaderPort) SetMode(*serial.Mode) error         { return nil }
+func (p *bootloaderPort) Drain() error                       { return nil }
+func (p *bootloaderPort) ResetInputBuffer() error            { return nil }
+func (p *bootloaderPort) ResetOutputBuffer() error           { return nil }
+func (p *bootloaderPort) SetDTR(bool) error                  { return nil }
+func (p *bootloaderPort) SetRTS(bool) error                  { return nil }
+func (p *bootloaderPort) SetReadTimeout(time.Duration) error { return nil }
+func (p *bootloaderPort) Close() error                       { return nil }
+func (p *bootloaderPort) Break(time.Duration) error          { return nil }
+func (p *bootloaderPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestIdentifyBootloader(t *testing.T) {
+	for reply, kind := range map[string]BootloaderKind{
+		"v1.1 Dec 15 2010 19:25:04\n\r":                              SAMBABootloader,
+		"Arduino Bootloader (SAM-BA extended) 2.0 [Arduino:IXV]\n\r": BOSSABootloader,
+	} {
+		RegisterTransport("test-bootloader://", TransportFunc(func(string, *serial.Mode) (serial.Port, error) {
+			return &bootloaderPort{replies: map[string]string{"V#": reply}}, nil
+		}))
+		got, err := IdentifyBootloader("test-bootloader://board")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != kind {
+			t.Errorf("%q: identified %s, want %s", reply, got, kind)
+		}
+	}
+	RegisterTransport("test-bootloader://", nil)
+}
@@ -0,0 +1,139 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// BridgeQuirks adjusts the 1200-bps touch for the boards using a USB-serial
+// bridge chip, whose drivers and reset circuits differ from the native USB
+// CDC boards. The quirks are applied automatically by the touch, unless
+// TouchOptions.DisableQuirks is set: the built-in ones, working around the
+// Windows drivers, only on Windows, the ones registered with
+// RegisterBridgeQuirks on all the platforms.
+type BridgeQuirks struct {
+	// Name is the name of the bridge chip.
+	Name string
+	// DTR and RTS replace the handling of the modem lines, when the
+	// TouchOptions select the platform default. DTRPlatformDefault and
+	// RTSPlatformDefault leave the default unchanged.
+	DTR DTRMode
+	RTS RTSMode
+	// BaudRate replaces the 1200 bps of the touch, for the chips that can't
+	// be set at 1200 bps (or round it badly): the boards behind a bridge
+	// are reset by the modem lines, regardless of the baud rate. Zero keeps
+	// 1200 bps.
+	BaudRate int
+	// HoldTime is an extra delay between the change of the modem lines and
+	// the close of the port, for the drivers that may drop the last change
+	// if the port is closed right away.
+	HoldTime time.Duration
+}
+
+// builtinBridgeQuirks are the quirks of the Windows drivers of the bridges.
+var builtinBridgeQuirks = map[USBID]*BridgeQuirks{
+	{"0403", "6001"}: {Name: "FT232R", HoldTime: 20 * time.Millisecond},
+	{"0403", "6015"}: {Name: "FT231X", HoldTime: 20 * time.Millisecond},
+	{"067B", "2303"}: {Name: "PL2303", BaudRate: 9600},
+}
+
+var bridgeQuirksMux sync.RWMutex
+var bridgeQuirks = map[USBID]*BridgeQuirks{}
+
+// RegisterBridgeQuirks sets the quirks of the bridge chip with the given USB
+// ID, replacing the built-in ones if any: a nil quirks disables them.
+func RegisterBridgeQuirks(id USBID, quirks *BridgeQuirks) {
+	bridgeQuirksMux.Lock()
+	defer bridgeQuirksMux.Unlock()
+	bridgeQuirks[normalizeUSBID(id)] = quirks
+}
+
+// LookupBridgeQuirks returns the quirks of the bridge chip of the port, or
+// nil if there are none.
+func LookupBridgeQuirks(port *PortDetails) *BridgeQuirks {
+	if port == nil || !port.IsUSB {
+		return nil
+	}
+	id := normalizeUSBID(USBID{VID: port.VID, PID: port.PID})
+	bridgeQuirksMux.RLock()
+	defer bridgeQuirksMux.RUnlock()
+	if quirks, ok := bridgeQuirks[id]; ok {
+		return quirks
+	}
+	if runtime.GOOS == "windows" {
+		return builtinBridgeQuirks[id]
+	}
+	return nil
+}
+
+// touchSettings are the parameters of the touch of a port, resolved from
+// the TouchOptions and the quirks of its bridge chip.
+type touchSettings struct {
+	baudRate    int
+	deassertDTR bool
+	rts         RTSMode
+	holdTime    time.Duration
+}
+
+// resolveTouchSettings returns the parameters of the touch of the port, the
+// port details are obtained from the given mapper if the settings depend on
+// them.
+func resolveTouchSettings(port string, opts *TouchOptions, detailedPortsMapper DetailedPortsMapper) touchSettings {
+	var details *PortDetails
+	if port != "" && touchNeedsPortDetails(opts) {
+		details = lookupPortDetails(port, detailedPortsMapper)
+	}
+	dtr, rts := opts.DTR, opts.RTS
+	res := touchSettings{baudRate: 1200}
+	if quirks := LookupBridgeQuirks(details); quirks != nil && !opts.DisableQuirks {
+		if dtr == DTRPlatformDefault {
+			dtr = quirks.DTR
+		}
+		if rts == RTSPlatformDefault {
+			rts = quirks.RTS
+		}
+		if quirks.BaudRate != 0 {
+			res.baudRate = quirks.BaudRate
+		}
+		res.holdTime = quirks.HoldTime
+	}
+	res.deassertDTR = dtr.deassertDTR(details)
+	res.rts = rts.resolve(details)
+	return res
+}
+
+// touchNeedsPortDetails tells if the touch settings depend on the details of
+// the port, to skip their (possibly slow) enumeration otherwise: they are
+// needed to resolve the RTSDeassertWCH and, on Windows, the
+// DTRPlatformDefault and the built-in BridgeQuirks. On the other platforms
+// the quirks apply only if some have been registered.
+func touchNeedsPortDetails(opts *TouchOptions) bool {
+	if opts.RTS == RTSDeassertWCH {
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		return opts.DTR == DTRPlatformDefault || !opts.DisableQuirks
+	}
+	if opts.DisableQuirks {
+		return false
+	}
+	bridgeQuirksMux.RLock()
+	defer bridgeQuirksMux.RUnlock()
+	return len(bridgeQuirks) > 0
+}
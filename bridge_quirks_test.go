@@ -0,0 +1,64 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestTouchNeedsPortDetails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the port details are needed by the Windows defaults")
+	}
+	bridgeQuirksMux.Lock()
+	registered := bridgeQuirks
+	bridgeQuirks = map[USBID]*BridgeQuirks{}
+	bridgeQuirksMux.Unlock()
+	defer func() {
+		bridgeQuirksMux.Lock()
+		bridgeQuirks = registered
+		bridgeQuirksMux.Unlock()
+	}()
+
+	ftdi := &PortDetails{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001"}
+	if quirks := LookupBridgeQuirks(ftdi); quirks != nil {
+		t.Fatalf("built-in Windows quirks applied: %+v", quirks)
+	}
+	if touchNeedsPortDetails(&TouchOptions{}) {
+		t.Fatal("port details needed without quirks")
+	}
+	if !touchNeedsPortDetails(&TouchOptions{RTS: RTSDeassertWCH}) {
+		t.Fatal("port details not needed for RTSDeassertWCH")
+	}
+
+	RegisterBridgeQuirks(USBID{"0403", "6001"}, &BridgeQuirks{Name: "FT232R", RTS: RTSToggle})
+	if quirks := LookupBridgeQuirks(ftdi); quirks == nil || quirks.RTS != RTSToggle {
+		t.Fatalf("registered quirks not found: %+v", quirks)
+	}
+	if !touchNeedsPortDetails(&TouchOptions{}) {
+		t.Fatal("port details not needed with registered quirks")
+	}
+	if touchNeedsPortDetails(&TouchOptions{DisableQuirks: true}) {
+		t.Fatal("port details needed with the quirks disabled")
+	}
+	settings := resolveTouchSettings("/dev/ttyUSB0", &TouchOptions{}, func() (map[string]*PortDetails, error) {
+		return map[string]*PortDetails{"/dev/ttyUSB0": ftdi}, nil
+	})
+	if settings.rts != RTSToggle || settings.baudRate != 1200 {
+		t.Fatalf("registered quirks not applied: %+v", settings)
+	}
+}
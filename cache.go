@@ -0,0 +1,69 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "sync"
+
+// NewCachedDetailedPortMapper returns a DetailedPortsMapper that avoids the
+// expensive detailed enumeration (SetupAPI on Windows, IOKit on macOS) when
+// nothing changed: at every call the cheap `names` mapper is used to list the
+// ports, and the `details` mapper is called only if the list differs from
+// the previous one. If nil, `names` and `details` default to
+// DefaultPortMapper and DefaultDetailedPortMapper.
+func NewCachedDetailedPortMapper(names PortsMapper, details DetailedPortsMapper) DetailedPortsMapper {
+	if names == nil {
+		names = DefaultPortMapper
+	}
+	if details == nil {
+		details = DefaultDetailedPortMapper
+	}
+	var mux sync.Mutex
+	var cache map[string]*PortDetails
+	return func() (map[string]*PortDetails, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		ports, err := names()
+		if err != nil {
+			return nil, err
+		}
+		if cache == nil || !sameDetailedPorts(ports, cache) {
+			if cache, err = details(); err != nil {
+				cache = nil
+				return nil, err
+			}
+		}
+		res := make(map[string]*PortDetails, len(cache))
+		for name, port := range cache {
+			details := *port
+			res[name] = &details
+		}
+		return res, nil
+	}
+}
+
+// sameDetailedPorts returns true if the detailed list contains exactly the
+// given ports.
+func sameDetailedPorts(ports map[string]bool, detailed map[string]*PortDetails) bool {
+	if len(ports) != len(detailed) {
+		return false
+	}
+	for name := range ports {
+		if _, ok := detailed[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// canonicalPortName returns a canonical form of the port name, identical
+// for all the aliases of the same port: the symlinks (like the ones in
+// /dev/serial/by-id) are resolved and, on the OS with case-insensitive port
+// names (Windows and macOS), the name is lowercased.
+func canonicalPortName(port string) string {
+	if strings.HasPrefix(port, "/") {
+		if resolved, err := filepath.EvalSymlinks(port); err == nil {
+			port = resolved
+		}
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		port = strings.ToLower(port)
+	}
+	return port
+}
+
+// canonicalPortsMapper wraps a PortsMapper so that all the aliases of a port
+// are reported with the same name: the first name seen for the port (or the
+// given known names, like the name of the port to touch). This prevents a
+// port that reappears with a different case, or as a symlinked alias, from
+// being reported as a new port. The aliases are resolved again on every poll,
+// so that a symlink removed or moved to another device is not reported in
+// place of the device name.
+func canonicalPortsMapper(mapper PortsMapper, knownNames ...string) PortsMapper {
+	var mux sync.Mutex
+	names := map[string]string{}
+	for _, name := range knownNames {
+		if name != "" {
+			names[canonicalPortName(name)] = name
+		}
+	}
+	return func() (map[string]bool, error) {
+		ports, err := mapper()
+		if err != nil {
+			return ports, err
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		// Every name is resolved at most once per poll
+		resolved := map[string]string{}
+		canonical := func(name string) string {
+			res, ok := resolved[name]
+			if !ok {
+				res = canonicalPortName(name)
+				resolved[name] = res
+			}
+			return res
+		}
+		res := map[string]bool{}
+		for port := range ports {
+			c := canonical(port)
+			name, ok := names[c]
+			if !ok || (name != port && canonical(name) != c) {
+				name = port
+				names[c] = name
+			}
+			res[name] = true
+		}
+		return res, nil
+	}
+}
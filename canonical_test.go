@@ -0,0 +1,93 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCanonicalPortName(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks not supported")
+	}
+	dir := t.TempDir()
+	device := filepath.Join(dir, "ttyACM0")
+	alias := filepath.Join(dir, "usb-Arduino_Leonardo-if00")
+	if err := os.WriteFile(device, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(device, alias); err != nil {
+		t.Fatal(err)
+	}
+	if canonicalPortName(alias) != canonicalPortName(device) {
+		t.Fatalf("alias %s and device %s have different canonical names", canonicalPortName(alias), canonicalPortName(device))
+	}
+	if got := canonicalPortName("rfc2217://host:4000"); got != "rfc2217://host:4000" {
+		t.Fatalf("network port changed to %s", got)
+	}
+}
+
+func TestCanonicalPortsMapper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks not supported")
+	}
+	dir := t.TempDir()
+	acm0 := filepath.Join(dir, "ttyACM0")
+	acm1 := filepath.Join(dir, "ttyACM1")
+	alias := filepath.Join(dir, "by-id")
+	for _, device := range []string{acm0, acm1} {
+		if err := os.WriteFile(device, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(acm0, alias); err != nil {
+		t.Fatal(err)
+	}
+	var ports map[string]bool
+	mapper := canonicalPortsMapper(func() (map[string]bool, error) { return ports, nil }, alias)
+	check := func(expected map[string]bool) {
+		t.Helper()
+		got, err := mapper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("got %v, want %v", got, expected)
+		}
+	}
+
+	// The device is reported under its known alias
+	ports = map[string]bool{acm0: true, acm1: true}
+	check(map[string]bool{alias: true, acm1: true})
+
+	// Once the alias is moved to another device, it's not reported in place
+	// of the first one anymore
+	if err := os.Remove(alias); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(acm1, alias); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]bool{acm0: true, acm1: true})
+
+	// The first name seen is kept for the aliases listed later
+	ports = map[string]bool{alias: true}
+	check(map[string]bool{acm1: true})
+}
@@ -0,0 +1,59 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"time"
+)
+
+// Clock abstracts the time functions used by the package, so that the
+// timing of the reset can be controlled in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep pauses for at least the duration d.
+	Sleep(d time.Duration)
+}
+
+// SystemClock is the Clock based on the system time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// sleepContext is like clock.Sleep but returns early if the context is
+// canceled. Only the SystemClock can be interrupted, the other clocks
+// always sleep for the whole duration.
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) {
+	if clock != SystemClock {
+		clock.Sleep(d)
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,83 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+// serial-list prints the list of the available serial ports with their
+// details (VID/PID, serial number, etc.) as a table or in JSON format.
+// With -bench it measures the cost of the enumeration instead.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "print the list in JSON format")
+	bench := flag.Int("bench", 0, "measure the average duration of `n` enumerations, names only and detailed")
+	flag.Parse()
+
+	if *bench > 0 {
+		benchmark("names", *bench, func() error {
+			_, err := serialutils.DefaultPortMapper()
+			return err
+		})
+		benchmark("detailed", *bench, func() error {
+			_, err := serialutils.DefaultDetailedPortMapper()
+			return err
+		})
+		return
+	}
+
+	list, err := serialutils.ListPorts()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(list, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Port\tVID\tPID\tSerial number\tProduct\tBoard")
+	for _, port := range list {
+		board := ""
+		if b := serialutils.GuessBoard(port); b != nil {
+			board = b.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", port.Name, port.VID, port.PID, port.SerialNumber, port.Product, board)
+	}
+	_ = w.Flush()
+}
+
+// benchmark prints the average duration of n calls to f.
+func benchmark(name string, n int, f func() error) {
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if err := f(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("%-10s %v/op\n", name, time.Since(start)/time.Duration(n))
+}
@@ -0,0 +1,179 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+// serial-reset resets a board using the 1200-bps touch and optionally waits
+// for the bootloader port to appear.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+func main() {
+	port := flag.String("port", "", "the port to touch (if empty the reset is skipped)")
+	wait := flag.Bool("wait", false, "wait for the bootloader port to appear")
+	timeout := flag.Duration("timeout", 0, "maximum time to wait for the bootloader port, if zero $SERIALUTILS_WAIT_TIMEOUT or the package default")
+	dryRun := flag.Bool("dry-run", false, "emulate the reset without touching any port")
+	script := flag.String("dry-run-script", "", "emulate the reset following the scenario in the given JSON file")
+	jsonOutput := flag.Bool("json", false, "print the result in JSON format")
+	verbose := flag.Bool("verbose", false, "print debugging messages on stderr")
+	latency := flag.Int("latency", 0, "measure the reset latency over `n` resets instead")
+	tracePath := flag.String("trace", "", "write the trace of the reset to the given JSON file")
+	replayPath := flag.String("replay", "", "replay the reset trace in the given JSON file instead")
+	recoverPort := flag.Bool("recover", false, "try escalating reset strategies until the board enters the bootloader instead")
+	powerCycle := flag.Bool("power-cycle", false, "with -recover, power-cycle the hub port of the board with uhubctl as a last resort")
+	recoverSAMBA := flag.Bool("recover-samba", false, "recover a SAMD board with a corrupted sketch instead, touching the new ports until its SAM-BA bootloader appears")
+	erase := flag.Bool("erase", false, "erase the sketch once the SAM-BA bootloader is recovered")
+	flag.Parse()
+
+	cb := &serialutils.ResetProgressCallbacks{}
+	if *verbose {
+		cb.Debug = func(msg string) { fmt.Fprintln(os.Stderr, msg) }
+	}
+	if *recoverPort {
+		strategies := serialutils.DefaultRecoveryStrategies
+		if *powerCycle {
+			strategies = append(strategies[:len(strategies):len(strategies)], serialutils.PowerCycleStrategy(nil))
+		}
+		report, err := serialutils.RecoverPortWithOptions(*port, &serialutils.RecoveryOptions{
+			Reset:      &serialutils.ResetOptions{Timeout: *timeout, Callbacks: cb},
+			Strategies: strategies,
+		})
+		if err != nil && !errors.Is(err, serialutils.ErrRecoveryFailed) {
+			fail(*jsonOutput, err)
+		}
+		if *jsonOutput {
+			output(report)
+		} else {
+			for _, attempt := range report.Attempts {
+				fmt.Printf("%-14s %s\n", attempt.Strategy+":", attemptOutcome(attempt))
+			}
+		}
+		if report.Result == nil {
+			os.Exit(2)
+		}
+		return
+	}
+	if *recoverSAMBA {
+		res, err := serialutils.RecoverSAMBA(&serialutils.SAMBARecoveryOptions{
+			Port:    *port,
+			Timeout: *timeout,
+			Erase:   *erase,
+			Debug:   cb.Debug,
+		})
+		if err != nil {
+			fail(*jsonOutput, err)
+		}
+		if *jsonOutput {
+			output(res)
+		} else if res.Port != "" {
+			fmt.Println(res.Port)
+		}
+		if res.Port == "" {
+			os.Exit(2)
+		}
+		return
+	}
+	if *latency > 0 {
+		report, err := serialutils.MeasureResetLatencyWithOptions(*port, nil, &serialutils.LatencyOptions{
+			Runs:    *latency,
+			Timeout: *timeout,
+			Debug:   cb.Debug,
+		})
+		if err != nil {
+			fail(*jsonOutput, err)
+		}
+		if *jsonOutput {
+			output(report)
+		} else {
+			fmt.Println("Disappear:  ", report.Disappear)
+			fmt.Println("Reenumerate:", report.Reenumerate)
+			fmt.Println("Failures:   ", report.Failures)
+		}
+		return
+	}
+
+	opts := &serialutils.ResetOptions{
+		Wait:      *wait,
+		Timeout:   *timeout,
+		Callbacks: cb,
+	}
+	if *replayPath != "" {
+		trace, err := serialutils.LoadResetTrace(*replayPath)
+		if err != nil {
+			fail(*jsonOutput, err)
+		}
+		opts.Simulator = trace.Simulator()
+		*port = trace.Port
+		*wait = true
+		opts.Wait = true
+	} else if *script != "" {
+		sim, err := serialutils.LoadSimulatorScript(*script)
+		if err != nil {
+			fail(*jsonOutput, err)
+		}
+		opts.Simulator = sim
+	} else if *dryRun {
+		opts.Simulator = &serialutils.Simulator{Scenario: serialutils.ScenarioNewPort}
+	}
+	if *tracePath != "" {
+		opts.Trace = &serialutils.ResetTrace{}
+	}
+	res, err := serialutils.ResetWithOptions(*port, opts)
+	if opts.Trace != nil {
+		if err := opts.Trace.WriteFile(*tracePath); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing the trace:", err)
+		}
+	}
+	if err != nil {
+		fail(*jsonOutput, err)
+	}
+
+	if *jsonOutput {
+		output(res)
+	} else if res.Target.Path != "" {
+		fmt.Println(res.Target.Path)
+	}
+	if *wait && res.Target.Kind == serialutils.NoTarget {
+		os.Exit(2)
+	}
+}
+
+func fail(jsonOutput bool, err error) {
+	if jsonOutput {
+		output(map[string]string{"error": err.Error()})
+	} else {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	os.Exit(1)
+}
+
+func output(v any) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(data))
+}
+
+func attemptOutcome(attempt serialutils.RecoveryAttempt) string {
+	if attempt.Error != "" {
+		return attempt.Error
+	}
+	return attempt.Target.Path
+}
@@ -0,0 +1,165 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+//go:build serialutils_cmsisdap
+
+package serialutils
+
+import (
+	"fmt"
+	"time"
+)
+
+// The CMSIS-DAP support is opt-in, it's built only with the
+// serialutils_cmsisdap build tag. Linux hidraw is the only backend: the probes
+// can't be listed or used on the other platforms.
+
+func init() {
+	optionalStrategies["cmsis-dap"] = func() Resetter { return &CMSISDAPResetter{} }
+}
+
+// CMSISDAPProbe is a CMSIS-DAP debug probe (v1, HID) attached to the host.
+type CMSISDAPProbe struct {
+	// Path is the path of the HID device of the probe (e.g. /dev/hidraw3).
+	Path string
+	// Name is the product name of the probe, containing "CMSIS-DAP".
+	Name string
+	// SerialNumber is the USB serial number of the probe.
+	SerialNumber string
+	// Location is the physical location of the USB device of the probe, in
+	// the Linux sysfs format (e.g. "1-2.3").
+	Location string
+}
+
+// ListCMSISDAPProbes returns the CMSIS-DAP probes attached to the host. It's
+// supported only on Linux, where the probes are driven through hidraw.
+func ListCMSISDAPProbes() ([]*CMSISDAPProbe, error) {
+	return nativeListCMSISDAPProbes()
+}
+
+// CMSISDAPResetter is a Resetter for the boards whose MCU can be rebooted
+// only through the debug port: it pulses the nRESET line of the target
+// through an attached CMSIS-DAP probe. The bootloader is then entered as
+// after a hardware reset. The probes are driven through Linux hidraw, the
+// resets fail on the other platforms.
+type CMSISDAPResetter struct {
+	// SerialNumber selects the probe with the given USB serial number. If
+	// empty, the probe on the same USB device of the reset port is used (the
+	// on-board debuggers, like EDBG, expose both), or the only probe
+	// attached.
+	SerialNumber string
+	// Pulse is the time nRESET is kept asserted, 100 ms if zero.
+	Pulse time.Duration
+}
+
+// The CMSIS-DAP commands used by the CMSISDAPResetter.
+const (
+	dapConnect    = 0x02
+	dapDisconnect = 0x03
+	dapSWJPins    = 0x10
+
+	dapPortSWD   = 0x01
+	dapPinNReset = 0x80
+)
+
+// dapTransport exchanges the CMSIS-DAP commands with a probe.
+type dapTransport interface {
+	// transfer sends the request and returns the response.
+	transfer(req []byte) ([]byte, error)
+	Close() error
+}
+
+// Reset implements Resetter.
+func (r *CMSISDAPResetter) Reset(port string) error {
+	probe, err := r.Probe(port)
+	if err != nil {
+		return err
+	}
+	pulse := r.Pulse
+	if pulse == 0 {
+		pulse = 100 * time.Millisecond
+	}
+
+	dap, err := nativeOpenCMSISDAP(probe.Path)
+	if err != nil {
+		return fmt.Errorf("opening CMSIS-DAP probe %s: %w", probe.Path, err)
+	}
+	defer dap.Close()
+	command := func(req ...byte) ([]byte, error) {
+		res, err := dap.transfer(req)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) < 2 || res[0] != req[0] {
+			return nil, fmt.Errorf("invalid response to command 0x%02X", req[0])
+		}
+		return res, nil
+	}
+	setNReset := func(high bool) error {
+		var output byte
+		if high {
+			output = dapPinNReset
+		}
+		// No wait for the pin to settle, the pulse is timed by the host
+		if _, err := command(dapSWJPins, output, dapPinNReset, 0, 0, 0, 0); err != nil {
+			return fmt.Errorf("setting nRESET: %w", err)
+		}
+		return nil
+	}
+
+	if res, err := command(dapConnect, dapPortSWD); err != nil {
+		return fmt.Errorf("connecting to the target: %w", err)
+	} else if res[1] == 0 {
+		return fmt.Errorf("connecting to the target: SWD not supported by the probe")
+	}
+	defer command(dapDisconnect)
+	if err := setNReset(false); err != nil {
+		return err
+	}
+	time.Sleep(pulse)
+	return setNReset(true)
+}
+
+// Probe returns the probe used to reset the board connected to the given
+// port.
+func (r *CMSISDAPResetter) Probe(port string) (*CMSISDAPProbe, error) {
+	probes, err := ListCMSISDAPProbes()
+	if err != nil {
+		return nil, fmt.Errorf("listing CMSIS-DAP probes: %w", err)
+	}
+	if r.SerialNumber != "" {
+		for _, probe := range probes {
+			if probe.SerialNumber == r.SerialNumber {
+				return probe, nil
+			}
+		}
+		return nil, fmt.Errorf("CMSIS-DAP probe %s not found", r.SerialNumber)
+	}
+	if location := usbLocation(port); location != "" {
+		for _, probe := range probes {
+			if probe.Location == location {
+				return probe, nil
+			}
+		}
+	}
+	switch len(probes) {
+	case 0:
+		return nil, fmt.Errorf("no CMSIS-DAP probe found")
+	case 1:
+		return probes[0], nil
+	default:
+		return nil, fmt.Errorf("%d CMSIS-DAP probes found, select one by serial number", len(probes))
+	}
+}
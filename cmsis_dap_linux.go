@@ -0,0 +1,129 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+//go:build serialutils_cmsisdap
+
+package serialutils
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// dapReportSize is the size of the HID reports of the CMSIS-DAP v1 probes.
+const dapReportSize = 64
+
+// dapTimeout is the maximum time waited for the response of a probe, in ms.
+const dapTimeout = 1000
+
+// nativeListCMSISDAPProbes lists the hidraw devices whose product name
+// contains "CMSIS-DAP", as required by the CMSIS-DAP specification.
+func nativeListCMSISDAPProbes() ([]*CMSISDAPProbe, error) {
+	devices, err := filepath.Glob("/sys/class/hidraw/hidraw*")
+	if err != nil {
+		return nil, err
+	}
+	res := []*CMSISDAPProbe{}
+	for _, dev := range devices {
+		device, err := filepath.EvalSymlinks(filepath.Join(dev, "device"))
+		if err != nil {
+			continue
+		}
+		uevent, err := readUevent(filepath.Join(device, "uevent"))
+		if err != nil || !strings.Contains(uevent["HID_NAME"], "CMSIS-DAP") {
+			continue
+		}
+		probe := &CMSISDAPProbe{
+			Path:         filepath.Join("/dev", filepath.Base(dev)),
+			Name:         uevent["HID_NAME"],
+			SerialNumber: uevent["HID_UNIQ"],
+		}
+		for dir := device; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			if sysfsUSBDeviceRegexp.MatchString(filepath.Base(dir)) {
+				probe.Location = filepath.Base(dir)
+				break
+			}
+		}
+		res = append(res, probe)
+	}
+	return res, nil
+}
+
+// readUevent reads the KEY=value pairs of a sysfs uevent file.
+func readUevent(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			res[key] = value
+		}
+	}
+	return res, scanner.Err()
+}
+
+type hidrawTransport struct {
+	fd int
+}
+
+func nativeOpenCMSISDAP(path string) (dapTransport, error) {
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &hidrawTransport{fd: fd}, nil
+}
+
+func (t *hidrawTransport) transfer(req []byte) ([]byte, error) {
+	// The reports are prefixed by the report ID, 0 for the probes
+	report := make([]byte, dapReportSize+1)
+	copy(report[1:], req)
+	if _, err := unix.Write(t.fd, report); err != nil {
+		return nil, err
+	}
+	fds := []unix.PollFd{{Fd: int32(t.fd), Events: unix.POLLIN}}
+	for {
+		n, err := unix.Poll(fds, dapTimeout)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, errors.New("timeout waiting for the probe response")
+		}
+		break
+	}
+	res := make([]byte, dapReportSize)
+	n, err := unix.Read(t.fd, res)
+	if err != nil {
+		return nil, err
+	}
+	return res[:n], nil
+}
+
+func (t *hidrawTransport) Close() error {
+	return unix.Close(t.fd)
+}
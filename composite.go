@@ -0,0 +1,77 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"sort"
+	"strings"
+)
+
+// InterfaceSelector selects a port among the interfaces of a composite USB
+// device exposing many CDC interfaces.
+type InterfaceSelector func(port *PortDetails) bool
+
+// InterfaceNamed selects the interface with the given name (e.g. "Debug"),
+// compared case-insensitively.
+func InterfaceNamed(name string) InterfaceSelector {
+	return func(port *PortDetails) bool {
+		return port != nil && strings.EqualFold(port.InterfaceName, name)
+	}
+}
+
+// InterfaceWithNumber selects the interface with the given number, in
+// hexadecimal (e.g. "02").
+func InterfaceWithNumber(number string) InterfaceSelector {
+	return func(port *PortDetails) bool {
+		return port != nil && port.InterfaceNumber != "" && strings.EqualFold(port.InterfaceNumber, number)
+	}
+}
+
+// CompositeInterfaces returns the ports of the same USB device of the given
+// port (including it), sorted by interface number. The ports of a device are
+// recognized by the USB serial number or location.
+func CompositeInterfaces(ports map[string]*PortDetails, port string) []*PortDetails {
+	details := ports[port]
+	if details == nil {
+		return nil
+	}
+	id := NewPortID(details)
+	res := []*PortDetails{}
+	for _, other := range ports {
+		if other.IsUSB && other.VID == details.VID && other.PID == details.PID && NewPortID(other).SameDevice(id) {
+			res = append(res, other)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].InterfaceNumber != res[j].InterfaceNumber {
+			return res[i].InterfaceNumber < res[j].InterfaceNumber
+		}
+		return NaturalLess(res[i].Name, res[j].Name)
+	})
+	return res
+}
+
+// SelectInterface returns the first port, among the interfaces of the same
+// USB device of the given port, chosen by the selector. It returns nil if
+// there are none.
+func SelectInterface(ports map[string]*PortDetails, port string, selector InterfaceSelector) *PortDetails {
+	for _, details := range CompositeInterfaces(ports, port) {
+		if selector(details) {
+			return details
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrResetInProgress is returned by ResetWithOptions (and LockPort) if
+// another reset of the same port is in progress, unless the
+// WaitForConcurrentReset option is set.
+var ErrResetInProgress = errors.New("reset already in progress on the port")
+
+// claimDuration is how long a bootloader port found by a reset stays
+// claimed by it, so that the other resets in progress don't pick it.
+const claimDuration = 10 * time.Second
+
+// activeResets keeps track of the ports touched and found by the resets in
+// progress in the process, so that the resets running concurrently on
+// different boards don't mistake the ports of each other for their
+// bootloader port. Only the resets using the OS port enumeration (or the
+// PortsMapper of the Config) take part, the ones with their own PortsMapper
+// or a Simulator see their own ports.
+var activeResets = &resetRegistry{claims: map[string]*portClaim{}}
+
+type resetRegistry struct {
+	mux    sync.Mutex
+	claims map[string]*portClaim
+}
+
+// portClaim is a port claimed by a reset session until it expires.
+type portClaim struct {
+	owner   *claimOwner
+	expires time.Time
+}
+
+// claimOwner identifies the session owning the claims. The claims don't
+// reference the session itself, so that an abandoned session can be garbage
+// collected (see ResetSession.Close).
+type claimOwner struct {
+	_ byte
+}
+
+// claim marks the port as used by the session for the given duration.
+func (r *resetRegistry) claim(owner *claimOwner, port string, d time.Duration) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.claims[port] = &portClaim{owner: owner, expires: time.Now().Add(d)}
+}
+
+// release removes the claim of the session on the port, if any.
+func (r *resetRegistry) release(owner *claimOwner, port string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if c := r.claims[port]; c != nil && c.owner == owner {
+		delete(r.claims, port)
+	}
+}
+
+// claimedByOthers returns true if the port is claimed by another session.
+func (r *resetRegistry) claimedByOthers(owner *claimOwner, port string) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	c := r.claims[port]
+	if c == nil {
+		return false
+	}
+	if time.Now().After(c.expires) {
+		delete(r.claims, port)
+		return false
+	}
+	return c.owner != owner
+}
+
+// portLocks serializes the resets of the same port in the process.
+var portLocks = &portLockSet{locks: map[string]chan struct{}{}}
+
+type portLockSet struct {
+	mux   sync.Mutex
+	locks map[string]chan struct{}
+}
+
+// lock locks the port for a reset. If the port is already locked it fails
+// with ErrResetInProgress, or waits for it to be unlocked if wait is true
+// (until the context is canceled). The returned function unlocks the port.
+func (l *portLockSet) lock(ctx context.Context, port string, wait bool) (func(), error) {
+	for {
+		l.mux.Lock()
+		held, ok := l.locks[port]
+		if !ok {
+			released := make(chan struct{})
+			l.locks[port] = released
+			l.mux.Unlock()
+			return func() {
+				l.mux.Lock()
+				delete(l.locks, port)
+				l.mux.Unlock()
+				close(released)
+			}, nil
+		}
+		l.mux.Unlock()
+		if !wait {
+			return nil, ErrResetInProgress
+		}
+		select {
+		case <-held:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingResetter is a Resetter keeping track of the resets in progress on
+// every port, each reset lasting until the release channel is closed or the
+// given delay expires.
+type blockingResetter struct {
+	mux      sync.Mutex
+	inFlight map[string]int
+	maxIn    map[string]int
+	started  chan string
+	release  chan struct{}
+	delay    time.Duration
+}
+
+func newBlockingResetter(delay time.Duration) *blockingResetter {
+	return &blockingResetter{
+		inFlight: map[string]int{},
+		maxIn:    map[string]int{},
+		started:  make(chan string, 64),
+		release:  make(chan struct{}),
+		delay:    delay,
+	}
+}
+
+func (b *blockingResetter) Reset(port string) error {
+	b.mux.Lock()
+	b.inFlight[port]++
+	if b.inFlight[port] > b.maxIn[port] {
+		b.maxIn[port] = b.inFlight[port]
+	}
+	b.mux.Unlock()
+	b.started <- port
+	select {
+	case <-b.release:
+	case <-time.After(b.delay):
+	}
+	b.mux.Lock()
+	b.inFlight[port]--
+	b.mux.Unlock()
+	return nil
+}
+
+func (b *blockingResetter) maxInFlight(port string) int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.maxIn[port]
+}
+
+// concurrencyTestOptions returns the options of a reset without wait on the
+// given ports, with the process and file locks of a temporary directory.
+func concurrencyTestOptions(t *testing.T, resetter Resetter, ports ...string) *ResetOptions {
+	oldLockDir := PortLockDir
+	PortLockDir = t.TempDir()
+	t.Cleanup(func() { PortLockDir = oldLockDir })
+	return &ResetOptions{
+		PortsMapper: func() (map[string]bool, error) {
+			res := map[string]bool{}
+			for _, port := range ports {
+				res[port] = true
+			}
+			return res, nil
+		},
+		Resetter: resetter,
+	}
+}
+
+func TestConcurrentResetsOfSamePortAreSerialized(t *testing.T) {
+	const port = "/dev/ttyTEST0"
+	resetter := newBlockingResetter(20 * time.Millisecond)
+	opts := concurrencyTestOptions(t, resetter, port)
+	opts.WaitForConcurrentReset = true
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ResetWithOptions(port, opts)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(resetter.started) != n {
+		t.Errorf("%d resets performed, want %d", len(resetter.started), n)
+	}
+	if max := resetter.maxInFlight(port); max != 1 {
+		t.Errorf("%d concurrent resets of the same port, want 1", max)
+	}
+}
+
+func TestConcurrentResetOfSamePortIsRejected(t *testing.T) {
+	const port = "/dev/ttyTEST0"
+	resetter := newBlockingResetter(time.Minute)
+	opts := concurrencyTestOptions(t, resetter, port)
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := ResetWithOptions(port, opts)
+		first <- err
+	}()
+	<-resetter.started
+	if _, err := ResetWithOptions(port, opts); !errors.Is(err, ErrResetInProgress) {
+		t.Errorf("got %v, want ErrResetInProgress", err)
+	}
+	close(resetter.release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentResetsOfDifferentPortsRunInParallel(t *testing.T) {
+	const n = 4
+	ports := make([]string, n)
+	for i := range ports {
+		ports[i] = fmt.Sprintf("/dev/ttyTEST%d", i)
+	}
+	// The resets last until all of them have started: they would time out
+	// if they were serialized
+	resetter := newBlockingResetter(time.Minute)
+	opts := concurrencyTestOptions(t, resetter, ports...)
+
+	errs := make(chan error, n)
+	for _, port := range ports {
+		port := port
+		go func() {
+			_, err := ResetWithOptions(port, opts)
+			errs <- err
+		}()
+	}
+	timeout := time.After(10 * time.Second)
+	for i := 0; i < n; i++ {
+		select {
+		case <-resetter.started:
+		case <-timeout:
+			t.Fatalf("only %d of %d resets started in parallel", i, n)
+		}
+	}
+	close(resetter.release)
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPortLockWaitCanceled(t *testing.T) {
+	unlock, err := portLocks.lock(context.Background(), "/dev/ttyTEST0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := portLocks.lock(ctx, "/dev/ttyTEST0", true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestResetRegistryConcurrentClaims(t *testing.T) {
+	registry := &resetRegistry{claims: map[string]*portClaim{}}
+	owners := []*claimOwner{{}, {}, {}, {}}
+	var wg sync.WaitGroup
+	for i, owner := range owners {
+		wg.Add(1)
+		go func(port string, owner *claimOwner) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				registry.claim(owner, port, time.Minute)
+				for _, other := range owners {
+					if claimed := registry.claimedByOthers(other, port); claimed != (other != owner) {
+						t.Errorf("claimedByOthers(%s) = %v", port, claimed)
+						return
+					}
+				}
+				registry.release(owner, port)
+			}
+		}(fmt.Sprintf("/dev/ttyTEST%d", i), owner)
+	}
+	wg.Wait()
+	if len(registry.claims) != 0 {
+		t.Errorf("%d claims left", len(registry.claims))
+	}
+}
+
+func TestConfigPortsMapperShared(t *testing.T) {
+	prev := CurrentConfig()
+	defer SetConfig(prev)
+	mapper := func() (map[string]bool, error) {
+		return map[string]bool{"/dev/ttyACM0": true}, nil
+	}
+	SetConfig(Config{PortsMapper: mapper})
+
+	// The PortsMapper of the Config is shared by the resets of the process,
+	// the one of the ResetOptions is not
+	for _, test := range []struct {
+		opts   *ResetOptions
+		shared bool
+	}{
+		{&ResetOptions{}, true},
+		{&ResetOptions{PortsMapper: mapper}, false},
+	} {
+		s, err := beginReset(context.Background(), "/dev/ttyACM0", test.opts, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.shared != test.shared {
+			t.Errorf("PortsMapper %v: got shared %v, want %v", test.opts.PortsMapper != nil, s.shared, test.shared)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,133 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"sync"
+	"time"
+)
+
+// Config holds the settings inherited by all the operations of the process
+// (the resets and the port watchers) whose options leave them unset, so that
+// large applications can configure the package once instead of plumbing the
+// same options through every call. The zero Config keeps the package
+// defaults.
+type Config struct {
+	// Timeout is the maximum time to wait for the bootloader port, used if
+	// ResetOptions.Timeout is zero.
+	Timeout time.Duration
+	// PollBackoff, Stabilization and ErrorTolerance are used if the
+	// corresponding ResetOptions are nil.
+	PollBackoff    *PollBackoff
+	Stabilization  *Stabilization
+	ErrorTolerance *ErrorTolerance
+	// PortsMapper and DetailedPortsMapper are used if the corresponding
+	// ResetOptions are nil (except for the RFC 2217 remote ports), the
+	// DetailedPortsMapper is also used by the port watchers started without
+	// a mapper.
+	PortsMapper         PortsMapper
+	DetailedPortsMapper DetailedPortsMapper
+	// PortFilter, if not nil, excludes the ports it rejects from the events
+	// of the port watchers and from the bootloader candidates of the resets
+	// (if ResetOptions.Accept is nil and the reset is not emulated by a
+	// Simulator), e.g. to ignore the Bluetooth ports.
+	PortFilter func(port *PortDetails) bool
+	// WatchInterval is the polling interval of the port watchers started
+	// with a zero interval, 1 second if zero.
+	WatchInterval time.Duration
+	// Debug, if not nil, receives the debug messages of the resets whose
+	// callbacks don't set their own.
+	Debug func(msg string)
+}
+
+var globalConfig struct {
+	mux sync.Mutex
+	cfg Config
+}
+
+// SetConfig sets the Config of the process. It should be called once, before
+// starting any operation: the operations already in progress keep the
+// previous settings.
+func SetConfig(cfg Config) {
+	globalConfig.mux.Lock()
+	globalConfig.cfg = cfg
+	globalConfig.mux.Unlock()
+}
+
+// CurrentConfig returns the Config of the process.
+func CurrentConfig() Config {
+	globalConfig.mux.Lock()
+	defer globalConfig.mux.Unlock()
+	return globalConfig.cfg
+}
+
+// withConfig returns the ResetOptions of the reset of the port with the
+// unset fields taken from the Config of the process.
+func withConfig(opts *ResetOptions, port string) *ResetOptions {
+	cfg := CurrentConfig()
+	res := *opts
+	if res.Timeout == 0 {
+		res.Timeout = cfg.Timeout
+	}
+	if res.PollBackoff == nil {
+		res.PollBackoff = cfg.PollBackoff
+	}
+	if res.Stabilization == nil {
+		res.Stabilization = cfg.Stabilization
+	}
+	if res.ErrorTolerance == nil {
+		res.ErrorTolerance = cfg.ErrorTolerance
+	}
+	if !IsRFC2217Port(port) {
+		if res.PortsMapper == nil {
+			res.PortsMapper = cfg.PortsMapper
+		}
+		if res.DetailedPortsMapper == nil {
+			res.DetailedPortsMapper = cfg.DetailedPortsMapper
+		}
+	}
+	// The filter is about the ports of the system, not the emulated ones
+	if res.Accept == nil && res.Simulator == nil && !res.DryRun {
+		res.Accept = cfg.PortFilter
+	}
+	if cfg.Debug != nil && (res.Callbacks == nil || res.Callbacks.Debug == nil) {
+		cb := ResetProgressCallbacks{}
+		if res.Callbacks != nil {
+			cb = *res.Callbacks
+		}
+		cb.Debug = cfg.Debug
+		res.Callbacks = &cb
+	}
+	return &res
+}
+
+// filteredDetailedPortsMapper wraps the mapper removing the ports rejected by
+// the filter.
+func filteredDetailedPortsMapper(mapper DetailedPortsMapper, filter func(port *PortDetails) bool) DetailedPortsMapper {
+	return func() (map[string]*PortDetails, error) {
+		ports, err := mapper()
+		if err != nil {
+			return nil, err
+		}
+		res := map[string]*PortDetails{}
+		for name, port := range ports {
+			if filter(port) {
+				res[name] = port
+			}
+		}
+		return res, nil
+	}
+}
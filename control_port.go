@@ -0,0 +1,86 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+
+	"go.bug.st/serial"
+)
+
+// ControlPortResetter is a Resetter for the boards exposing, besides the
+// port of the sketch, a second CDC interface accepting a
+// reboot-to-bootloader command: the command is sent to the sibling port of
+// the same USB device (recognized by its serial number or USB location, see
+// CompositeInterfaces).
+type ControlPortResetter struct {
+	// Interface selects the control port among the interfaces of the
+	// device. If nil the first interface other than the reset port is used.
+	Interface InterfaceSelector
+	// Command is the command sent to the control port, including its
+	// terminator (e.g. "reboot-bootloader\n").
+	Command string
+	// BaudRate is the speed used to open the control port, 115200 if zero.
+	BaudRate int
+	// DetailedPortsMapper is used to find the control port, if nil the
+	// DefaultDetailedPortMapper is used.
+	DetailedPortsMapper DetailedPortsMapper
+}
+
+// Reset implements Resetter.
+func (r *ControlPortResetter) Reset(port string) error {
+	control, err := r.ControlPort(port)
+	if err != nil {
+		return err
+	}
+	baudRate := r.BaudRate
+	if baudRate == 0 {
+		baudRate = 115200
+	}
+	p, err := openPortWithTimeout(control, &serial.Mode{BaudRate: baudRate}, 0)
+	if err != nil {
+		return fmt.Errorf("opening control port %s: %w", control, err)
+	}
+	defer p.Close()
+	if _, err := p.Write([]byte(r.Command)); err != nil {
+		return fmt.Errorf("sending command to control port %s: %w", control, err)
+	}
+	// The board may reboot before the output is drained
+	_ = p.Drain()
+	return nil
+}
+
+// ControlPort returns the control port of the board connected to the given
+// port.
+func (r *ControlPortResetter) ControlPort(port string) (string, error) {
+	mapper := r.DetailedPortsMapper
+	if mapper == nil {
+		mapper = DefaultDetailedPortMapper
+	}
+	ports, err := mapper()
+	if err != nil {
+		return "", fmt.Errorf("listing ports: %w", err)
+	}
+	for _, details := range CompositeInterfaces(ports, port) {
+		if details.Name == port {
+			continue
+		}
+		if r.Interface == nil || r.Interface(details) {
+			return details.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no control port found for %s", port)
+}
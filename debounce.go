@@ -0,0 +1,110 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"sync"
+	"time"
+)
+
+// DebouncedPortsMapper returns a PortsMapper that hides the rapid changes of
+// the port list, like the ones happening while a board resets: a new list
+// is reported only after it stayed unchanged for the given window, until
+// then the previous settled list is reported. The first list is reported
+// immediately. This is useful for the board lists of the IDEs, that would
+// otherwise flicker during the resets.
+func DebouncedPortsMapper(mapper PortsMapper, window time.Duration) PortsMapper {
+	if mapper == nil {
+		mapper = DefaultPortMapper
+	}
+	get := debounce(mapper, window, samePortList)
+	return func() (map[string]bool, error) {
+		ports, err := get()
+		if err != nil {
+			return nil, err
+		}
+		res := make(map[string]bool, len(ports))
+		for name := range ports {
+			res[name] = true
+		}
+		return res, nil
+	}
+}
+
+// DebouncedDetailedPortsMapper is like DebouncedPortsMapper for a
+// DetailedPortsMapper. Only the port names are considered to detect the
+// changes.
+func DebouncedDetailedPortsMapper(mapper DetailedPortsMapper, window time.Duration) DetailedPortsMapper {
+	if mapper == nil {
+		mapper = DefaultDetailedPortMapper
+	}
+	get := debounce(mapper, window, sameKeys[*PortDetails])
+	return func() (map[string]*PortDetails, error) {
+		ports, err := get()
+		if err != nil {
+			return nil, err
+		}
+		res := make(map[string]*PortDetails, len(ports))
+		for name, port := range ports {
+			details := *port
+			res[name] = &details
+		}
+		return res, nil
+	}
+}
+
+// debounce returns a function calling mapper and returning its last result
+// that stayed unchanged (according to same) for the window.
+func debounce[T any](mapper func() (T, error), window time.Duration, same func(a, b T) bool) func() (T, error) {
+	var mux sync.Mutex
+	var settled, candidate T
+	var since time.Time
+	initialized := false
+	return func() (T, error) {
+		ports, err := mapper()
+		if err != nil {
+			return ports, err
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		now := time.Now()
+		if !initialized {
+			settled, candidate, since = ports, ports, now
+			initialized = true
+			return settled, nil
+		}
+		if !same(ports, candidate) {
+			candidate, since = ports, now
+		}
+		if same(candidate, settled) || now.Sub(since) >= window {
+			settled = ports
+		}
+		return settled, nil
+	}
+}
+
+// sameKeys returns true if the two maps have the same keys.
+func sameKeys[T any](a, b map[string]T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
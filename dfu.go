@@ -0,0 +1,50 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+//go:build serialutils_dfu
+
+package serialutils
+
+// USB class of the DFU interfaces (application specific class, DFU
+// subclass).
+const (
+	usbClassApplicationSpecific = 0xFE
+	usbSubClassDFU              = 0x01
+)
+
+// IsDFUDevice returns true if the USB device has a DFU interface, like the
+// STM32 and ATmega DFU bootloaders.
+func IsDFUDevice(d *USBDevice) bool {
+	return d.HasInterface(usbClassApplicationSpecific, usbSubClassDFU)
+}
+
+// DFUDiscoverer returns a TargetDiscoverer of the USB DFU devices, to detect
+// the DFU bootloaders appearing after a reset. The targets found are of
+// kind DFUDevice and their path is the "bus:address" of the device, the
+// details of the device are reported in ResetTarget.USB. It is supported
+// only on Linux, and built only with the serialutils_dfu build tag.
+func DFUDiscoverer() TargetDiscoverer {
+	return &usbDeviceDiscoverer{kind: DFUDevice, match: IsDFUDevice}
+}
+
+// DFUUtilArgs returns the dfu-util arguments selecting the device: the
+// VID/PID and, if known, the USB location.
+func (d *USBDevice) DFUUtilArgs() []string {
+	res := []string{"-d", d.VID + ":" + d.PID}
+	if d.Location != "" {
+		res = append(res, "-p", d.Location)
+	}
+	return res
+}
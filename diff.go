@@ -0,0 +1,67 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "sort"
+
+// DiffPorts compares two port lists and returns the ports added and removed
+// in after with respect to before, sorted in natural order. These are the
+// same semantics used by Reset to detect the new ports.
+func DiffPorts(before, after map[string]bool) (added, removed []string) {
+	added, removed = []string{}, []string{}
+	for port := range after {
+		if !before[port] {
+			added = append(added, port)
+		}
+	}
+	for port := range before {
+		if !after[port] {
+			removed = append(removed, port)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return NaturalLess(added[i], added[j]) })
+	sort.Slice(removed, func(i, j int) bool { return NaturalLess(removed[i], removed[j]) })
+	return added, removed
+}
+
+// DiffPortDetails is like DiffPorts but compares two lists of port details,
+// as returned by a DetailedPortsMapper.
+func DiffPortDetails(before, after map[string]*PortDetails) (added, removed []*PortDetails) {
+	added, removed = []*PortDetails{}, []*PortDetails{}
+	for name, port := range after {
+		if _, ok := before[name]; !ok {
+			added = append(added, port)
+		}
+	}
+	for name, port := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, port)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return NaturalLess(added[i].Name, added[j].Name) })
+	sort.Slice(removed, func(i, j int) bool { return NaturalLess(removed[i].Name, removed[j].Name) })
+	return added, removed
+}
+
+// sortedKeys returns the keys of the map sorted in natural order.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return NaturalLess(keys[i], keys[j]) })
+	return keys
+}
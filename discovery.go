@@ -0,0 +1,62 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"strings"
+)
+
+// DiscoveryPort is a port as described in the Arduino pluggable-discovery
+// protocol messages.
+type DiscoveryPort struct {
+	Address       string            `json:"address"`
+	Label         string            `json:"label,omitempty"`
+	Protocol      string            `json:"protocol,omitempty"`
+	ProtocolLabel string            `json:"protocolLabel,omitempty"`
+	HardwareID    string            `json:"hardwareId,omitempty"`
+	Properties    map[string]string `json:"properties,omitempty"`
+}
+
+// discoveryMessage is a message sent by a pluggable discovery.
+type discoveryMessage struct {
+	EventType       string           `json:"eventType"`
+	Message         string           `json:"message,omitempty"`
+	Error           bool             `json:"error,omitempty"`
+	ProtocolVersion int              `json:"protocolVersion,omitempty"`
+	Ports           []*DiscoveryPort `json:"ports,omitempty"`
+	Port            *DiscoveryPort   `json:"port,omitempty"`
+}
+
+// PortDetails converts the DiscoveryPort into a PortDetails.
+func (p *DiscoveryPort) PortDetails() *PortDetails {
+	vid := normalizeDiscoveryUSBID(p.Properties["vid"])
+	pid := normalizeDiscoveryUSBID(p.Properties["pid"])
+	return &PortDetails{
+		Name:         p.Address,
+		IsUSB:        vid != "" && pid != "",
+		VID:          vid,
+		PID:          pid,
+		SerialNumber: p.Properties["serialNumber"],
+		Product:      p.Label,
+	}
+}
+
+// normalizeDiscoveryUSBID converts a "0x2341" VID/PID, as reported by the
+// discoveries, into the "2341" format used in PortDetails.
+func normalizeDiscoveryUSBID(id string) string {
+	id = strings.TrimPrefix(strings.ToLower(id), "0x")
+	return strings.ToUpper(id)
+}
@@ -0,0 +1,217 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ErrDiscoveryTerminated is returned by the mappers of a DiscoveryClient
+// whose discovery has quit.
+var ErrDiscoveryTerminated = errors.New("discovery terminated")
+
+// DiscoveryClient runs an Arduino pluggable discovery (like serial-discovery)
+// and keeps track of the ports it reports using the START_SYNC mode. Its
+// PortsMapper can be used in place of the direct enumeration.
+type DiscoveryClient struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	replies  chan *discoveryMessage
+	mux      sync.Mutex
+	ports    map[string]*DiscoveryPort
+	err      error
+	finished chan struct{}
+	// pending is the event type of the reply to the command in progress
+	pending   string
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewDiscoveryClient returns a client for the pluggable discovery started by
+// running the given command.
+func NewDiscoveryClient(name string, args ...string) *DiscoveryClient {
+	return &DiscoveryClient{
+		cmd:      exec.Command(name, args...),
+		replies:  make(chan *discoveryMessage, 1),
+		ports:    map[string]*DiscoveryPort{},
+		finished: make(chan struct{}),
+	}
+}
+
+// Start runs the discovery and puts it in START_SYNC mode.
+func (d *DiscoveryClient) Start() error {
+	stdout, err := d.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("starting discovery: %w", err)
+	}
+	if d.stdin, err = d.cmd.StdinPipe(); err != nil {
+		return fmt.Errorf("starting discovery: %w", err)
+	}
+	if err := d.cmd.Start(); err != nil {
+		return fmt.Errorf("starting discovery: %w", err)
+	}
+	go d.readMessages(stdout)
+
+	if err := d.command("HELLO 1 \"go-serial-utils\"", "hello"); err != nil {
+		_ = d.Close()
+		return err
+	}
+	if err := d.command("START_SYNC", "start_sync"); err != nil {
+		_ = d.Close()
+		return err
+	}
+	return nil
+}
+
+// Close stops the discovery. It can be called more than once, returning the
+// same error.
+func (d *DiscoveryClient) Close() error {
+	d.closeOnce.Do(func() {
+		_, _ = io.WriteString(d.stdin, "QUIT\n")
+		_ = d.stdin.Close()
+		select {
+		case <-d.finished:
+		case <-time.After(5 * time.Second):
+			_ = d.cmd.Process.Kill()
+		}
+		d.closeErr = d.cmd.Wait()
+	})
+	return d.closeErr
+}
+
+// PortsMapper returns a PortsMapper reporting the ports currently known by the
+// discovery.
+func (d *DiscoveryClient) PortsMapper() PortsMapper {
+	return func() (map[string]bool, error) {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		if d.err != nil {
+			return nil, d.err
+		}
+		res := map[string]bool{}
+		for address := range d.ports {
+			res[address] = true
+		}
+		return res, nil
+	}
+}
+
+// DetailedPortsMapper returns a DetailedPortsMapper reporting the details of
+// the ports currently known by the discovery.
+func (d *DiscoveryClient) DetailedPortsMapper() DetailedPortsMapper {
+	return func() (map[string]*PortDetails, error) {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		if d.err != nil {
+			return nil, d.err
+		}
+		res := map[string]*PortDetails{}
+		for address, port := range d.ports {
+			res[address] = port.PortDetails()
+		}
+		return res, nil
+	}
+}
+
+// command sends a command to the discovery and waits for the reply.
+func (d *DiscoveryClient) command(cmd string, expectedEvent string) error {
+	// Only the reply to this command is forwarded from now on, drop the late
+	// reply to a previous command timed out
+	d.mux.Lock()
+	d.pending = expectedEvent
+	d.mux.Unlock()
+	defer func() {
+		d.mux.Lock()
+		d.pending = ""
+		d.mux.Unlock()
+	}()
+	select {
+	case <-d.replies:
+	default:
+	}
+
+	if _, err := io.WriteString(d.stdin, cmd+"\n"); err != nil {
+		return fmt.Errorf("sending command to discovery: %w", err)
+	}
+	select {
+	case msg, ok := <-d.replies:
+		if !ok {
+			return ErrDiscoveryTerminated
+		}
+		if msg.Error {
+			return fmt.Errorf("discovery error: %s", msg.Message)
+		}
+		return nil
+	case <-time.After(10 * time.Second):
+		return fmt.Errorf("timeout waiting for discovery reply to %s", cmd)
+	}
+}
+
+func (d *DiscoveryClient) readMessages(stdout io.Reader) {
+	defer close(d.finished)
+	defer close(d.replies)
+	decoder := json.NewDecoder(bufio.NewReader(stdout))
+	for {
+		var msg discoveryMessage
+		if err := decoder.Decode(&msg); err != nil {
+			d.mux.Lock()
+			d.err = fmt.Errorf("reading from discovery: %w", err)
+			d.mux.Unlock()
+			return
+		}
+		switch msg.EventType {
+		case "add":
+			if msg.Port != nil {
+				d.mux.Lock()
+				d.ports[msg.Port.Address] = msg.Port
+				d.mux.Unlock()
+			}
+		case "remove":
+			if msg.Port != nil {
+				d.mux.Lock()
+				delete(d.ports, msg.Port.Address)
+				d.mux.Unlock()
+			}
+		case "quit":
+			d.mux.Lock()
+			d.err = ErrDiscoveryTerminated
+			d.mux.Unlock()
+			return
+		default:
+			// The events not replying to the command in progress are
+			// ignored, so that they don't take the place of the reply
+			d.mux.Lock()
+			forward := msg.EventType == d.pending
+			if forward {
+				d.pending = ""
+			}
+			d.mux.Unlock()
+			if forward {
+				select {
+				case d.replies <- &msg:
+				default:
+				}
+			}
+		}
+	}
+}
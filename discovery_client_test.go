@@ -0,0 +1,85 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFakeDiscovery is not a real test: it runs as a fake pluggable
+// discovery when the test binary is started by newFakeDiscoveryClient.
+func TestFakeDiscovery(t *testing.T) {
+	if os.Getenv("SERIALUTILS_FAKE_DISCOVERY") != "1" {
+		t.Skip("helper process")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		switch cmd := strings.Fields(scanner.Text())[0]; cmd {
+		case "HELLO":
+			// An unsolicited event sent before the reply
+			fmt.Println(`{"eventType":"list","ports":[]}`)
+			fmt.Println(`{"eventType":"hello","message":"OK","protocolVersion":1}`)
+		case "START_SYNC":
+			fmt.Println(`{"eventType":"start_sync","message":"OK"}`)
+			fmt.Println(`{"eventType":"add","port":{"address":"/dev/ttyACM0","protocol":"serial","properties":{"vid":"0x2341","pid":"0x8036"}}}`)
+		case "QUIT":
+			fmt.Println(`{"eventType":"quit","message":"OK"}`)
+			os.Exit(0)
+		}
+	}
+	os.Exit(0)
+}
+
+func newFakeDiscoveryClient() *DiscoveryClient {
+	d := NewDiscoveryClient(os.Args[0], "-test.run=^TestFakeDiscovery$")
+	d.cmd.Env = append(os.Environ(), "SERIALUTILS_FAKE_DISCOVERY=1")
+	return d
+}
+
+func TestDiscoveryClient(t *testing.T) {
+	d := newFakeDiscoveryClient()
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// The add event may follow the start_sync reply
+	var ports map[string]*PortDetails
+	for i := 0; i < 100 && len(ports) == 0; i++ {
+		var err error
+		if ports, err = d.DetailedPortsMapper()(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p := ports["/dev/ttyACM0"]; p == nil || p.VID != "2341" || p.PID != "8036" {
+		t.Fatalf("port not reported: %v", ports)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if _, err := d.PortsMapper()(); !errors.Is(err, ErrDiscoveryTerminated) {
+		t.Fatalf("expected ErrDiscoveryTerminated, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewDiscoveryPort converts a PortDetails into a DiscoveryPort, as reported by
+// the Arduino serial-discovery.
+func NewDiscoveryPort(port *PortDetails) *DiscoveryPort {
+	res := &DiscoveryPort{
+		Address:       port.Name,
+		Label:         port.Name,
+		Protocol:      "serial",
+		ProtocolLabel: "Serial Port",
+		Properties:    map[string]string{"name": filepath.Base(port.Name)},
+	}
+	if port.IsUSB {
+		res.ProtocolLabel = "Serial Port (USB)"
+		res.HardwareID = port.SerialNumber
+		res.Properties["vid"] = "0x" + strings.ToLower(port.VID)
+		res.Properties["pid"] = "0x" + strings.ToLower(port.PID)
+		res.Properties["serialNumber"] = port.SerialNumber
+	}
+	return res
+}
+
+// RunDiscoveryServer runs a pluggable-discovery compliant server, reading the
+// commands from `in` and writing the replies and events to `out`, making
+// this package usable as a serial-discovery replacement. The ports are listed
+// using `mapper` (DefaultDetailedPortMapper if nil). The function returns
+// when the QUIT command is received or `in` is closed.
+func RunDiscoveryServer(in io.Reader, out io.Writer, mapper DetailedPortsMapper) error {
+	if mapper == nil {
+		mapper = DefaultDetailedPortMapper
+	}
+	s := &discoveryServer{out: out, mapper: mapper}
+	defer s.stopWatcher()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd := strings.ToUpper(fields[0])
+		if cmd == "QUIT" {
+			s.stopWatcher()
+			return s.send(&discoveryMessage{EventType: "quit", Message: "OK"})
+		}
+		if err := s.handle(cmd, fields[1:]); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type discoveryServer struct {
+	out     io.Writer
+	outMux  sync.Mutex
+	mapper  DetailedPortsMapper
+	hello   bool
+	started bool
+	watcher *PortWatcher
+}
+
+func (s *discoveryServer) handle(cmd string, args []string) error {
+	if cmd != "HELLO" && !s.hello {
+		return s.sendError("command_error", "HELLO not called")
+	}
+	switch cmd {
+	case "HELLO":
+		if s.hello {
+			return s.sendError("command_error", "HELLO already called")
+		}
+		if len(args) < 1 || args[0] != "1" {
+			return s.sendError("hello", "Invalid HELLO command")
+		}
+		s.hello = true
+		return s.send(&discoveryMessage{EventType: "hello", ProtocolVersion: 1, Message: "OK"})
+	case "START":
+		if s.started || s.watcher != nil {
+			return s.sendError("start", "Discovery already STARTed")
+		}
+		s.started = true
+		return s.send(&discoveryMessage{EventType: "start", Message: "OK"})
+	case "STOP":
+		if !s.started && s.watcher == nil {
+			return s.sendError("stop", "Discovery already STOPped")
+		}
+		s.started = false
+		s.stopWatcher()
+		return s.send(&discoveryMessage{EventType: "stop", Message: "OK"})
+	case "LIST":
+		if !s.started {
+			return s.sendError("list", "Discovery not STARTed")
+		}
+		ports, err := s.mapper()
+		if err != nil {
+			return s.sendError("list", err.Error())
+		}
+		msg := &discoveryMessage{EventType: "list", Ports: []*DiscoveryPort{}}
+		for _, port := range ports {
+			msg.Ports = append(msg.Ports, NewDiscoveryPort(port))
+		}
+		sort.Slice(msg.Ports, func(i, j int) bool { return msg.Ports[i].Address < msg.Ports[j].Address })
+		return s.send(msg)
+	case "START_SYNC":
+		if s.started || s.watcher != nil {
+			return s.sendError("start_sync", "Discovery already STARTed")
+		}
+		if err := s.send(&discoveryMessage{EventType: "start_sync", Message: "OK"}); err != nil {
+			return err
+		}
+		s.watcher = WatchPorts(s.mapper, time.Second, func(ev PortEvent) {
+			switch ev.Type {
+			case PortAdded:
+				_ = s.send(&discoveryMessage{EventType: "add", Port: NewDiscoveryPort(ev.Port)})
+			case PortRemoved:
+				_ = s.send(&discoveryMessage{EventType: "remove", Port: &DiscoveryPort{Address: ev.Port.Name, Protocol: "serial"}})
+			}
+		})
+		return nil
+	default:
+		return s.sendError("command_error", fmt.Sprintf("Command %s not supported", cmd))
+	}
+}
+
+func (s *discoveryServer) stopWatcher() {
+	if s.watcher != nil {
+		s.watcher.Close()
+		s.watcher = nil
+	}
+}
+
+func (s *discoveryServer) sendError(eventType, msg string) error {
+	return s.send(&discoveryMessage{EventType: eventType, Error: true, Message: msg})
+}
+
+func (s *discoveryServer) send(msg *discoveryMessage) error {
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding discovery message: %w", err)
+	}
+	s.outMux.Lock()
+	defer s.outMux.Unlock()
+	if _, err := s.out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing discovery message: %w", err)
+	}
+	return nil
+}
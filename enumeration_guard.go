@@ -0,0 +1,84 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"sync"
+	"time"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// MinEnumerationInterval is the minimum interval between two enumerations
+// of the OS serial ports done by DefaultPortMapper and
+// DefaultDetailedPortMapper, that are shared by all the consumers in the
+// process (port watchers, resets, UI refreshes): the calls made within the
+// interval reuse the result of the previous enumeration. Besides, the
+// concurrent calls always share the same enumeration, since simultaneous
+// calls to the OS enumeration APIs are known to fail spuriously on Windows.
+// Zero disables the reuse of the results.
+var MinEnumerationInterval = 50 * time.Millisecond
+
+// enumerationGuard coalesces the concurrent calls to an enumeration and
+// reuses its result for MinEnumerationInterval.
+type enumerationGuard[T any] struct {
+	mux     sync.Mutex
+	pending *mapperCall[T]
+	last    *mapperCall[T]
+	lastAt  time.Time
+}
+
+func (g *enumerationGuard[T]) get(enumerate func() (T, error)) (T, error) {
+	g.mux.Lock()
+	if g.pending == nil && g.last != nil && time.Since(g.lastAt) < MinEnumerationInterval {
+		last := g.last
+		g.mux.Unlock()
+		return last.res, last.err
+	}
+	if call := g.pending; call != nil {
+		g.mux.Unlock()
+		<-call.done
+		return call.res, call.err
+	}
+	call := &mapperCall[T]{done: make(chan struct{})}
+	g.pending = call
+	g.mux.Unlock()
+
+	call.res, call.err = enumerate()
+	g.mux.Lock()
+	g.pending = nil
+	if call.err == nil {
+		g.last, g.lastAt = call, time.Now()
+	}
+	g.mux.Unlock()
+	close(call.done)
+	return call.res, call.err
+}
+
+var portsListGuard enumerationGuard[[]string]
+var detailedPortsListGuard enumerationGuard[[]*enumerator.PortDetails]
+
+// guardedGetPortsList is nativeGetPortsList through the process-wide
+// enumeration guard.
+func guardedGetPortsList() ([]string, error) {
+	return portsListGuard.get(nativeGetPortsList)
+}
+
+// guardedGetDetailedPortsList is nativeGetDetailedPortsList through the
+// process-wide enumeration guard.
+func guardedGetDetailedPortsList() ([]*enumerator.PortDetails, error) {
+	return detailedPortsListGuard.get(nativeGetDetailedPortsList)
+}
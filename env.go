@@ -0,0 +1,52 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"os"
+	"time"
+)
+
+// The environment variables overriding the default timings of the package,
+// for the users that need to tune them (e.g. for slow boards or loaded CI
+// machines) without rebuilding the tools. The values are parsed with
+// time.ParseDuration (e.g. "15s", "750ms"), the invalid or non-positive
+// values are ignored.
+const (
+	EnvWaitTimeout    = "SERIALUTILS_WAIT_TIMEOUT"
+	EnvPostTouchDelay = "SERIALUTILS_POST_TOUCH_DELAY"
+	EnvTouchTimeout   = "SERIALUTILS_TOUCH_TIMEOUT"
+	EnvOpenTimeout    = "SERIALUTILS_OPEN_TIMEOUT"
+)
+
+func init() {
+	applyEnvDuration(EnvWaitTimeout, &DefaultWaitTimeout)
+	applyEnvDuration(EnvPostTouchDelay, &DefaultPostTouchDelay)
+	applyEnvDuration(EnvTouchTimeout, &DefaultTouchTimeout)
+	applyEnvDuration(EnvOpenTimeout, &DefaultOpenTimeout)
+}
+
+// applyEnvDuration overrides the duration with the value of the environment
+// variable, if set to a valid positive duration.
+func applyEnvDuration(name string, d *time.Duration) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+	if v, err := time.ParseDuration(value); err == nil && v > 0 {
+		*d = v
+	}
+}
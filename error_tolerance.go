@@ -0,0 +1,65 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorTolerance defines how many port enumeration errors are tolerated
+// while waiting for the bootloader port. Transient enumeration failures are
+// common on Windows right after the re-enumeration of a device: the
+// failed polls are skipped until the limits are exceeded, then the wait is
+// aborted with the last error.
+type ErrorTolerance struct {
+	// MaxConsecutive is the number of consecutive failures tolerated.
+	MaxConsecutive int
+	// MaxTotal is the total number of failures tolerated during the wait.
+	MaxTotal int
+}
+
+// DefaultErrorTolerance is the ErrorTolerance used if none is specified.
+var DefaultErrorTolerance = ErrorTolerance{
+	MaxConsecutive: 3,
+	MaxTotal:       10,
+}
+
+// errTransientEnumeration is returned by the PortsMapper wrapped by an
+// ErrorTolerance in place of the tolerated errors.
+var errTransientEnumeration = errors.New("transient enumeration error")
+
+// wrap returns a PortsMapper that replaces the tolerated errors of mapper
+// with errTransientEnumeration.
+func (t *ErrorTolerance) wrap(mapper PortsMapper, debug func(string)) PortsMapper {
+	consecutive, total := 0, 0
+	return func() (map[string]bool, error) {
+		ports, err := mapper()
+		if err == nil {
+			consecutive = 0
+			return ports, nil
+		}
+		consecutive++
+		total++
+		if consecutive > t.MaxConsecutive || total > t.MaxTotal {
+			return nil, err
+		}
+		if debug != nil {
+			debug(fmt.Sprintf("Enumeration error tolerated (%d consecutive, %d total): %v", consecutive, total, err))
+		}
+		return nil, errTransientEnumeration
+	}
+}
@@ -0,0 +1,92 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"github.com/arduino/go-serial-utils/serialutilstest"
+)
+
+var errEnum = errors.New("enumeration failed")
+
+// resetWithFailingPolls runs a reset whose board re-enumerates as
+// /dev/ttyACM1 after 2 seconds, where the polls of the wait for which fail
+// returns true fail with errEnum.
+func resetWithFailingPolls(tolerance *serialutils.ErrorTolerance, fail func(poll int) bool) (*serialutils.ResetResult, error) {
+	env := serialutilstest.NewEnvironment("/dev/ttyACM0").
+		RemovePortAt(0, "/dev/ttyACM0").
+		AddPortAt(2*time.Second, "/dev/ttyACM1")
+	mapper := env.PortsMapper()
+	calls := 0
+	opts := env.ResetOptions()
+	opts.Wait = true
+	opts.ErrorTolerance = tolerance
+	opts.PollBackoff = &serialutils.PollBackoff{Initial: 100 * time.Millisecond}
+	opts.PortsMapper = func() (map[string]bool, error) {
+		// The first call lists the ports before the reset
+		calls++
+		if calls > 1 && fail(calls-1) {
+			return nil, errEnum
+		}
+		return mapper()
+	}
+	return serialutils.ResetWithOptions("/dev/ttyACM0", opts)
+}
+
+func TestErrorTolerance(t *testing.T) {
+	tolerance := &serialutils.ErrorTolerance{MaxConsecutive: 2, MaxTotal: 4}
+	for _, test := range []struct {
+		name  string
+		fail  func(poll int) bool
+		fails bool
+	}{
+		{"no failures", func(int) bool { return false }, false},
+		{"consecutive failures tolerated", func(poll int) bool { return poll == 3 || poll == 4 }, false},
+		{"too many consecutive failures", func(poll int) bool { return poll >= 3 && poll <= 5 }, true},
+		{"total failures tolerated", func(poll int) bool { return poll%3 == 0 && poll <= 12 }, false},
+		{"too many total failures", func(poll int) bool { return poll%3 == 0 }, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := resetWithFailingPolls(tolerance, test.fail)
+			if test.fails {
+				if !errors.Is(err, errEnum) {
+					t.Fatalf("expected the enumeration error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Target.Path != "/dev/ttyACM1" {
+				t.Fatalf("found %q, want /dev/ttyACM1", res.Target.Path)
+			}
+		})
+	}
+}
+
+func TestDefaultErrorTolerance(t *testing.T) {
+	max := serialutils.DefaultErrorTolerance.MaxConsecutive
+	if _, err := resetWithFailingPolls(nil, func(poll int) bool { return poll <= max }); err != nil {
+		t.Fatalf("%d consecutive failures not tolerated: %v", max, err)
+	}
+	if _, err := resetWithFailingPolls(nil, func(poll int) bool { return poll <= max+1 }); !errors.Is(err, errEnum) {
+		t.Fatalf("%d consecutive failures tolerated: %v", max+1, err)
+	}
+}
@@ -0,0 +1,79 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ESPResetter is a Resetter that puts an Espressif ESP8266/ESP32 in its ROM
+// download mode using the DTR/RTS auto-reset circuit of the development
+// boards (RTS→EN, DTR→GPIO0), with the same sequence used by esptool.
+//
+// The board does not change port after the reset, so this Resetter is
+// usually used without waiting for a new port.
+type ESPResetter struct {
+	// ResetDelay is the time EN is kept low, 100 ms if zero.
+	ResetDelay time.Duration
+	// BootDelay is the time GPIO0 is kept low after EN is released, 50 ms if zero.
+	BootDelay time.Duration
+}
+
+// Reset implements Resetter.
+func (r *ESPResetter) Reset(port string) error {
+	resetDelay := r.ResetDelay
+	if resetDelay == 0 {
+		resetDelay = 100 * time.Millisecond
+	}
+	bootDelay := r.BootDelay
+	if bootDelay == 0 {
+		bootDelay = 50 * time.Millisecond
+	}
+
+	p, err := openPortWithTimeout(port, &serial.Mode{
+		BaudRate:          115200,
+		InitialStatusBits: &serial.ModemOutputBits{DTR: false, RTS: false},
+	}, 0)
+	if err != nil {
+		return fmt.Errorf("opening port: %w", err)
+	}
+	defer p.Close()
+
+	setLines := func(dtr, rts bool) error {
+		if err := p.SetDTR(dtr); err != nil {
+			return fmt.Errorf("setting DTR: %w", err)
+		}
+		if err := p.SetRTS(rts); err != nil {
+			return fmt.Errorf("setting RTS: %w", err)
+		}
+		return nil
+	}
+	// EN low (reset), GPIO0 high
+	if err := setLines(false, true); err != nil {
+		return err
+	}
+	time.Sleep(resetDelay)
+	// EN high, GPIO0 low: the chip boots in download mode
+	if err := setLines(true, false); err != nil {
+		return err
+	}
+	time.Sleep(bootDelay)
+	// Release GPIO0
+	return setLines(false, false)
+}
@@ -2,9 +2,9 @@ module github.com/arduino/go-serial-utils
 
 go 1.21
 
-require go.bug.st/serial v1.6.1
-
 require (
-	github.com/creack/goselect v0.1.2 // indirect
-	golang.org/x/sys v0.16.0 // indirect
+	go.bug.st/serial v1.6.1
+	golang.org/x/sys v0.16.0
 )
+
+require github.com/creack/goselect v0.1.2 // indirect
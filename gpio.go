@@ -0,0 +1,51 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"time"
+)
+
+// GPIOResetter is a Resetter that pulses a GPIO line wired to the RESET pin of
+// the target board, this is useful on Linux single-board computers flashing
+// MCUs attached to their UART. The GPIO line is driven through the Linux GPIO
+// character device (/dev/gpiochipN), on other OS the reset fails.
+//
+// The port name is not used by this Resetter.
+type GPIOResetter struct {
+	// Chip is the path of the GPIO character device, e.g. "/dev/gpiochip0".
+	Chip string
+	// Line is the offset of the GPIO line in the chip.
+	Line uint32
+	// ActiveHigh must be set if the RESET line is active-high, by default
+	// the line is driven low to reset the target.
+	ActiveHigh bool
+	// Pulse is the duration of the reset pulse, 100 ms if zero.
+	Pulse time.Duration
+}
+
+// Reset implements Resetter.
+func (r *GPIOResetter) Reset(port string) error {
+	pulse := r.Pulse
+	if pulse == 0 {
+		pulse = 100 * time.Millisecond
+	}
+	if err := nativeGPIOPulse(r.Chip, r.Line, r.ActiveHigh, pulse); err != nil {
+		return fmt.Errorf("pulsing GPIO line %d of %s: %w", r.Line, r.Chip, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The structures and the constants below mirror the GPIO character device
+// ABI (v1) defined in linux/gpio.h.
+
+const (
+	gpioGetLineHandleIoctl     = 0xC16CB403
+	gpioHandleSetLineValsIoctl = 0xC040B409
+	gpioHandleRequestOutput    = 1 << 1
+)
+
+type gpioHandleRequest struct {
+	LineOffsets   [64]uint32
+	Flags         uint32
+	DefaultValues [64]uint8
+	ConsumerLabel [32]byte
+	Lines         uint32
+	Fd            int32
+}
+
+type gpioHandleData struct {
+	Values [64]uint8
+}
+
+func nativeGPIOPulse(chip string, line uint32, activeHigh bool, pulse time.Duration) error {
+	f, err := os.OpenFile(chip, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	active, inactive := uint8(0), uint8(1)
+	if activeHigh {
+		active, inactive = 1, 0
+	}
+
+	req := gpioHandleRequest{Flags: gpioHandleRequestOutput, Lines: 1}
+	req.LineOffsets[0] = line
+	req.DefaultValues[0] = inactive
+	copy(req.ConsumerLabel[:], "serialutils-reset")
+	if err := ioctl(f.Fd(), gpioGetLineHandleIoctl, unsafe.Pointer(&req)); err != nil {
+		return err
+	}
+	defer unix.Close(int(req.Fd))
+
+	setLine := func(value uint8) error {
+		data := gpioHandleData{}
+		data.Values[0] = value
+		return ioctl(uintptr(req.Fd), gpioHandleSetLineValsIoctl, unsafe.Pointer(&data))
+	}
+	if err := setLine(active); err != nil {
+		return err
+	}
+	time.Sleep(pulse)
+	return setLine(inactive)
+}
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
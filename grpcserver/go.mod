@@ -0,0 +1,21 @@
+module github.com/arduino/go-serial-utils/grpcserver
+
+go 1.25.0
+
+require (
+	github.com/arduino/go-serial-utils v0.0.0
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.12
+)
+
+require (
+	github.com/creack/goselect v0.1.2 // indirect
+	go.bug.st/serial v1.6.1 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+)
+
+// The server is developed along with the serialutils package
+replace github.com/arduino/go-serial-utils => ../
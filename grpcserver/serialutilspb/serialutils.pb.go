@@ -0,0 +1,807 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: serialutils.proto
+
+package serialutilspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Port struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Name            string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	IsUsb           bool                   `protobuf:"varint,2,opt,name=is_usb,json=isUsb,proto3" json:"is_usb,omitempty"`
+	Vid             string                 `protobuf:"bytes,3,opt,name=vid,proto3" json:"vid,omitempty"`
+	Pid             string                 `protobuf:"bytes,4,opt,name=pid,proto3" json:"pid,omitempty"`
+	SerialNumber    string                 `protobuf:"bytes,5,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	Product         string                 `protobuf:"bytes,6,opt,name=product,proto3" json:"product,omitempty"`
+	FriendlyName    string                 `protobuf:"bytes,7,opt,name=friendly_name,json=friendlyName,proto3" json:"friendly_name,omitempty"`
+	Driver          string                 `protobuf:"bytes,8,opt,name=driver,proto3" json:"driver,omitempty"`
+	Manufacturer    string                 `protobuf:"bytes,9,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	InterfaceName   string                 `protobuf:"bytes,10,opt,name=interface_name,json=interfaceName,proto3" json:"interface_name,omitempty"`
+	InterfaceNumber string                 `protobuf:"bytes,11,opt,name=interface_number,json=interfaceNumber,proto3" json:"interface_number,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Port) Reset() {
+	*x = Port{}
+	mi := &file_serialutils_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Port) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Port) ProtoMessage() {}
+
+func (x *Port) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Port.ProtoReflect.Descriptor instead.
+func (*Port) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Port) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Port) GetIsUsb() bool {
+	if x != nil {
+		return x.IsUsb
+	}
+	return false
+}
+
+func (x *Port) GetVid() string {
+	if x != nil {
+		return x.Vid
+	}
+	return ""
+}
+
+func (x *Port) GetPid() string {
+	if x != nil {
+		return x.Pid
+	}
+	return ""
+}
+
+func (x *Port) GetSerialNumber() string {
+	if x != nil {
+		return x.SerialNumber
+	}
+	return ""
+}
+
+func (x *Port) GetProduct() string {
+	if x != nil {
+		return x.Product
+	}
+	return ""
+}
+
+func (x *Port) GetFriendlyName() string {
+	if x != nil {
+		return x.FriendlyName
+	}
+	return ""
+}
+
+func (x *Port) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *Port) GetManufacturer() string {
+	if x != nil {
+		return x.Manufacturer
+	}
+	return ""
+}
+
+func (x *Port) GetInterfaceName() string {
+	if x != nil {
+		return x.InterfaceName
+	}
+	return ""
+}
+
+func (x *Port) GetInterfaceNumber() string {
+	if x != nil {
+		return x.InterfaceNumber
+	}
+	return ""
+}
+
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_serialutils_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{1}
+}
+
+type ListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ports         []*Port                `protobuf:"bytes,1,rep,name=ports,proto3" json:"ports,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_serialutils_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListResponse) GetPorts() []*Port {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// interval is the polling interval, the default is used if not set.
+	Interval      *durationpb.Duration `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_serialutils_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WatchRequest) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+type WatchResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// type is "add", "remove" or "error".
+	Type          string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Port          *Port  `protobuf:"bytes,2,opt,name=port,proto3" json:"port,omitempty"`
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_serialutils_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WatchResponse) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetPort() *Port {
+	if x != nil {
+		return x.Port
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ResetRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Port               string                 `protobuf:"bytes,1,opt,name=port,proto3" json:"port,omitempty"`
+	Wait               bool                   `protobuf:"varint,2,opt,name=wait,proto3" json:"wait,omitempty"`
+	Timeout            *durationpb.Duration   `protobuf:"bytes,3,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	WaitForMassStorage bool                   `protobuf:"varint,4,opt,name=wait_for_mass_storage,json=waitForMassStorage,proto3" json:"wait_for_mass_storage,omitempty"`
+	VerifyBootloader   bool                   `protobuf:"varint,5,opt,name=verify_bootloader,json=verifyBootloader,proto3" json:"verify_bootloader,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ResetRequest) Reset() {
+	*x = ResetRequest{}
+	mi := &file_serialutils_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetRequest) ProtoMessage() {}
+
+func (x *ResetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetRequest.ProtoReflect.Descriptor instead.
+func (*ResetRequest) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResetRequest) GetPort() string {
+	if x != nil {
+		return x.Port
+	}
+	return ""
+}
+
+func (x *ResetRequest) GetWait() bool {
+	if x != nil {
+		return x.Wait
+	}
+	return false
+}
+
+func (x *ResetRequest) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+func (x *ResetRequest) GetWaitForMassStorage() bool {
+	if x != nil {
+		return x.WaitForMassStorage
+	}
+	return false
+}
+
+func (x *ResetRequest) GetVerifyBootloader() bool {
+	if x != nil {
+		return x.VerifyBootloader
+	}
+	return false
+}
+
+type ResetProgress struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// type is "touching", "waiting", "wait-progress", "found" or "debug".
+	Type      string               `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Port      string               `protobuf:"bytes,2,opt,name=port,proto3" json:"port,omitempty"`
+	Elapsed   *durationpb.Duration `protobuf:"bytes,3,opt,name=elapsed,proto3" json:"elapsed,omitempty"`
+	Remaining *durationpb.Duration `protobuf:"bytes,4,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	Message   string               `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	// details are the details of the port touched or found, for the
+	// "touching" and "found" messages.
+	Details *Port `protobuf:"bytes,6,opt,name=details,proto3" json:"details,omitempty"`
+	// board is the name of the board guessed from the details.
+	Board         string `protobuf:"bytes,7,opt,name=board,proto3" json:"board,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetProgress) Reset() {
+	*x = ResetProgress{}
+	mi := &file_serialutils_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetProgress) ProtoMessage() {}
+
+func (x *ResetProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetProgress.ProtoReflect.Descriptor instead.
+func (*ResetProgress) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ResetProgress) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ResetProgress) GetPort() string {
+	if x != nil {
+		return x.Port
+	}
+	return ""
+}
+
+func (x *ResetProgress) GetElapsed() *durationpb.Duration {
+	if x != nil {
+		return x.Elapsed
+	}
+	return nil
+}
+
+func (x *ResetProgress) GetRemaining() *durationpb.Duration {
+	if x != nil {
+		return x.Remaining
+	}
+	return nil
+}
+
+func (x *ResetProgress) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ResetProgress) GetDetails() *Port {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *ResetProgress) GetBoard() string {
+	if x != nil {
+		return x.Board
+	}
+	return ""
+}
+
+type ResetResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// kind is "none", "serial-port", "mass-storage-volume", "dfu-device"
+	// or "hid-device".
+	Kind string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Path string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	// bootloader is the kind of bootloader verified on the port, if requested.
+	Bootloader string `protobuf:"bytes,3,opt,name=bootloader,proto3" json:"bootloader,omitempty"`
+	// skipped tells that the board doesn't need a reset.
+	Skipped       bool `protobuf:"varint,4,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetResult) Reset() {
+	*x = ResetResult{}
+	mi := &file_serialutils_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetResult) ProtoMessage() {}
+
+func (x *ResetResult) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetResult.ProtoReflect.Descriptor instead.
+func (*ResetResult) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ResetResult) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ResetResult) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ResetResult) GetBootloader() string {
+	if x != nil {
+		return x.Bootloader
+	}
+	return ""
+}
+
+func (x *ResetResult) GetSkipped() bool {
+	if x != nil {
+		return x.Skipped
+	}
+	return false
+}
+
+type ResetResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*ResetResponse_Progress
+	//	*ResetResponse_Result
+	Message       isResetResponse_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetResponse) Reset() {
+	*x = ResetResponse{}
+	mi := &file_serialutils_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetResponse) ProtoMessage() {}
+
+func (x *ResetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_serialutils_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetResponse.ProtoReflect.Descriptor instead.
+func (*ResetResponse) Descriptor() ([]byte, []int) {
+	return file_serialutils_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ResetResponse) GetMessage() isResetResponse_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *ResetResponse) GetProgress() *ResetProgress {
+	if x != nil {
+		if x, ok := x.Message.(*ResetResponse_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+func (x *ResetResponse) GetResult() *ResetResult {
+	if x != nil {
+		if x, ok := x.Message.(*ResetResponse_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isResetResponse_Message interface {
+	isResetResponse_Message()
+}
+
+type ResetResponse_Progress struct {
+	Progress *ResetProgress `protobuf:"bytes,1,opt,name=progress,proto3,oneof"`
+}
+
+type ResetResponse_Result struct {
+	Result *ResetResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*ResetResponse_Progress) isResetResponse_Message() {}
+
+func (*ResetResponse_Result) isResetResponse_Message() {}
+
+var File_serialutils_proto protoreflect.FileDescriptor
+
+const file_serialutils_proto_rawDesc = "" +
+	"\n" +
+	"\x11serialutils.proto\x12\x16arduino.serialutils.v1\x1a\x1egoogle/protobuf/duration.proto\"\xc7\x02\n" +
+	"\x04Port\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x15\n" +
+	"\x06is_usb\x18\x02 \x01(\bR\x05isUsb\x12\x10\n" +
+	"\x03vid\x18\x03 \x01(\tR\x03vid\x12\x10\n" +
+	"\x03pid\x18\x04 \x01(\tR\x03pid\x12#\n" +
+	"\rserial_number\x18\x05 \x01(\tR\fserialNumber\x12\x18\n" +
+	"\aproduct\x18\x06 \x01(\tR\aproduct\x12#\n" +
+	"\rfriendly_name\x18\a \x01(\tR\ffriendlyName\x12\x16\n" +
+	"\x06driver\x18\b \x01(\tR\x06driver\x12\"\n" +
+	"\fmanufacturer\x18\t \x01(\tR\fmanufacturer\x12%\n" +
+	"\x0einterface_name\x18\n" +
+	" \x01(\tR\rinterfaceName\x12)\n" +
+	"\x10interface_number\x18\v \x01(\tR\x0finterfaceNumber\"\r\n" +
+	"\vListRequest\"B\n" +
+	"\fListResponse\x122\n" +
+	"\x05ports\x18\x01 \x03(\v2\x1c.arduino.serialutils.v1.PortR\x05ports\"E\n" +
+	"\fWatchRequest\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\"k\n" +
+	"\rWatchResponse\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x120\n" +
+	"\x04port\x18\x02 \x01(\v2\x1c.arduino.serialutils.v1.PortR\x04port\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xcb\x01\n" +
+	"\fResetRequest\x12\x12\n" +
+	"\x04port\x18\x01 \x01(\tR\x04port\x12\x12\n" +
+	"\x04wait\x18\x02 \x01(\bR\x04wait\x123\n" +
+	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x121\n" +
+	"\x15wait_for_mass_storage\x18\x04 \x01(\bR\x12waitForMassStorage\x12+\n" +
+	"\x11verify_bootloader\x18\x05 \x01(\bR\x10verifyBootloader\"\x8d\x02\n" +
+	"\rResetProgress\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04port\x18\x02 \x01(\tR\x04port\x123\n" +
+	"\aelapsed\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\aelapsed\x127\n" +
+	"\tremaining\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\tremaining\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x126\n" +
+	"\adetails\x18\x06 \x01(\v2\x1c.arduino.serialutils.v1.PortR\adetails\x12\x14\n" +
+	"\x05board\x18\a \x01(\tR\x05board\"o\n" +
+	"\vResetResult\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x1e\n" +
+	"\n" +
+	"bootloader\x18\x03 \x01(\tR\n" +
+	"bootloader\x12\x18\n" +
+	"\askipped\x18\x04 \x01(\bR\askipped\"\x9e\x01\n" +
+	"\rResetResponse\x12C\n" +
+	"\bprogress\x18\x01 \x01(\v2%.arduino.serialutils.v1.ResetProgressH\x00R\bprogress\x12=\n" +
+	"\x06result\x18\x02 \x01(\v2#.arduino.serialutils.v1.ResetResultH\x00R\x06resultB\t\n" +
+	"\amessage2\x90\x02\n" +
+	"\vSerialUtils\x12Q\n" +
+	"\x04List\x12#.arduino.serialutils.v1.ListRequest\x1a$.arduino.serialutils.v1.ListResponse\x12V\n" +
+	"\x05Watch\x12$.arduino.serialutils.v1.WatchRequest\x1a%.arduino.serialutils.v1.WatchResponse0\x01\x12V\n" +
+	"\x05Reset\x12$.arduino.serialutils.v1.ResetRequest\x1a%.arduino.serialutils.v1.ResetResponse0\x01B=Z;github.com/arduino/go-serial-utils/grpcserver/serialutilspbb\x06proto3"
+
+var (
+	file_serialutils_proto_rawDescOnce sync.Once
+	file_serialutils_proto_rawDescData []byte
+)
+
+func file_serialutils_proto_rawDescGZIP() []byte {
+	file_serialutils_proto_rawDescOnce.Do(func() {
+		file_serialutils_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_serialutils_proto_rawDesc), len(file_serialutils_proto_rawDesc)))
+	})
+	return file_serialutils_proto_rawDescData
+}
+
+var file_serialutils_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_serialutils_proto_goTypes = []any{
+	(*Port)(nil),                // 0: arduino.serialutils.v1.Port
+	(*ListRequest)(nil),         // 1: arduino.serialutils.v1.ListRequest
+	(*ListResponse)(nil),        // 2: arduino.serialutils.v1.ListResponse
+	(*WatchRequest)(nil),        // 3: arduino.serialutils.v1.WatchRequest
+	(*WatchResponse)(nil),       // 4: arduino.serialutils.v1.WatchResponse
+	(*ResetRequest)(nil),        // 5: arduino.serialutils.v1.ResetRequest
+	(*ResetProgress)(nil),       // 6: arduino.serialutils.v1.ResetProgress
+	(*ResetResult)(nil),         // 7: arduino.serialutils.v1.ResetResult
+	(*ResetResponse)(nil),       // 8: arduino.serialutils.v1.ResetResponse
+	(*durationpb.Duration)(nil), // 9: google.protobuf.Duration
+}
+var file_serialutils_proto_depIdxs = []int32{
+	0,  // 0: arduino.serialutils.v1.ListResponse.ports:type_name -> arduino.serialutils.v1.Port
+	9,  // 1: arduino.serialutils.v1.WatchRequest.interval:type_name -> google.protobuf.Duration
+	0,  // 2: arduino.serialutils.v1.WatchResponse.port:type_name -> arduino.serialutils.v1.Port
+	9,  // 3: arduino.serialutils.v1.ResetRequest.timeout:type_name -> google.protobuf.Duration
+	9,  // 4: arduino.serialutils.v1.ResetProgress.elapsed:type_name -> google.protobuf.Duration
+	9,  // 5: arduino.serialutils.v1.ResetProgress.remaining:type_name -> google.protobuf.Duration
+	0,  // 6: arduino.serialutils.v1.ResetProgress.details:type_name -> arduino.serialutils.v1.Port
+	6,  // 7: arduino.serialutils.v1.ResetResponse.progress:type_name -> arduino.serialutils.v1.ResetProgress
+	7,  // 8: arduino.serialutils.v1.ResetResponse.result:type_name -> arduino.serialutils.v1.ResetResult
+	1,  // 9: arduino.serialutils.v1.SerialUtils.List:input_type -> arduino.serialutils.v1.ListRequest
+	3,  // 10: arduino.serialutils.v1.SerialUtils.Watch:input_type -> arduino.serialutils.v1.WatchRequest
+	5,  // 11: arduino.serialutils.v1.SerialUtils.Reset:input_type -> arduino.serialutils.v1.ResetRequest
+	2,  // 12: arduino.serialutils.v1.SerialUtils.List:output_type -> arduino.serialutils.v1.ListResponse
+	4,  // 13: arduino.serialutils.v1.SerialUtils.Watch:output_type -> arduino.serialutils.v1.WatchResponse
+	8,  // 14: arduino.serialutils.v1.SerialUtils.Reset:output_type -> arduino.serialutils.v1.ResetResponse
+	12, // [12:15] is the sub-list for method output_type
+	9,  // [9:12] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_serialutils_proto_init() }
+func file_serialutils_proto_init() {
+	if File_serialutils_proto != nil {
+		return
+	}
+	file_serialutils_proto_msgTypes[8].OneofWrappers = []any{
+		(*ResetResponse_Progress)(nil),
+		(*ResetResponse_Result)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_serialutils_proto_rawDesc), len(file_serialutils_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_serialutils_proto_goTypes,
+		DependencyIndexes: file_serialutils_proto_depIdxs,
+		MessageInfos:      file_serialutils_proto_msgTypes,
+	}.Build()
+	File_serialutils_proto = out.File
+	file_serialutils_proto_goTypes = nil
+	file_serialutils_proto_depIdxs = nil
+}
@@ -0,0 +1,229 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: serialutils.proto
+
+package serialutilspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SerialUtils_List_FullMethodName  = "/arduino.serialutils.v1.SerialUtils/List"
+	SerialUtils_Watch_FullMethodName = "/arduino.serialutils.v1.SerialUtils/Watch"
+	SerialUtils_Reset_FullMethodName = "/arduino.serialutils.v1.SerialUtils/Reset"
+)
+
+// SerialUtilsClient is the client API for SerialUtils service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SerialUtils lists the serial ports and resets the boards connected to them.
+type SerialUtilsClient interface {
+	// List returns the available serial ports.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Watch streams the ports added and removed until the call is canceled.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error)
+	// Reset resets the board on a port, streaming the progress and the result.
+	Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResetResponse], error)
+}
+
+type serialUtilsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSerialUtilsClient(cc grpc.ClientConnInterface) SerialUtilsClient {
+	return &serialUtilsClient{cc}
+}
+
+func (c *serialUtilsClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, SerialUtils_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serialUtilsClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SerialUtils_ServiceDesc.Streams[0], SerialUtils_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SerialUtils_WatchClient = grpc.ServerStreamingClient[WatchResponse]
+
+func (c *serialUtilsClient) Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResetResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SerialUtils_ServiceDesc.Streams[1], SerialUtils_Reset_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ResetRequest, ResetResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SerialUtils_ResetClient = grpc.ServerStreamingClient[ResetResponse]
+
+// SerialUtilsServer is the server API for SerialUtils service.
+// All implementations must embed UnimplementedSerialUtilsServer
+// for forward compatibility.
+//
+// SerialUtils lists the serial ports and resets the boards connected to them.
+type SerialUtilsServer interface {
+	// List returns the available serial ports.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Watch streams the ports added and removed until the call is canceled.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error
+	// Reset resets the board on a port, streaming the progress and the result.
+	Reset(*ResetRequest, grpc.ServerStreamingServer[ResetResponse]) error
+	mustEmbedUnimplementedSerialUtilsServer()
+}
+
+// UnimplementedSerialUtilsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSerialUtilsServer struct{}
+
+func (UnimplementedSerialUtilsServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedSerialUtilsServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchResponse]) error {
+	return status.Error(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedSerialUtilsServer) Reset(*ResetRequest, grpc.ServerStreamingServer[ResetResponse]) error {
+	return status.Error(codes.Unimplemented, "method Reset not implemented")
+}
+func (UnimplementedSerialUtilsServer) mustEmbedUnimplementedSerialUtilsServer() {}
+func (UnimplementedSerialUtilsServer) testEmbeddedByValue()                     {}
+
+// UnsafeSerialUtilsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SerialUtilsServer will
+// result in compilation errors.
+type UnsafeSerialUtilsServer interface {
+	mustEmbedUnimplementedSerialUtilsServer()
+}
+
+func RegisterSerialUtilsServer(s grpc.ServiceRegistrar, srv SerialUtilsServer) {
+	// If the following call panics, it indicates UnimplementedSerialUtilsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SerialUtils_ServiceDesc, srv)
+}
+
+func _SerialUtils_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SerialUtilsServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SerialUtils_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SerialUtilsServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SerialUtils_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SerialUtilsServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SerialUtils_WatchServer = grpc.ServerStreamingServer[WatchResponse]
+
+func _SerialUtils_Reset_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ResetRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SerialUtilsServer).Reset(m, &grpc.GenericServerStream[ResetRequest, ResetResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SerialUtils_ResetServer = grpc.ServerStreamingServer[ResetResponse]
+
+// SerialUtils_ServiceDesc is the grpc.ServiceDesc for SerialUtils service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SerialUtils_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "arduino.serialutils.v1.SerialUtils",
+	HandlerType: (*SerialUtilsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _SerialUtils_List_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _SerialUtils_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Reset",
+			Handler:       _SerialUtils_Reset_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "serialutils.proto",
+}
@@ -0,0 +1,230 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+// Package grpcserver implements the SerialUtils gRPC service, defined in
+// serialutilspb/serialutils.proto, on top of the serialutils package.
+//
+// It is a separate Go module, so that the serialutils module doesn't depend
+// on google.golang.org/grpc. Register a Server on a grpc.Server with:
+//
+//	serialutilspb.RegisterSerialUtilsServer(grpcServer, &grpcserver.Server{})
+package grpcserver
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative serialutilspb/serialutils.proto
+
+import (
+	"context"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"github.com/arduino/go-serial-utils/grpcserver/serialutilspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// DefaultWatchInterval is the polling interval of Watch if the request does
+// not specify one.
+var DefaultWatchInterval = time.Second
+
+// Server implements the SerialUtils service. The zero value is ready to use.
+type Server struct {
+	serialutilspb.UnimplementedSerialUtilsServer
+
+	// DetailedPortsMapper is used to list the ports, if nil the
+	// DefaultDetailedPortMapper is used.
+	DetailedPortsMapper serialutils.DetailedPortsMapper
+	// ResetOptions, if not nil, are the base options of the resets: the
+	// settings of the ResetRequest override them.
+	ResetOptions *serialutils.ResetOptions
+}
+
+var _ serialutilspb.SerialUtilsServer = (*Server)(nil)
+
+func (s *Server) mapper() serialutils.DetailedPortsMapper {
+	if s.DetailedPortsMapper != nil {
+		return s.DetailedPortsMapper
+	}
+	return serialutils.DefaultDetailedPortMapper
+}
+
+// List returns the available serial ports, sorted in natural order.
+func (s *Server) List(ctx context.Context, req *serialutilspb.ListRequest) (*serialutilspb.ListResponse, error) {
+	ports, err := s.mapper()()
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "listing ports: %v", err)
+	}
+	res := &serialutilspb.ListResponse{}
+	for _, port := range serialutils.SortPorts(ports) {
+		port := port
+		res.Ports = append(res.Ports, portToPB(&port))
+	}
+	return res, nil
+}
+
+// Watch streams the port events until the stream context is canceled or a
+// Send fails.
+func (s *Server) Watch(req *serialutilspb.WatchRequest, stream grpc.ServerStreamingServer[serialutilspb.WatchResponse]) error {
+	interval := req.GetInterval().AsDuration()
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ctx := stream.Context()
+	events := make(chan serialutils.PortEvent, 16)
+	w := serialutils.WatchPorts(s.mapper(), interval, func(ev serialutils.PortEvent) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	})
+	defer w.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case ev := <-events:
+			msg := &serialutilspb.WatchResponse{Type: ev.Type.String()}
+			if ev.Port != nil {
+				msg.Port = portToPB(ev.Port)
+			}
+			if ev.Err != nil {
+				msg.Error = ev.Err.Error()
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Reset resets the board on the requested port, streaming its progress and,
+// as the last message, its result. The reset is canceled if the stream
+// context is canceled.
+func (s *Server) Reset(req *serialutilspb.ResetRequest, stream grpc.ServerStreamingServer[serialutilspb.ResetResponse]) error {
+	if req.GetPort() == "" {
+		return status.Error(codes.InvalidArgument, "missing port")
+	}
+	var opts serialutils.ResetOptions
+	if s.ResetOptions != nil {
+		opts = *s.ResetOptions
+	}
+	if opts.DetailedPortsMapper == nil {
+		opts.DetailedPortsMapper = s.DetailedPortsMapper
+	}
+	opts.Wait = req.GetWait()
+	if timeout := req.GetTimeout().AsDuration(); timeout > 0 {
+		opts.Timeout = timeout
+	}
+	opts.WaitForMassStorage = opts.WaitForMassStorage || req.GetWaitForMassStorage()
+	opts.VerifyBootloader = opts.VerifyBootloader || req.GetVerifyBootloader()
+
+	// The callbacks are called by the reset goroutine only, so the sends
+	// never overlap with the final one
+	var sendErr error
+	send := func(p *serialutilspb.ResetProgress) {
+		if sendErr == nil {
+			sendErr = stream.Send(&serialutilspb.ResetResponse{
+				Message: &serialutilspb.ResetResponse_Progress{Progress: p},
+			})
+		}
+	}
+	var found *serialutils.PortDetails
+	opts.Callbacks = &serialutils.ResetProgressCallbacks{
+		TouchingPortDetails: func(details *serialutils.PortDetails) {
+			send(&serialutilspb.ResetProgress{Type: "touching", Port: details.Name, Details: portToPB(details), Board: boardName(details)})
+		},
+		WaitingForNewSerial: func() {
+			send(&serialutilspb.ResetProgress{Type: "waiting"})
+		},
+		WaitProgress: func(elapsed, remaining time.Duration) {
+			send(&serialutilspb.ResetProgress{Type: "wait-progress", Elapsed: durationpb.New(elapsed), Remaining: durationpb.New(remaining)})
+		},
+		// The details of the bootloader port found are reported just before
+		// the port itself
+		BootloaderPortFoundDetails: func(details *serialutils.PortDetails) {
+			found = details
+		},
+		BootloaderPortFound: func(port string) {
+			p := &serialutilspb.ResetProgress{Type: "found", Port: port}
+			if found != nil {
+				p.Details, p.Board = portToPB(found), boardName(found)
+			}
+			send(p)
+		},
+		Debug: func(msg string) {
+			send(&serialutilspb.ResetProgress{Type: "debug", Message: msg})
+		},
+	}
+
+	h := serialutils.ResetAsync(req.GetPort(), &opts)
+	select {
+	case <-h.Done():
+	case <-stream.Context().Done():
+		h.Cancel()
+	}
+	res, err := h.Result()
+	if err != nil {
+		if ctxErr := stream.Context().Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		return status.Errorf(codes.Unknown, "resetting board: %v", err)
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	return stream.Send(&serialutilspb.ResetResponse{
+		Message: &serialutilspb.ResetResponse_Result{Result: resultToPB(res)},
+	})
+}
+
+// portToPB converts the port details to their protobuf message.
+func portToPB(port *serialutils.PortDetails) *serialutilspb.Port {
+	return &serialutilspb.Port{
+		Name:            port.Name,
+		IsUsb:           port.IsUSB,
+		Vid:             port.VID,
+		Pid:             port.PID,
+		SerialNumber:    port.SerialNumber,
+		Product:         port.Product,
+		FriendlyName:    port.FriendlyName,
+		Driver:          port.Driver,
+		Manufacturer:    port.Manufacturer,
+		InterfaceName:   port.InterfaceName,
+		InterfaceNumber: port.InterfaceNumber,
+	}
+}
+
+// resultToPB converts the result of a reset to its protobuf message.
+func resultToPB(res *serialutils.ResetResult) *serialutilspb.ResetResult {
+	msg := &serialutilspb.ResetResult{
+		Kind:    res.Target.Kind.String(),
+		Path:    res.Target.Path,
+		Skipped: res.Skipped,
+	}
+	if res.Bootloader != nil {
+		msg.Bootloader = res.Bootloader.Kind.String()
+	}
+	return msg
+}
+
+// boardName returns the name of the board guessed from the port details, or
+// the empty string if unknown.
+func boardName(details *serialutils.PortDetails) string {
+	if board := serialutils.GuessBoard(*details); board != nil {
+		return board.String()
+	}
+	return ""
+}
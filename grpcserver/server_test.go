@@ -0,0 +1,166 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"github.com/arduino/go-serial-utils/grpcserver/serialutilspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newTestClient serves the Server on an in-memory connection and returns a
+// client connected to it.
+func newTestClient(t *testing.T, s *Server) serialutilspb.SerialUtilsClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	serialutilspb.RegisterSerialUtilsServer(grpcServer, s)
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return serialutilspb.NewSerialUtilsClient(conn)
+}
+
+// testPorts is a DetailedPortsMapper whose ports can be changed by the test.
+type testPorts struct {
+	mux   sync.Mutex
+	ports map[string]*serialutils.PortDetails
+}
+
+func (p *testPorts) add(port *serialutils.PortDetails) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.ports[port.Name] = port
+}
+
+func (p *testPorts) mapper() (map[string]*serialutils.PortDetails, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	res := map[string]*serialutils.PortDetails{}
+	for name, port := range p.ports {
+		details := *port
+		res[name] = &details
+	}
+	return res, nil
+}
+
+func TestList(t *testing.T) {
+	ports := &testPorts{ports: map[string]*serialutils.PortDetails{
+		"/dev/ttyACM10": {Name: "/dev/ttyACM10"},
+		"/dev/ttyACM2":  {Name: "/dev/ttyACM2", IsUSB: true, VID: "2341", PID: "8036", SerialNumber: "ABC"},
+	}}
+	client := newTestClient(t, &Server{DetailedPortsMapper: ports.mapper})
+	res, err := client.List(context.Background(), &serialutilspb.ListRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetPorts()) != 2 {
+		t.Fatalf("got %d ports, want 2", len(res.GetPorts()))
+	}
+	first := res.GetPorts()[0]
+	if first.GetName() != "/dev/ttyACM2" || !first.GetIsUsb() || first.GetVid() != "2341" || first.GetSerialNumber() != "ABC" {
+		t.Errorf("unexpected first port %v", first)
+	}
+	if res.GetPorts()[1].GetName() != "/dev/ttyACM10" {
+		t.Errorf("ports not in natural order: %v", res.GetPorts())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ports := &testPorts{ports: map[string]*serialutils.PortDetails{}}
+	client := newTestClient(t, &Server{DetailedPortsMapper: ports.mapper})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &serialutilspb.WatchRequest{Interval: durationpb.New(10 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports.add(&serialutils.PortDetails{Name: "/dev/ttyACM0"})
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.GetType() != serialutils.PortAdded.String() || ev.GetPort().GetName() != "/dev/ttyACM0" {
+		t.Errorf("unexpected event %v", ev)
+	}
+}
+
+func TestReset(t *testing.T) {
+	client := newTestClient(t, &Server{ResetOptions: &serialutils.ResetOptions{
+		Simulator: &serialutils.Simulator{Scenario: serialutils.ScenarioNewPort, BootloaderPort: "/dev/ttyACM1"},
+	}})
+	stream, err := client.Reset(context.Background(), &serialutilspb.ResetRequest{Port: "/dev/ttyACM0", Wait: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	var result *serialutilspb.ResetResult
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := msg.GetProgress(); p != nil && p.GetType() != "debug" && p.GetType() != "wait-progress" {
+			types = append(types, p.GetType())
+		}
+		if r := msg.GetResult(); r != nil {
+			result = r
+		}
+	}
+	if result == nil || result.GetKind() != serialutils.SerialPort.String() || result.GetPath() != "/dev/ttyACM1" {
+		t.Fatalf("unexpected result %v", result)
+	}
+	want := []string{"touching", "waiting", "found"}
+	if len(types) != len(want) {
+		t.Fatalf("got progress %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("got progress %v, want %v", types, want)
+		}
+	}
+}
+
+func TestResetMissingPort(t *testing.T) {
+	client := newTestClient(t, &Server{})
+	stream, err := client.Reset(context.Background(), &serialutilspb.ResetRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("reset without port accepted")
+	}
+}
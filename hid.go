@@ -0,0 +1,61 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "strings"
+
+// usbClassHID is the USB class of the HID interfaces.
+const usbClassHID = 0x03
+
+// KnownHIDBootloaderIDs are the USB IDs of the known HID bootloaders, used
+// by HIDDiscoverer if no IDs are given.
+var KnownHIDBootloaderIDs = []USBID{
+	{"16C0", "0478"}, // Teensy HalfKay
+	{"1209", "BEBA"}, // STM32 HID bootloader
+}
+
+// IsHIDDevice returns true if the USB device has a HID interface.
+func IsHIDDevice(d *USBDevice) bool {
+	for _, intf := range d.Interfaces {
+		if intf.Class == usbClassHID {
+			return true
+		}
+	}
+	return false
+}
+
+// HIDDiscoverer returns a TargetDiscoverer of the HID bootloaders with the
+// given USB IDs (KnownHIDBootloaderIDs if none), like the Teensy HalfKay
+// bootloader. The targets found are of kind HIDDevice and their path is the
+// "bus:address" of the device, the details of the device are reported in
+// ResetTarget.USB. It is supported only on Linux.
+func HIDDiscoverer(ids ...USBID) TargetDiscoverer {
+	if len(ids) == 0 {
+		ids = KnownHIDBootloaderIDs
+	}
+	match := func(d *USBDevice) bool {
+		if !IsHIDDevice(d) {
+			return false
+		}
+		for _, id := range ids {
+			if strings.EqualFold(id.VID, d.VID) && strings.EqualFold(id.PID, d.PID) {
+				return true
+			}
+		}
+		return false
+	}
+	return &usbDeviceDiscoverer{kind: HIDDevice, match: match}
+}
@@ -0,0 +1,76 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"sync"
+	"time"
+)
+
+// PortHistory records when the ports have been seen for the last time. It
+// is used by ResetWithOptions to exclude from the new ports detection the
+// ports that were momentarily missing when the reset started (see
+// ResetOptions.GracePeriod).
+type PortHistory struct {
+	mux      sync.Mutex
+	clock    Clock
+	lastSeen map[string]time.Time
+}
+
+// NewPortHistory returns an empty PortHistory. If clock is nil the
+// SystemClock is used.
+func NewPortHistory(clock Clock) *PortHistory {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &PortHistory{clock: clock, lastSeen: map[string]time.Time{}}
+}
+
+// Observe records the ports as seen now.
+func (h *PortHistory) Observe(ports map[string]bool) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	now := h.clock.Now()
+	for port := range ports {
+		h.lastSeen[port] = now
+	}
+}
+
+// PortsMapper returns a PortsMapper wrapping mapper that records all the
+// ports listed in the history.
+func (h *PortHistory) PortsMapper(mapper PortsMapper) PortsMapper {
+	return func() (map[string]bool, error) {
+		ports, err := mapper()
+		if err == nil {
+			h.Observe(ports)
+		}
+		return ports, err
+	}
+}
+
+// SeenWithin returns the ports seen during the last d.
+func (h *PortHistory) SeenWithin(d time.Duration) map[string]bool {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	since := h.clock.Now().Add(-d)
+	res := map[string]bool{}
+	for port, seen := range h.lastSeen {
+		if !seen.Before(since) {
+			res[port] = true
+		}
+	}
+	return res
+}
@@ -0,0 +1,47 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ResetHook is a function called before or after the reset of a port, it can
+// be used to drive external hardware (relays, GPIO lines, etc.) needed to put
+// some boards in bootloader mode.
+type ResetHook func(port string) error
+
+// CommandHook returns a ResetHook that runs an external command. Every
+// occurrence of "{port}" in the arguments is replaced with the port name.
+func CommandHook(name string, args ...string) ResetHook {
+	return func(port string) error {
+		cmdArgs := make([]string, len(args))
+		for i, arg := range args {
+			cmdArgs[i] = strings.ReplaceAll(arg, "{port}", port)
+		}
+		cmd := exec.Command(name, cmdArgs...)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("running %s: %w: %s", name, err, strings.TrimSpace(output.String()))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,453 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+// Package httpserver provides an embeddable HTTP handler exposing the port
+// enumeration, the board reset and the port events of the serialutils
+// package, for the web-based IDE agents.
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+// DefaultWatchInterval is the polling interval of the port watcher if the
+// Handler does not specify one.
+var DefaultWatchInterval = time.Second
+
+// DefaultMaxTimeout is the maximum wait timeout accepted by /reset if the
+// Handler does not specify one.
+var DefaultMaxTimeout = time.Minute
+
+// Handler is an http.Handler serving:
+//   - GET /ports: the available ports, as a JSON array of PortDetails
+//   - POST /reset: resets the board on a port, the request body is a JSON
+//     ResetRequest and the response is the JSON ResetResult
+//   - GET /events: the port events and the reset progress, as server-sent
+//     events named "port" (a PortEvent) and "reset" (a ResetProgress). The
+//     ports already present are not reported, the clients should get them
+//     from /ports after subscribing.
+//   - GET /ws: the same events of /events on a WebSocket, as JSON text
+//     messages {"event": "port" or "reset", "data": ...}
+//
+// The requests are checked against the web pages attacking the local
+// services: the Host must be an IP address, localhost or one of the
+// AllowedHosts (against DNS rebinding), the state-changing requests and the
+// WebSocket handshake must come from an allowed Origin (see AllowedOrigins),
+// and the /reset body must have the application/json Content-Type, that the
+// browsers don't send cross-origin without a preflight request.
+//
+// It can be mounted under a prefix with http.StripPrefix. The zero value is
+// ready to use.
+type Handler struct {
+	// DetailedPortsMapper is used to list the ports, if nil the
+	// DefaultDetailedPortMapper is used.
+	DetailedPortsMapper serialutils.DetailedPortsMapper
+	// ResetOptions, if not nil, are the base options of the resets: the
+	// settings of the ResetRequest override them.
+	ResetOptions *serialutils.ResetOptions
+	// WatchInterval is the polling interval of the port watcher feeding the
+	// events, if zero DefaultWatchInterval is used.
+	WatchInterval time.Duration
+	// MaxTimeout is the maximum wait timeout accepted from the /reset
+	// requests, if zero DefaultMaxTimeout is used.
+	MaxTimeout time.Duration
+	// AllowRemotePorts accepts any port in the /reset requests. By default
+	// only the ports listed by the DetailedPortsMapper can be reset, so that
+	// the handler can't be used to connect to arbitrary hosts through the
+	// network transports (like rfc2217://).
+	AllowRemotePorts bool
+	// AllowedHosts are the host names accepted in the Host header, besides
+	// the IP addresses and localhost, for the handlers served under a domain
+	// name.
+	AllowedHosts []string
+	// AllowedOrigins are the values of the Origin header (e.g.
+	// "https://app.arduino.cc") accepted for the state-changing requests and
+	// the WebSocket, "*" accepts any origin. The same-origin requests are
+	// accepted if the Host is an IP address or localhost.
+	AllowedOrigins []string
+	// RequireOrigin rejects the state-changing requests without an Origin
+	// header. By default they are accepted, since the browsers always send
+	// the Origin of the cross-origin requests and the other clients, like
+	// the command line tools, don't send it at all.
+	RequireOrigin bool
+	// CheckOrigin, if not nil, replaces the AllowedOrigins and RequireOrigin
+	// checks: it returns true if the request can change the state.
+	CheckOrigin func(r *http.Request) bool
+
+	mux         sync.Mutex
+	subscribers map[chan *event]bool
+	watcher     *serialutils.PortWatcher
+}
+
+// ResetRequest is the body of a /reset request.
+type ResetRequest struct {
+	Port string `json:"port"`
+	Wait bool   `json:"wait"`
+	// Timeout is the maximum time to wait for the bootloader port, in the
+	// time.ParseDuration format (e.g. "10s").
+	Timeout            string `json:"timeout,omitempty"`
+	WaitForMassStorage bool   `json:"waitForMassStorage,omitempty"`
+	VerifyBootloader   bool   `json:"verifyBootloader,omitempty"`
+}
+
+// ResetProgress is the progress of a reset, sent as a "reset" event.
+type ResetProgress struct {
+	// Port is the port being reset.
+	Port string `json:"port"`
+	// Type is "touching", "waiting", "wait-progress", "found" or "done".
+	Type string `json:"type"`
+	// Target is the bootloader port found, for the "found" events.
+	Target string `json:"target,omitempty"`
+	// Details are the details of the port touched or found, for the
+	// "touching" and "found" events, and Board is the board guessed from
+	// them.
+	Details *serialutils.PortDetails `json:"details,omitempty"`
+	Board   *serialutils.BoardInfo   `json:"board,omitempty"`
+	// ElapsedMs and RemainingMs are the time elapsed and remaining during
+	// the wait, for the "wait-progress" events.
+	ElapsedMs   int64 `json:"elapsedMs,omitempty"`
+	RemainingMs int64 `json:"remainingMs,omitempty"`
+	// Error is the error message of a failed reset, for the "done" events.
+	Error string `json:"error,omitempty"`
+}
+
+// event is a named event with its JSON data.
+type event struct {
+	name string
+	data []byte
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.hostAllowed(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("host %s not allowed", r.Host))
+		return
+	}
+	switch r.URL.Path {
+	case "/ports":
+		h.servePorts(w, r)
+	case "/reset":
+		if !h.checkOrigin(w, r) {
+			return
+		}
+		h.serveReset(w, r)
+	case "/events":
+		h.serveEvents(w, r)
+	case "/ws":
+		if !h.checkOrigin(w, r) {
+			return
+		}
+		h.serveWebSocket(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// checkOrigin replies with 403 Forbidden and returns false if the request
+// comes from an origin that is not allowed.
+func (h *Handler) checkOrigin(w http.ResponseWriter, r *http.Request) bool {
+	if h.originAllowed(r) {
+		return true
+	}
+	writeError(w, http.StatusForbidden, fmt.Errorf("origin %s not allowed", r.Header.Get("Origin")))
+	return false
+}
+
+func (h *Handler) originAllowed(r *http.Request) bool {
+	if h.CheckOrigin != nil {
+		return h.CheckOrigin(r)
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return !h.RequireOrigin
+	}
+	for _, allowed := range h.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	// A domain name may be rebound to a local address by the attacker, the
+	// same-origin requests can be trusted only for the local names
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host && isLocalHost(hostName(r.Host))
+}
+
+// hostAllowed returns true if the Host header of the request is an IP
+// address, localhost or one of the AllowedHosts.
+func (h *Handler) hostAllowed(host string) bool {
+	name := hostName(host)
+	if isLocalHost(name) {
+		return true
+	}
+	for _, allowed := range h.AllowedHosts {
+		if strings.EqualFold(allowed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostName returns the host of a "host:port" address, without the port.
+func hostName(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return strings.Trim(host, "[]")
+}
+
+// isLocalHost returns true if the host name can't be rebound by DNS: an IP
+// address or localhost.
+func isLocalHost(name string) bool {
+	return net.ParseIP(name) != nil || strings.EqualFold(name, "localhost")
+}
+
+func (h *Handler) mapper() serialutils.DetailedPortsMapper {
+	if h.DetailedPortsMapper != nil {
+		return h.DetailedPortsMapper
+	}
+	return serialutils.DefaultDetailedPortMapper
+}
+
+func (h *Handler) servePorts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	ports, err := h.mapper()()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, serialutils.SortPorts(ports))
+}
+
+func (h *Handler) serveReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("content type %q not supported", r.Header.Get("Content-Type")))
+		return
+	}
+	var req ResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding reset request: %w", err))
+		return
+	}
+	if req.Port == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing port"))
+		return
+	}
+	var opts serialutils.ResetOptions
+	if h.ResetOptions != nil {
+		opts = *h.ResetOptions
+	}
+	if opts.DetailedPortsMapper == nil {
+		opts.DetailedPortsMapper = h.DetailedPortsMapper
+	}
+	opts.Wait = req.Wait
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout: %w", err))
+			return
+		}
+		maxTimeout := h.MaxTimeout
+		if maxTimeout == 0 {
+			maxTimeout = DefaultMaxTimeout
+		}
+		if timeout < 0 || timeout > maxTimeout {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("timeout %s out of range (max %s)", timeout, maxTimeout))
+			return
+		}
+		opts.Timeout = timeout
+	}
+	// The simulated and dry-run resets don't open the port
+	if !h.AllowRemotePorts && opts.Simulator == nil && !opts.DryRun {
+		if status, err := h.checkPort(req.Port, opts.DetailedPortsMapper); err != nil {
+			writeError(w, status, err)
+			return
+		}
+	}
+	opts.WaitForMassStorage = opts.WaitForMassStorage || req.WaitForMassStorage
+	opts.VerifyBootloader = opts.VerifyBootloader || req.VerifyBootloader
+	opts.Callbacks = h.resetCallbacks(req.Port)
+
+	handle := serialutils.ResetAsync(req.Port, &opts)
+	select {
+	case <-handle.Done():
+	case <-r.Context().Done():
+		handle.Cancel()
+	}
+	res, err := handle.Result()
+	done := &ResetProgress{Port: req.Port, Type: "done"}
+	if err != nil {
+		done.Error = err.Error()
+	}
+	h.publish("reset", done)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// checkPort returns an error, with its status code, if the port is not listed
+// by the mapper.
+func (h *Handler) checkPort(port string, mapper serialutils.DetailedPortsMapper) (int, error) {
+	if mapper == nil {
+		mapper = serialutils.DefaultDetailedPortMapper
+	}
+	ports, err := mapper()
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("listing ports: %w", err)
+	}
+	if _, ok := ports[serialutils.NormalizePortName(port)]; !ok {
+		return http.StatusNotFound, fmt.Errorf("port %s not found", port)
+	}
+	return 0, nil
+}
+
+// resetCallbacks returns the callbacks publishing the progress of the reset
+// of the given port.
+func (h *Handler) resetCallbacks(port string) *serialutils.ResetProgressCallbacks {
+	// The details of the bootloader port found are reported just before
+	// the port itself, by the reset goroutine
+	var found *serialutils.PortDetails
+	return &serialutils.ResetProgressCallbacks{
+		TouchingPortDetails: func(details *serialutils.PortDetails) {
+			h.publish("reset", &ResetProgress{Port: port, Type: "touching", Details: details, Board: serialutils.GuessBoard(*details)})
+		},
+		WaitingForNewSerial: func() {
+			h.publish("reset", &ResetProgress{Port: port, Type: "waiting"})
+		},
+		WaitProgress: func(elapsed, remaining time.Duration) {
+			h.publish("reset", &ResetProgress{Port: port, Type: "wait-progress", ElapsedMs: elapsed.Milliseconds(), RemainingMs: remaining.Milliseconds()})
+		},
+		BootloaderPortFoundDetails: func(details *serialutils.PortDetails) {
+			found = details
+		},
+		BootloaderPortFound: func(target string) {
+			ev := &ResetProgress{Port: port, Type: "found", Target: target}
+			if found != nil {
+				ev.Details, ev.Board = found, serialutils.GuessBoard(*found)
+			}
+			h.publish("reset", ev)
+		},
+	}
+}
+
+func (h *Handler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+	events := h.subscribe()
+	defer h.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// subscribe registers a new events subscriber, starting the port watcher if
+// it is the first one.
+func (h *Handler) subscribe() chan *event {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	events := make(chan *event, 64)
+	if h.subscribers == nil {
+		h.subscribers = map[chan *event]bool{}
+	}
+	h.subscribers[events] = true
+	if h.watcher == nil {
+		interval := h.WatchInterval
+		if interval == 0 {
+			interval = DefaultWatchInterval
+		}
+		h.watcher = serialutils.WatchPorts(h.mapper(), interval, func(ev serialutils.PortEvent) {
+			h.publish("port", ev)
+		})
+	}
+	return events
+}
+
+// unsubscribe removes an events subscriber, stopping the port watcher if it
+// was the last one.
+func (h *Handler) unsubscribe(events chan *event) {
+	h.mux.Lock()
+	delete(h.subscribers, events)
+	var watcher *serialutils.PortWatcher
+	if len(h.subscribers) == 0 {
+		watcher, h.watcher = h.watcher, nil
+	}
+	h.mux.Unlock()
+
+	// Close waits for the watcher goroutine, that may be publishing
+	if watcher != nil {
+		watcher.Close()
+	}
+}
+
+// publish sends an event to all the subscribers. The events are dropped for
+// the subscribers that are not keeping up.
+func (h *Handler) publish(name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	ev := &event{name: name, data: data}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	for events := range h.subscribers {
+		select {
+		case events <- ev:
+		default:
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,113 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+func serve(h *Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func resetRequest(host, origin, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "http://"+host+"/reset", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	return r
+}
+
+func TestHostCheck(t *testing.T) {
+	h := &Handler{
+		DetailedPortsMapper: func() (map[string]*serialutils.PortDetails, error) {
+			return map[string]*serialutils.PortDetails{}, nil
+		},
+		AllowedHosts: []string{"serial.example.com"},
+	}
+	for host, status := range map[string]int{
+		"127.0.0.1:8080":         http.StatusOK,
+		"[::1]:8080":             http.StatusOK,
+		"localhost:8080":         http.StatusOK,
+		"serial.example.com":     http.StatusOK,
+		"attacker.example.com":   http.StatusForbidden,
+		"rebound.example.com:80": http.StatusForbidden,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://"+host+"/ports", nil)
+		if w := serve(h, r); w.Code != status {
+			t.Errorf("host %s: got status %d, want %d", host, w.Code, status)
+		}
+	}
+}
+
+func TestOriginCheck(t *testing.T) {
+	h := &Handler{
+		ResetOptions:   &serialutils.ResetOptions{DryRun: true},
+		AllowedHosts:   []string{"serial.example.com"},
+		AllowedOrigins: []string{"https://app.arduino.cc"},
+	}
+	for _, test := range []struct {
+		host, origin string
+		status       int
+	}{
+		{"localhost:8080", "", http.StatusOK},
+		{"localhost:8080", "https://app.arduino.cc", http.StatusOK},
+		{"localhost:8080", "http://localhost:8080", http.StatusOK},
+		{"localhost:8080", "https://attacker.example.com", http.StatusForbidden},
+		// The same-origin rule is not trusted for the domain names
+		{"serial.example.com", "http://serial.example.com", http.StatusForbidden},
+	} {
+		r := resetRequest(test.host, test.origin, `{"port":"/dev/ttyACM0"}`)
+		if w := serve(h, r); w.Code != test.status {
+			t.Errorf("host %s origin %q: got status %d, want %d", test.host, test.origin, w.Code, test.status)
+		}
+	}
+
+	h.RequireOrigin = true
+	if w := serve(h, resetRequest("localhost", "", `{"port":"/dev/ttyACM0"}`)); w.Code != http.StatusForbidden {
+		t.Errorf("request without origin: got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestResetRequestValidation(t *testing.T) {
+	h := &Handler{
+		DetailedPortsMapper: func() (map[string]*serialutils.PortDetails, error) {
+			return map[string]*serialutils.PortDetails{
+				"/dev/ttyACM0": {Name: "/dev/ttyACM0"},
+			}, nil
+		},
+		ResetOptions: &serialutils.ResetOptions{SkipReset: true},
+	}
+	for body, status := range map[string]int{
+		`{"port":"/dev/ttyACM0"}`:                        http.StatusOK,
+		`{"port":"rfc2217://attacker.example.com:2217"}`: http.StatusNotFound,
+		`{"port":"/dev/ttyACM0","timeout":"30s"}`:        http.StatusOK,
+		`{"port":"/dev/ttyACM0","timeout":"2h"}`:         http.StatusBadRequest,
+		`{"port":"/dev/ttyACM0","timeout":"-1s"}`:        http.StatusBadRequest,
+	} {
+		if w := serve(h, resetRequest("localhost", "", body)); w.Code != status {
+			t.Errorf("%s: got status %d, want %d (%s)", body, w.Code, status, w.Body)
+		}
+	}
+}
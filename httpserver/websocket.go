@@ -0,0 +1,210 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package httpserver
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The WebSocket protocol is implemented here, rather than with one of the
+// WebSocket packages, to keep the module dependencies to the minimum: the
+// handler only sends unfragmented text messages and answers the control
+// frames of the client, a small subset of RFC 6455 covered by the tests.
+
+// websocketGUID is the GUID used to compute the Sec-WebSocket-Accept header,
+// as defined in RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// The WebSocket frame opcodes.
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// websocketMessage is the JSON message sent on the WebSocket for every event.
+type websocketMessage struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// serveWebSocket streams the events on a WebSocket: every event is sent as
+// a text message {"event": "port" or "reset", "data": ...}, with the same
+// data of the server-sent events. The messages sent by the client are
+// ignored.
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("not a websocket handshake"))
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported websocket handshake"))
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("websocket not supported"))
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	events := h.subscribe()
+	defer h.unsubscribe(events)
+
+	ws := &websocketConn{conn: conn}
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		ws.readLoop(rw.Reader)
+	}()
+	for {
+		select {
+		case <-closed:
+			return
+		case ev := <-events:
+			msg, _ := json.Marshal(&websocketMessage{Event: ev.name, Data: ev.data})
+			if err := ws.writeFrame(wsOpText, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// websocketConn is the server side of a WebSocket connection.
+type websocketConn struct {
+	conn    io.Writer
+	writeMu sync.Mutex
+}
+
+// writeFrame writes an unfragmented and unmasked frame.
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// readLoop reads the client frames, answering the pings, until the
+// connection is closed by the client or fails.
+func (c *websocketConn) readLoop(r *bufio.Reader) {
+	for {
+		opcode, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpClose:
+			_ = c.writeFrame(wsOpClose, payload)
+			return
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFrame reads a client frame, returning its opcode and unmasked payload.
+// The client frames must be masked, as required by RFC 6455.
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, fmt.Errorf("unmasked websocket frame")
+	}
+	size := uint64(header[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	// The clients only send small control messages
+	if size > 1<<20 {
+		return 0, nil, fmt.Errorf("websocket frame too large: %d bytes", size)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// headerContains returns true if the comma separated values of the header
+// contain the given token, ignoring the case.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+// maskedFrame encodes a client frame, masked as required by RFC 6455.
+func maskedFrame(opcode byte, payload []byte) []byte {
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame decodes an unmasked server frame.
+func readServerFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	if header[0]&0x80 == 0 {
+		return 0, nil, fmt.Errorf("fragmented frame")
+	}
+	if header[1]&0x80 != 0 {
+		return 0, nil, fmt.Errorf("masked server frame")
+	}
+	size := uint64(header[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, size)
+	_, err := io.ReadFull(r, payload)
+	return header[0] & 0x0F, payload, err
+}
+
+func TestReadFrame(t *testing.T) {
+	for _, size := range []int{0, 5, 125, 126, 0xFFFF, 0x10000} {
+		payload := bytes.Repeat([]byte("abc"), size/3+1)[:size]
+		opcode, got, err := readFrame(bufio.NewReader(bytes.NewReader(maskedFrame(wsOpPing, payload))))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if opcode != wsOpPing || !bytes.Equal(got, payload) {
+			t.Errorf("size %d: got opcode %d and %d bytes", size, opcode, len(got))
+		}
+	}
+
+	unmasked := []byte{0x80 | wsOpText, 2, 'h', 'i'}
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader(unmasked))); err == nil {
+		t.Error("unmasked frame accepted")
+	}
+	tooLarge := binary.BigEndian.AppendUint64([]byte{0x80 | wsOpText, 0x80 | 127}, 1<<21)
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader(tooLarge))); err == nil {
+		t.Error("too large frame accepted")
+	}
+	truncated := maskedFrame(wsOpText, []byte("hello"))[:8]
+	if _, _, err := readFrame(bufio.NewReader(bytes.NewReader(truncated))); err == nil {
+		t.Error("truncated frame accepted")
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		var buf bytes.Buffer
+		payload := bytes.Repeat([]byte{'x'}, size)
+		if err := (&websocketConn{conn: &buf}).writeFrame(wsOpText, payload); err != nil {
+			t.Fatal(err)
+		}
+		opcode, got, err := readServerFrame(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if opcode != wsOpText || !bytes.Equal(got, payload) {
+			t.Errorf("size %d: got opcode %d and %d bytes", size, opcode, len(got))
+		}
+		if buf.Len() != 0 {
+			t.Errorf("size %d: %d trailing bytes", size, buf.Len())
+		}
+	}
+}
+
+// dialWebSocket makes the WebSocket handshake with the server, using the
+// sample key of RFC 6455, and returns the response.
+func dialWebSocket(t *testing.T, server *httptest.Server, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, r, resp
+}
+
+func newTestServer(t *testing.T, h *Handler) *httptest.Server {
+	if h.DetailedPortsMapper == nil {
+		h.DetailedPortsMapper = func() (map[string]*serialutils.PortDetails, error) {
+			return map[string]*serialutils.PortDetails{}, nil
+		}
+	}
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebSocketEvents(t *testing.T) {
+	h := &Handler{}
+	server := newTestServer(t, h)
+	conn, r, resp := dialWebSocket(t, server, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake failed: %s", resp.Status)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("wrong Sec-WebSocket-Accept %q", accept)
+	}
+
+	// The pong is sent after the subscription to the events
+	if _, err := conn.Write(maskedFrame(wsOpPing, []byte("sync"))); err != nil {
+		t.Fatal(err)
+	}
+	opcode, payload, err := readServerFrame(r)
+	if err != nil || opcode != wsOpPong || string(payload) != "sync" {
+		t.Fatalf("expected pong, got opcode %d %q: %v", opcode, payload, err)
+	}
+
+	h.publish("reset", &ResetProgress{Port: "/dev/ttyACM0", Type: "waiting"})
+	opcode, payload, err = readServerFrame(r)
+	if err != nil || opcode != wsOpText {
+		t.Fatalf("expected text message, got opcode %d: %v", opcode, err)
+	}
+	var msg struct {
+		Event string        `json:"event"`
+		Data  ResetProgress `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Event != "reset" || msg.Data.Port != "/dev/ttyACM0" || msg.Data.Type != "waiting" {
+		t.Errorf("unexpected message %s", payload)
+	}
+
+	if _, err := conn.Write(maskedFrame(wsOpClose, []byte{0x03, 0xE8})); err != nil {
+		t.Fatal(err)
+	}
+	opcode, payload, err = readServerFrame(r)
+	if err != nil || opcode != wsOpClose || !bytes.Equal(payload, []byte{0x03, 0xE8}) {
+		t.Fatalf("expected close echo, got opcode %d %q: %v", opcode, payload, err)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("connection not closed: %v", err)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	server := newTestServer(t, &Handler{AllowedOrigins: []string{"https://app.arduino.cc"}})
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"https://app.arduino.cc", http.StatusSwitchingProtocols},
+		{"http://" + strings.TrimPrefix(server.URL, "http://"), http.StatusSwitchingProtocols},
+		{"https://evil.example", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		_, _, resp := dialWebSocket(t, server, test.origin)
+		if resp.StatusCode != test.status {
+			t.Errorf("origin %q: got %s, want %d", test.origin, resp.Status, test.status)
+		}
+	}
+
+	server = newTestServer(t, &Handler{CheckOrigin: func(r *http.Request) bool { return false }})
+	if _, _, resp := dialWebSocket(t, server, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("CheckOrigin ignored: got %s", resp.Status)
+	}
+}
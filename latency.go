@@ -0,0 +1,267 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// LatencyOptions contains the parameters of MeasureResetLatencyWithOptions.
+type LatencyOptions struct {
+	// Runs is the number of resets performed, 5 if zero.
+	Runs int
+	// Timeout is the maximum time to wait for the port to disappear, for the
+	// new port to appear and for the board to come back before the next
+	// run, 10 seconds if zero.
+	Timeout time.Duration
+	// PollInterval is the interval between two enumerations of the ports,
+	// 10 ms if zero. The resolution of the measures is bounded by it, and by
+	// the time taken by the enumeration.
+	PollInterval time.Duration
+	// PortsMapper is used to list the ports, if nil the DefaultPortMapper is
+	// used.
+	PortsMapper PortsMapper
+	// Clock is used for the timing, if nil the SystemClock is used.
+	Clock Clock
+	// Debug, if not nil, is called with debugging messages.
+	Debug func(msg string)
+}
+
+// LatencyRun is the outcome of a single reset of MeasureResetLatency.
+type LatencyRun struct {
+	// Disappear is the time taken by the port to disappear after the reset
+	// started, zero if it didn't disappear.
+	Disappear time.Duration `json:"disappear"`
+	// Reenumerate is the time taken by the new port to appear after the reset
+	// started, zero if none appeared.
+	Reenumerate time.Duration `json:"reenumerate"`
+	// Port is the port appeared after the reset (it may be the same port).
+	Port string `json:"port,omitempty"`
+	// Error is the reason of the failure of the run, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// LatencyStats are the statistics of a set of durations.
+type LatencyStats struct {
+	Count  int           `json:"count"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	Mean   time.Duration `json:"mean"`
+	Median time.Duration `json:"median"`
+	StdDev time.Duration `json:"stdDev"`
+}
+
+func (s LatencyStats) String() string {
+	if s.Count == 0 {
+		return "no samples"
+	}
+	return fmt.Sprintf("min %v, max %v, mean %v, median %v, stddev %v (%d samples)", s.Min, s.Max, s.Mean, s.Median, s.StdDev, s.Count)
+}
+
+// LatencyReport is the result of MeasureResetLatency.
+type LatencyReport struct {
+	Runs []LatencyRun `json:"runs"`
+	// Disappear and Reenumerate are the statistics of the successful runs.
+	Disappear   LatencyStats `json:"disappear"`
+	Reenumerate LatencyStats `json:"reenumerate"`
+	// Failures is the number of runs failed.
+	Failures int `json:"failures"`
+}
+
+// MeasureResetLatency resets the board on the port several times with the
+// given strategy (the 1200-bps touch if nil), timing how long the port takes
+// to disappear and the new port to appear, and reports the statistics. It
+// is a diagnostic tool, useful to tune the reset profiles and to debug the
+// flaky boards. Before every run it waits for the port to come back.
+func MeasureResetLatency(port string, strategy Resetter) (*LatencyReport, error) {
+	return MeasureResetLatencyWithOptions(port, strategy, nil)
+}
+
+// MeasureResetLatencyWithOptions is like MeasureResetLatency but uses the
+// given LatencyOptions.
+func MeasureResetLatencyWithOptions(port string, strategy Resetter, opts *LatencyOptions) (*LatencyReport, error) {
+	if opts == nil {
+		opts = &LatencyOptions{}
+	}
+	m := &latencyMeter{
+		port:         NormalizePortName(port),
+		strategy:     strategy,
+		runs:         opts.Runs,
+		timeout:      opts.Timeout,
+		pollInterval: opts.PollInterval,
+		portsMapper:  opts.PortsMapper,
+		clock:        opts.Clock,
+		debug:        opts.Debug,
+	}
+	if m.strategy == nil {
+		m.strategy = Touch1200bpsResetter
+	}
+	if m.runs == 0 {
+		m.runs = 5
+	}
+	if m.timeout == 0 {
+		m.timeout = 10 * time.Second
+	}
+	if m.pollInterval == 0 {
+		m.pollInterval = 10 * time.Millisecond
+	}
+	if m.portsMapper == nil {
+		m.portsMapper = DefaultPortMapper
+	}
+	if m.clock == nil {
+		m.clock = SystemClock
+	}
+
+	res := &LatencyReport{}
+	var disappear, reenumerate []time.Duration
+	for i := 0; i < m.runs; i++ {
+		if ok, err := m.waitFor(func(ports map[string]bool) bool { return ports[m.port] }); err != nil {
+			return nil, err
+		} else if !ok {
+			if i == 0 {
+				return nil, fmt.Errorf("port %s not found", m.port)
+			}
+			return res, fmt.Errorf("port %s not back after run %d", m.port, i)
+		}
+		run, err := m.run()
+		if err != nil {
+			return nil, err
+		}
+		if m.debug != nil {
+			m.debug(fmt.Sprintf("RUN %d: disappear %v, reenumerate %v as %s %s", i+1, run.Disappear, run.Reenumerate, run.Port, run.Error))
+		}
+		res.Runs = append(res.Runs, *run)
+		if run.Error != "" {
+			res.Failures++
+			continue
+		}
+		disappear = append(disappear, run.Disappear)
+		reenumerate = append(reenumerate, run.Reenumerate)
+	}
+	res.Disappear = newLatencyStats(disappear)
+	res.Reenumerate = newLatencyStats(reenumerate)
+	return res, nil
+}
+
+type latencyMeter struct {
+	port         string
+	strategy     Resetter
+	runs         int
+	timeout      time.Duration
+	pollInterval time.Duration
+	portsMapper  PortsMapper
+	clock        Clock
+	debug        func(string)
+}
+
+// run performs and times a single reset.
+func (m *latencyMeter) run() (*LatencyRun, error) {
+	before, err := m.portsMapper()
+	if err != nil {
+		return nil, err
+	}
+	run := &LatencyRun{}
+	start := m.clock.Now()
+	if err := m.strategy.Reset(m.port); err != nil {
+		run.Error = err.Error()
+		return run, nil
+	}
+	gone, err := m.waitFor(func(ports map[string]bool) bool { return !ports[m.port] })
+	if err != nil {
+		return nil, err
+	}
+	if !gone {
+		run.Error = "the port did not disappear"
+		return run, nil
+	}
+	run.Disappear = m.clock.Now().Sub(start)
+	var found string
+	added, err := m.waitFor(func(ports map[string]bool) bool {
+		for port := range ports {
+			if !before[port] || port == m.port {
+				found = port
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		run.Error = "no port appeared after the reset"
+		return run, nil
+	}
+	run.Reenumerate = m.clock.Now().Sub(start)
+	run.Port = found
+	return run, nil
+}
+
+// waitFor polls the ports until the condition is satisfied or the timeout
+// expires.
+func (m *latencyMeter) waitFor(cond func(ports map[string]bool) bool) (bool, error) {
+	return waitForPorts(m.portsMapper, m.clock, m.timeout, m.pollInterval, cond)
+}
+
+// waitForPorts polls the ports until the condition is satisfied or the
+// timeout expires, the transient enumeration errors are ignored.
+func waitForPorts(portsMapper PortsMapper, clock Clock, timeout, interval time.Duration, cond func(ports map[string]bool) bool) (bool, error) {
+	deadline := clock.Now().Add(timeout)
+	for {
+		ports, err := portsMapper()
+		if err != nil && !errors.Is(err, errTransientEnumeration) {
+			return false, err
+		}
+		if err == nil && cond(ports) {
+			return true, nil
+		}
+		if !clock.Now().Before(deadline) {
+			return false, nil
+		}
+		clock.Sleep(interval)
+	}
+}
+
+// newLatencyStats returns the statistics of the durations.
+func newLatencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	res := LatencyStats{Count: len(sorted), Min: sorted[0], Max: sorted[len(sorted)-1]}
+	var sum float64
+	for _, d := range sorted {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(sorted))
+	var variance float64
+	for _, d := range sorted {
+		variance += (float64(d) - mean) * (float64(d) - mean)
+	}
+	res.Mean = time.Duration(mean)
+	res.StdDev = time.Duration(math.Sqrt(variance / float64(len(sorted))))
+	if n := len(sorted); n%2 == 1 {
+		res.Median = sorted[n/2]
+	} else {
+		res.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return res
+}
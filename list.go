@@ -0,0 +1,100 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "sort"
+
+// ListPorts returns the details of the available serial ports, sorted by
+// name in natural order (so that COM10 follows COM9), for the UIs that
+// render the list directly.
+func ListPorts() ([]PortDetails, error) {
+	ports, err := DefaultDetailedPortMapper()
+	if err != nil {
+		return nil, err
+	}
+	return SortPorts(ports), nil
+}
+
+// ListPortNames returns the names of the available serial ports, sorted in
+// natural order. It is the low-overhead alternative to ListPorts, for the
+// callers polling the port list frequently: on most OS listing the names
+// only is much cheaper than querying the USB details.
+func ListPortNames() ([]string, error) {
+	ports, err := DefaultPortMapper()
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(ports), nil
+}
+
+// SortPorts returns the ports of the map as a slice, sorted by name in
+// natural order.
+func SortPorts(ports map[string]*PortDetails) []PortDetails {
+	res := make([]PortDetails, 0, len(ports))
+	for _, port := range ports {
+		res = append(res, *port)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return NaturalLess(res[i].Name, res[j].Name) })
+	return res
+}
+
+// NaturalLess compares two strings in natural order: the runs of digits are
+// compared by their numeric value, so that "COM9" comes before "COM10".
+func NaturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, ra := splitDigits(a)
+			nb, rb := splitDigits(b)
+			// Compare the numbers by length first, ignoring the leading zeros
+			ta, tb := trimZeros(na), trimZeros(nb)
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+			if na != nb {
+				return len(na) < len(nb)
+			}
+			a, b = ra, rb
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
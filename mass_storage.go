@@ -0,0 +1,102 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// VolumesMapper is a function that returns a map of the mounted removable
+// volumes (the map keys are the mount paths).
+type VolumesMapper func() (map[string]bool, error)
+
+// DefaultVolumesMapper returns the removable volumes currently mounted using
+// the OS-specific enumeration.
+func DefaultVolumesMapper() (map[string]bool, error) {
+	volumes, err := nativeListRemovableVolumes()
+	if err != nil {
+		return nil, fmt.Errorf("listing removable volumes: %w", err)
+	}
+	res := map[string]bool{}
+	for _, volume := range volumes {
+		res[volume] = true
+	}
+	return res, nil
+}
+
+// IsUF2Volume returns true if the volume at the given path looks like an UF2
+// bootloader drive, i.e. it contains an INFO_UF2.TXT file.
+func IsUF2Volume(path string) bool {
+	info, err := os.Stat(filepath.Join(path, "INFO_UF2.TXT"))
+	return err == nil && !info.IsDir()
+}
+
+// WaitForMassStorageBootloader waits for a new removable volume to be mounted
+// and returns its path. This is the behavior of the UF2 bootloaders (RP2040,
+// SAMD UF2, etc.) that enumerate a drive instead of a serial port after the
+// 1200-bps touch.
+//
+// `before` is the list of volumes mounted before the reset, if nil the list
+// is taken when this function is called. If `requireUF2` is true only the
+// volumes containing an INFO_UF2.TXT file are considered. `volumesMapper` is
+// used to list the mounted volumes, if nil the DefaultVolumesMapper is used.
+//
+// If no new volume appears before the timeout expires the empty string is returned.
+func WaitForMassStorageBootloader(before map[string]bool, timeout time.Duration, requireUF2 bool, volumesMapper VolumesMapper) (string, error) {
+	if volumesMapper == nil {
+		volumesMapper = DefaultVolumesMapper
+	}
+	if before == nil {
+		var err error
+		if before, err = volumesMapper(); err != nil {
+			return "", err
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		volume, err := findNewVolume(before, requireUF2, volumesMapper)
+		if err != nil {
+			return "", err
+		}
+		if volume != "" {
+			return volume, nil
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return "", nil
+}
+
+// findNewVolume returns a volume that is not present in the `before` list, or
+// the empty string if there are none.
+func findNewVolume(before map[string]bool, requireUF2 bool, volumesMapper VolumesMapper) (string, error) {
+	now, err := volumesMapper()
+	if err != nil {
+		return "", err
+	}
+	for volume := range now {
+		if before[volume] {
+			continue
+		}
+		if requireUF2 && !IsUF2Volume(volume) {
+			continue
+		}
+		return volume, nil
+	}
+	return "", nil
+}
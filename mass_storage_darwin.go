@@ -0,0 +1,60 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// removableFilesystems are the filesystems of the USB mass storage devices,
+// like the bootloaders exposing a UF2 drive.
+var removableFilesystems = map[string]bool{
+	"msdos": true,
+	"exfat": true,
+}
+
+// nativeListRemovableVolumes lists the volumes mounted under /Volumes from a
+// removable media or with the filesystems of the USB mass storage devices.
+// The boot volume, the network mounts and the hidden mounts are skipped.
+func nativeListRemovableVolumes() ([]string, error) {
+	n, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, err
+	}
+	mounts := make([]unix.Statfs_t, n)
+	if n, err = unix.Getfsstat(mounts, unix.MNT_NOWAIT); err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, mount := range mounts[:n] {
+		path := unix.ByteSliceToString(mount.Mntonname[:])
+		if filepath.Dir(path) != "/Volumes" {
+			continue
+		}
+		if mount.Flags&unix.MNT_LOCAL == 0 || mount.Flags&(unix.MNT_ROOTFS|unix.MNT_DONTBROWSE) != 0 {
+			continue
+		}
+		fsType := strings.ToLower(unix.ByteSliceToString(mount.Fstypename[:]))
+		if mount.Flags&unix.MNT_REMOVABLE == 0 && !removableFilesystems[fsType] {
+			continue
+		}
+		res = append(res, path)
+	}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func nativeListRemovableVolumes() ([]string, error) {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		if isRemovableBlockDevice(filepath.Base(fields[0])) {
+			// Mount paths have spaces escaped as octal sequences
+			res = append(res, strings.ReplaceAll(fields[1], "\\040", " "))
+		}
+	}
+	return res, scanner.Err()
+}
+
+// isRemovableBlockDevice checks the "removable" attribute of the block device
+// (or of its parent device if the given one is a partition).
+func isRemovableBlockDevice(dev string) bool {
+	sysPath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", dev))
+	if err != nil {
+		return false
+	}
+	for _, p := range []string{sysPath, filepath.Dir(sysPath)} {
+		if data, err := os.ReadFile(filepath.Join(p, "removable")); err == nil {
+			return strings.TrimSpace(string(data)) == "1"
+		}
+	}
+	return false
+}
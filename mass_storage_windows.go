@@ -0,0 +1,42 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+func nativeListRemovableVolumes() ([]string, error) {
+	drives, err := windows.GetLogicalDrives()
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for i := 0; i < 26; i++ {
+		if drives&(1<<uint(i)) == 0 {
+			continue
+		}
+		root := string(rune('A'+i)) + ":\\"
+		rootPtr, err := windows.UTF16PtrFromString(root)
+		if err != nil {
+			continue
+		}
+		if windows.GetDriveType(rootPtr) == windows.DRIVE_REMOVABLE {
+			res = append(res, root)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,62 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "time"
+
+// The names of the metrics reported to the Metrics interface, following
+// the Prometheus naming conventions.
+const (
+	// MetricResetAttempts counts the calls to ResetWithOptions.
+	MetricResetAttempts = "serialutils_reset_attempts_total"
+	// MetricTouchFailures counts the failed board resets.
+	MetricTouchFailures = "serialutils_touch_failures_total"
+	// MetricWaitTimeouts counts the waits for the bootloader that timed out.
+	MetricWaitTimeouts = "serialutils_wait_timeouts_total"
+	// MetricWaitDuration is the histogram of the durations of the waits for
+	// the bootloader.
+	MetricWaitDuration = "serialutils_wait_duration_seconds"
+	// MetricPollDuration is the histogram of the durations of the port list
+	// polls during the wait, to catch the regressions in the polling cost.
+	MetricPollDuration = "serialutils_poll_duration_seconds"
+)
+
+// pollBudget is the expected maximum duration of a poll of the port list
+// during the wait, the slower polls are reported in the debug output.
+const pollBudget = 200 * time.Millisecond
+
+// Metrics is the interface used to export the metrics about the resets
+// outcomes, it can be easily backed by Prometheus counters and histograms.
+type Metrics interface {
+	// IncCounter increments the counter with the given name.
+	IncCounter(name string)
+	// ObserveDuration adds a sample to the histogram with the given name.
+	ObserveDuration(name string, d time.Duration)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) IncCounter(name string) {}
+
+func (noopMetrics) ObserveDuration(name string, d time.Duration) {}
+
+// metricsOf returns the Metrics of the options, or a no-op Metrics if not set.
+func metricsOf(opts *ResetOptions) Metrics {
+	if opts.Metrics == nil {
+		return noopMetrics{}
+	}
+	return opts.Metrics
+}
@@ -0,0 +1,186 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"github.com/arduino/go-serial-utils/serialutilstest"
+)
+
+// recordingMetrics is a Metrics recording the observed durations.
+type recordingMetrics struct {
+	mux       sync.Mutex
+	durations map[string][]time.Duration
+}
+
+func (m *recordingMetrics) IncCounter(name string) {}
+
+func (m *recordingMetrics) ObserveDuration(name string, d time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.durations == nil {
+		m.durations = map[string][]time.Duration{}
+	}
+	m.durations[name] = append(m.durations[name], d)
+}
+
+func (m *recordingMetrics) observed(name string) []time.Duration {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return append([]time.Duration{}, m.durations[name]...)
+}
+
+// manyPortsEnvironment returns an Environment with n ports already present,
+// where the board on /dev/ttyACM0 is reset to /dev/ttyACM1 after 1 second.
+func manyPortsEnvironment(n int) *serialutilstest.Environment {
+	ports := []string{"/dev/ttyACM0"}
+	for i := 0; i < n; i++ {
+		ports = append(ports, fmt.Sprintf("/dev/ttyUSB%d", i))
+	}
+	return serialutilstest.NewEnvironment(ports...).
+		RemovePortAt(500*time.Millisecond, "/dev/ttyACM0").
+		AddPortAt(time.Second, "/dev/ttyACM1")
+}
+
+func TestPollDurationWithinBudget(t *testing.T) {
+	env := manyPortsEnvironment(64)
+	metrics := &recordingMetrics{}
+	var slow []string
+	opts := env.ResetOptions()
+	opts.Wait = true
+	opts.Metrics = metrics
+	opts.Callbacks = &serialutils.ResetProgressCallbacks{
+		Debug: func(msg string) {
+			if strings.HasPrefix(msg, "SLOW:") {
+				slow = append(slow, msg)
+			}
+		},
+	}
+	res, err := serialutils.ResetWithOptions("/dev/ttyACM0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Target.Path != "/dev/ttyACM1" {
+		t.Fatalf("found %q, want /dev/ttyACM1", res.Target.Path)
+	}
+	polls := metrics.observed(serialutils.MetricPollDuration)
+	if len(polls) == 0 {
+		t.Fatal("no poll duration observed")
+	}
+	for _, d := range polls {
+		if d > serialutils.PollBudget {
+			t.Errorf("poll took %v, over the budget of %v", d, serialutils.PollBudget)
+		}
+	}
+	if len(slow) > 0 {
+		t.Errorf("unexpected slow polls reported: %v", slow)
+	}
+}
+
+func TestSlowPollReported(t *testing.T) {
+	env := manyPortsEnvironment(0)
+	mapper := env.PortsMapper()
+	polls := 0
+	var slow []string
+	opts := env.ResetOptions()
+	opts.Wait = true
+	// The first call lists the ports before the reset, the second one is
+	// the first poll of the wait
+	opts.PortsMapper = func() (map[string]bool, error) {
+		polls++
+		if polls == 2 {
+			time.Sleep(serialutils.PollBudget + 10*time.Millisecond)
+		}
+		return mapper()
+	}
+	opts.Callbacks = &serialutils.ResetProgressCallbacks{
+		Debug: func(msg string) {
+			if strings.HasPrefix(msg, "SLOW:") {
+				slow = append(slow, msg)
+			}
+		},
+	}
+	if _, err := serialutils.ResetWithOptions("/dev/ttyACM0", opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(slow) != 1 {
+		t.Errorf("got %d slow polls reported, want 1: %v", len(slow), slow)
+	}
+}
+
+// BenchmarkWaitForBootloader measures the cost of the poll loop of a reset,
+// on the fake ports of an Environment so that only the package overhead is
+// measured.
+func BenchmarkWaitForBootloader(b *testing.B) {
+	for _, n := range []int{0, 16, 256} {
+		b.Run(fmt.Sprintf("ports=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				opts := manyPortsEnvironment(n).ResetOptions()
+				opts.Wait = true
+				if _, err := serialutils.ResetWithOptions("/dev/ttyACM0", opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPortsMapper measures the cost of the fake mappers, the baseline
+// of BenchmarkWaitForBootloader.
+func BenchmarkPortsMapper(b *testing.B) {
+	env := manyPortsEnvironment(256)
+	b.Run("names", func(b *testing.B) {
+		mapper := env.PortsMapper()
+		for i := 0; i < b.N; i++ {
+			if _, err := mapper(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("detailed", func(b *testing.B) {
+		mapper := env.DetailedPortsMapper()
+		for i := 0; i < b.N; i++ {
+			if _, err := mapper(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkDefaultPortMapper measures the cost of the OS port enumeration,
+// like serial-list -bench.
+func BenchmarkDefaultPortMapper(b *testing.B) {
+	b.Run("names", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := serialutils.DefaultPortMapper(); err != nil {
+				b.Skip(err)
+			}
+		}
+	})
+	b.Run("detailed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := serialutils.DefaultDetailedPortMapper(); err != nil {
+				b.Skip(err)
+			}
+		}
+	})
+}
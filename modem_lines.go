@@ -0,0 +1,136 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"runtime"
+)
+
+// DTRMode selects how the DTR line is handled during the 1200-bps touch.
+type DTRMode int
+
+const (
+	// DTRPlatformDefault deasserts DTR before closing the port on all the
+	// platforms except Windows, where the native USB CDC drivers don't need
+	// it. On Windows DTR is deasserted anyway for the USB-serial bridges
+	// (CH340, CP210x, FTDI) whose drivers leave DTR asserted after the close,
+	// preventing the reset of some boards.
+	DTRPlatformDefault DTRMode = iota
+	// DTRDeassert always deasserts DTR before closing the port.
+	DTRDeassert
+	// DTRUntouched never changes the DTR line.
+	DTRUntouched
+)
+
+// RTSMode selects how the RTS line is handled during the 1200-bps touch.
+type RTSMode int
+
+const (
+	// RTSPlatformDefault leaves RTS untouched, unless the BridgeQuirks of
+	// the USB-serial bridge of the port define its handling.
+	RTSPlatformDefault RTSMode = iota
+	// RTSDeassert always deasserts RTS before closing the port.
+	RTSDeassert
+	// RTSToggle asserts RTS and deasserts it again, for the boards resetting
+	// on an RTS edge.
+	RTSToggle
+	// RTSUntouched never changes the RTS line.
+	RTSUntouched
+	// RTSDeassertWCH deasserts RTS for the WCH bridges (CH340, CH341,
+	// CH9102), since many clone boards using them wire the reset circuit to
+	// RTS, and leaves it untouched otherwise. It must be selected
+	// explicitly, since it changes the reset of the other boards using them.
+	RTSDeassertWCH
+)
+
+// wchBridges are the USB IDs of the WCH USB-serial bridges.
+var wchBridges = []USBID{
+	{"1A86", "7523"}, // CH340
+	{"1A86", "5523"}, // CH341
+	{"1A86", "55D4"}, // CH9102
+}
+
+// resolve returns the RTS handling to use for the port with the given
+// details (nil if not available), solving the RTSPlatformDefault and
+// RTSDeassertWCH modes.
+func (m RTSMode) resolve(details *PortDetails) RTSMode {
+	switch m {
+	case RTSPlatformDefault:
+		return RTSUntouched
+	case RTSDeassertWCH:
+		if MatchesAny(wchBridges, details) {
+			return RTSDeassert
+		}
+		return RTSUntouched
+	}
+	return m
+}
+
+// lookupPortDetails returns the details of the port, or nil if not available.
+func lookupPortDetails(port string, detailedPortsMapper DetailedPortsMapper) *PortDetails {
+	if detailedPortsMapper == nil {
+		detailedPortsMapper = DefaultDetailedPortMapper
+	}
+	ports, err := detailedPortsMapper()
+	if err != nil {
+		return nil
+	}
+	return ports[NormalizePortName(port)]
+}
+
+// lookupPortDetails returns the details of the port with the mapper of the
+// options, or nil if not available. The emulated resets (with a Simulator or
+// in dry-run mode) don't look at the ports of the system.
+func (opts *ResetOptions) lookupPortDetails(port string) *PortDetails {
+	if opts.Simulator != nil || opts.DryRun {
+		return nil
+	}
+	return lookupPortDetails(port, opts.DetailedPortsMapper)
+}
+
+// usbSerialBridges are the USB IDs of the common USB-serial bridge chips.
+var usbSerialBridges = []USBID{
+	{"1A86", "7523"}, // CH340
+	{"1A86", "5523"}, // CH341
+	{"1A86", "55D4"}, // CH9102
+	{"10C4", "EA60"}, // CP2102/CP2104
+	{"10C4", "EA70"}, // CP2105
+	{"0403", "6001"}, // FT232R
+	{"0403", "6010"}, // FT2232
+	{"0403", "6014"}, // FT232H
+	{"0403", "6015"}, // FT231X
+}
+
+// IsUSBSerialBridge returns true if the port belongs to a common USB-serial
+// bridge chip (CH340, CP210x, FTDI).
+func IsUSBSerialBridge(port *PortDetails) bool {
+	return MatchesAny(usbSerialBridges, port)
+}
+
+// deassertDTR tells if DTR must be deasserted during the touch of the port
+// with the given details (nil if not available).
+func (m DTRMode) deassertDTR(details *PortDetails) bool {
+	switch m {
+	case DTRDeassert:
+		return true
+	case DTRUntouched:
+		return false
+	}
+	if runtime.GOOS != "windows" {
+		return true
+	}
+	return IsUSBSerialBridge(details)
+}
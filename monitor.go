@@ -0,0 +1,136 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"sync"
+
+	"go.bug.st/serial"
+)
+
+// Monitor is a component using a serial port (like a serial monitor) that
+// can release the port when a reset needs it.
+type Monitor interface {
+	// Release stops the use of the port. The monitor may hand off its open
+	// port handle, in that case the 1200-bps touch is performed on it (see
+	// TouchOpenPort), otherwise it must close the port and return nil.
+	Release(port string) (serial.Port, error)
+	// Reconnect signals that the port can be used again. The board may be
+	// available at a different port than the one released.
+	Reconnect(port string)
+}
+
+// MonitorCoordinator keeps track of the Monitors using the ports, so that
+// the resets can ask them to release the ports and signal when they may
+// reconnect.
+type MonitorCoordinator struct {
+	mux      sync.Mutex
+	monitors map[string]*monitorRegistration
+}
+
+type monitorRegistration struct {
+	port    string
+	monitor Monitor
+	leased  bool
+}
+
+// NewMonitorCoordinator returns an empty MonitorCoordinator.
+func NewMonitorCoordinator() *MonitorCoordinator {
+	return &MonitorCoordinator{monitors: map[string]*monitorRegistration{}}
+}
+
+// Register registers the monitor using the port, replacing any other
+// monitor registered for it. The returned function unregisters it, wherever
+// the monitor moved after a reset.
+func (c *MonitorCoordinator) Register(port string, monitor Monitor) (unregister func()) {
+	reg := &monitorRegistration{port: NormalizePortName(port), monitor: monitor}
+	c.mux.Lock()
+	c.monitors[reg.port] = reg
+	c.mux.Unlock()
+	return func() {
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		if c.monitors[reg.port] == reg {
+			delete(c.monitors, reg.port)
+		}
+	}
+}
+
+// Acquire asks the monitor registered for the port, if any, to release it.
+// The returned lease must be completed with Done when the monitor may
+// reconnect. The lease is nil if no monitor is using the port.
+func (c *MonitorCoordinator) Acquire(port string) (*MonitorLease, error) {
+	c.mux.Lock()
+	reg := c.monitors[NormalizePortName(port)]
+	if reg == nil || reg.leased {
+		c.mux.Unlock()
+		return nil, nil
+	}
+	reg.leased = true
+	c.mux.Unlock()
+
+	handle, err := reg.monitor.Release(reg.port)
+	if err != nil {
+		c.mux.Lock()
+		reg.leased = false
+		c.mux.Unlock()
+		return nil, fmt.Errorf("releasing port from monitor: %w", err)
+	}
+	return &MonitorLease{coordinator: c, reg: reg, Port: handle}, nil
+}
+
+// MonitorLease is a port released by its Monitor, obtained with
+// MonitorCoordinator.Acquire.
+type MonitorLease struct {
+	// Port is the open port handed off by the monitor, nil if the monitor
+	// closed the port. It is owned by the lease holder.
+	Port        serial.Port
+	coordinator *MonitorCoordinator
+	reg         *monitorRegistration
+	once        sync.Once
+}
+
+// Done signals the monitor that it may reconnect to the given port (the
+// released port if empty), moving its registration there. Monitors
+// unregistered meanwhile are not signaled. It is safe to
+// call Done on a nil lease and more than once, only the first call has
+// effect.
+func (l *MonitorLease) Done(port string) {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() {
+		c, reg := l.coordinator, l.reg
+		if port == "" {
+			port = reg.port
+		}
+		port = NormalizePortName(port)
+		c.mux.Lock()
+		registered := c.monitors[reg.port] == reg
+		if registered {
+			delete(c.monitors, reg.port)
+			reg.port = port
+			c.monitors[port] = reg
+		}
+		reg.leased = false
+		c.mux.Unlock()
+		// The monitor may have been unregistered meanwhile
+		if registered {
+			reg.monitor.Reconnect(port)
+		}
+	})
+}
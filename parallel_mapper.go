@@ -0,0 +1,119 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PortDetailer returns the details of a single port.
+type PortDetailer func(port string) (*PortDetails, error)
+
+// DefaultPortDetailsTimeout is the time given to a PortDetailer to query a
+// port in a ParallelDetailedPortsMapper, if no other timeout is specified.
+var DefaultPortDetailsTimeout = 2 * time.Second
+
+// maxParallelPortDetails is the maximum number of ports queried at the same
+// time by a ParallelDetailedPortsMapper.
+const maxParallelPortDetails = 8
+
+// DefaultPortDetailer returns the details of a single port. On Linux they
+// are read from sysfs, on the other OS the port is looked up in the
+// DefaultDetailedPortMapper.
+func DefaultPortDetailer(port string) (*PortDetails, error) {
+	return nativePortDetails(NormalizePortName(port))
+}
+
+// ParallelDetailedPortsMapper returns a DetailedPortsMapper that lists the
+// ports with the names mapper (DefaultPortMapper if nil) and queries their
+// details concurrently with the detailer (DefaultPortDetailer if nil). The
+// query of a port is abandoned after the timeout (DefaultPortDetailsTimeout
+// if zero), so that a single unresponsive device doesn't delay the entire
+// list: such ports, and the ones whose query fails, are reported with their
+// name only.
+func ParallelDetailedPortsMapper(names PortsMapper, detailer PortDetailer, timeout time.Duration) DetailedPortsMapper {
+	if names == nil {
+		names = DefaultPortMapper
+	}
+	if detailer == nil {
+		detailer = DefaultPortDetailer
+	}
+	if timeout == 0 {
+		timeout = DefaultPortDetailsTimeout
+	}
+	return func() (map[string]*PortDetails, error) {
+		ports, err := names()
+		if err != nil {
+			return nil, err
+		}
+		var mux sync.Mutex
+		res := map[string]*PortDetails{}
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, maxParallelPortDetails)
+		for port := range ports {
+			wg.Add(1)
+			go func(port string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				details := queryPortDetails(detailer, port, timeout)
+				<-sem
+				mux.Lock()
+				res[port] = details
+				mux.Unlock()
+			}(port)
+		}
+		wg.Wait()
+		return res, nil
+	}
+}
+
+// queryPortDetails runs the detailer on the port, returning the port with
+// only the name set if it fails or doesn't complete within the timeout.
+func queryPortDetails(detailer PortDetailer, port string, timeout time.Duration) *PortDetails {
+	done := make(chan *PortDetails, 1)
+	go func() {
+		details, err := detailer(port)
+		if err != nil || details == nil {
+			details = &PortDetails{Name: port}
+		}
+		done <- details
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case details := <-done:
+		details.Name = port
+		return details
+	case <-t.C:
+		return &PortDetails{Name: port}
+	}
+}
+
+// lookupDefaultPortDetails returns the details of the port from the
+// DefaultDetailedPortMapper.
+func lookupDefaultPortDetails(port string) (*PortDetails, error) {
+	ports, err := DefaultDetailedPortMapper()
+	if err != nil {
+		return nil, err
+	}
+	details, ok := ports[port]
+	if !ok {
+		return nil, fmt.Errorf("port %s not found", port)
+	}
+	return details, nil
+}
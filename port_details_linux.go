@@ -0,0 +1,57 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nativePortDetails reads the details of the port from sysfs.
+func nativePortDetails(port string) (*PortDetails, error) {
+	device, err := filepath.EvalSymlinks(filepath.Join("/sys/class/tty", filepath.Base(port), "device"))
+	if err != nil {
+		return nil, err
+	}
+	res := &PortDetails{Name: port}
+	if driver, err := os.Readlink(filepath.Join(device, "driver")); err == nil {
+		res.Driver = filepath.Base(driver)
+	}
+	// Walk up from the USB interface to the USB device
+	for dir := device; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		if !sysfsUSBDeviceRegexp.MatchString(filepath.Base(dir)) {
+			continue
+		}
+		read := func(name string) string {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return ""
+			}
+			return strings.TrimSpace(string(data))
+		}
+		res.IsUSB = true
+		res.VID = strings.ToUpper(read("idVendor"))
+		res.PID = strings.ToUpper(read("idProduct"))
+		res.SerialNumber = read("serial")
+		res.Product = read("product")
+		break
+	}
+	if res.IsUSB {
+		nativeUSBDescriptors(res)
+	}
+	return res, nil
+}
@@ -0,0 +1,89 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PortID is the identity of a serial port: beside the name, that may change
+// across a reset, it carries the USB VID/PID, serial number and physical
+// location of the device, allowing to recognize the same device under a
+// different name.
+type PortID struct {
+	Name         string `json:"name"`
+	VID          string `json:"vid,omitempty"`
+	PID          string `json:"pid,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// Location is the physical USB location of the device, in the Linux
+	// sysfs format (e.g. "1-2.3"). It is available only on Linux.
+	Location string `json:"location,omitempty"`
+}
+
+// NewPortID returns the PortID of the port with the given details.
+func NewPortID(details *PortDetails) PortID {
+	return PortID{
+		Name:         details.Name,
+		VID:          strings.ToUpper(details.VID),
+		PID:          strings.ToUpper(details.PID),
+		SerialNumber: details.SerialNumber,
+		Location:     usbLocation(details.Name),
+	}
+}
+
+// ResolvePortID returns the PortID of the given port, using the
+// DefaultDetailedPortMapper to get its details.
+func ResolvePortID(port string) (PortID, error) {
+	ports, err := DefaultDetailedPortMapper()
+	if err != nil {
+		return PortID{}, err
+	}
+	details, ok := ports[port]
+	if !ok {
+		return PortID{}, fmt.Errorf("port %s not found", port)
+	}
+	return NewPortID(details), nil
+}
+
+// SameDevice returns true if the two ports belong to the same device. The
+// serial number and the USB location are compared if known by both ports,
+// otherwise the names are compared.
+func (id PortID) SameDevice(other PortID) bool {
+	if id.SerialNumber != "" && other.SerialNumber != "" {
+		return id.SerialNumber == other.SerialNumber
+	}
+	if id.Location != "" && other.Location != "" {
+		return id.Location == other.Location
+	}
+	return id.Name == other.Name
+}
+
+// USBID returns the USB VID/PID of the port, the zero USBID if unknown.
+func (id PortID) USBID() USBID {
+	return USBID{VID: id.VID, PID: id.PID}
+}
+
+func (id PortID) String() string {
+	if id.VID == "" {
+		return id.Name
+	}
+	res := fmt.Sprintf("%s (%s:%s", id.Name, id.VID, id.PID)
+	if id.SerialNumber != "" {
+		res += " " + id.SerialNumber
+	}
+	return res + ")"
+}
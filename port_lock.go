@@ -0,0 +1,126 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PortLockDir is the directory of the lock files used to coordinate the
+// resets of the same port across processes.
+var PortLockDir = filepath.Join(os.TempDir(), "arduino-serial-utils-locks")
+
+// LockPort locks the port for a reset, both in the process and across the
+// processes using this package (through a lock file in PortLockDir), so
+// that two tools running in parallel (like two arduino-cli invocations in
+// CI jobs) don't fight over one board. If the port is already locked it
+// fails with ErrResetInProgress, or waits for it to be unlocked if wait is
+// true, until the context is canceled. The returned function unlocks the
+// port. All the aliases of a port (like its /dev/serial/by-id symlinks)
+// share the same lock.
+//
+// The lock across processes is best-effort: if the lock file can't be
+// created, only the lock in the process is taken (ResetWithOptions reports
+// it through the Debug callback).
+func LockPort(ctx context.Context, port string, wait bool) (unlock func(), err error) {
+	return lockPort(ctx, port, wait, nil)
+}
+
+// lockPort is LockPort reporting the failures of the lock file to debug, if
+// not nil.
+func lockPort(ctx context.Context, port string, wait bool, debug func(string)) (unlock func(), err error) {
+	port = canonicalPortName(NormalizePortName(port))
+	unlockProcess, err := portLocks.lock(ctx, port, wait)
+	if err != nil {
+		return nil, err
+	}
+	unlockFile, err := lockPortFile(ctx, port, wait, debug)
+	if err != nil {
+		unlockProcess()
+		return nil, err
+	}
+	return func() {
+		unlockFile()
+		unlockProcess()
+	}, nil
+}
+
+// lockPortFile takes the lock file of the port. If the lock file can't be
+// used, the failure is reported to debug and a no-op unlock is returned.
+func lockPortFile(ctx context.Context, port string, wait bool, debug func(string)) (func(), error) {
+	noop := func() {}
+	failed := func(err error) (func(), error) {
+		if debug != nil {
+			debug(fmt.Sprintf("Could not use the lock file of %s, locking only in the process: %v", port, err))
+		}
+		return noop, nil
+	}
+	if err := os.MkdirAll(PortLockDir, 0o777); err != nil {
+		return failed(err)
+	}
+	f, err := os.OpenFile(filepath.Join(PortLockDir, portLockFileName(port)), os.O_CREATE|os.O_RDWR, 0o666)
+	if err != nil {
+		return failed(err)
+	}
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			_ = f.Close()
+			return failed(err)
+		}
+		if locked {
+			return func() {
+				_ = unlockFile(f)
+				_ = f.Close()
+			}, nil
+		}
+		if !wait {
+			_ = f.Close()
+			return nil, fmt.Errorf("%w (by another process)", ErrResetInProgress)
+		}
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// portLockFileName returns the name of the lock file of the (canonical) port
+// name: a hash of the name, that is unique for every port, prefixed by the
+// name with the characters not allowed in file names replaced, to tell the
+// lock files apart.
+func portLockFileName(port string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.TrimPrefix(port, "/dev/"))
+	if len(name) > 32 {
+		name = name[len(name)-32:]
+	}
+	hash := sha256.Sum256([]byte(port))
+	return name + "-" + hex.EncodeToString(hash[:8]) + ".lock"
+}
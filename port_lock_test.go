@@ -0,0 +1,166 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tempPortLockDir sets PortLockDir to a temporary directory for the test.
+func tempPortLockDir(t *testing.T) string {
+	dir := PortLockDir
+	PortLockDir = t.TempDir()
+	t.Cleanup(func() { PortLockDir = dir })
+	return PortLockDir
+}
+
+func TestPortLockFileName(t *testing.T) {
+	names := map[string]string{}
+	for _, port := range []string{"/dev/ttyACM0", "/dev/ttyacm0", "COM1", "com1", "a/b", "a_b", "rfc2217://h:1", "rfc2217___h_1"} {
+		name := portLockFileName(port)
+		if other, ok := names[name]; ok {
+			t.Errorf("%s and %s have the same lock file %s", port, other, name)
+		}
+		names[name] = port
+		if strings.ContainsAny(name, "/\\:") || !strings.HasSuffix(name, ".lock") {
+			t.Errorf("invalid lock file name %s for %s", name, port)
+		}
+		if portLockFileName(port) != name {
+			t.Errorf("lock file name of %s not stable", port)
+		}
+	}
+	if name := portLockFileName("/dev/serial/by-id/usb-Arduino_LLC_Arduino_Leonardo-if00"); len(name) > 64 {
+		t.Errorf("lock file name %s too long", name)
+	}
+}
+
+func TestLockPort(t *testing.T) {
+	tempPortLockDir(t)
+	unlock, err := LockPort(context.Background(), "/dev/ttyTEST0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockPort(context.Background(), "/dev/ttyTEST0", false); !errors.Is(err, ErrResetInProgress) {
+		t.Fatalf("got %v, want ErrResetInProgress", err)
+	}
+	other, err := LockPort(context.Background(), "/dev/ttyTEST1", false)
+	if err != nil {
+		t.Fatalf("locking another port: %v", err)
+	}
+	other()
+
+	// The waiting lock is taken when the port is unlocked
+	locked := make(chan error, 1)
+	go func() {
+		unlock, err := LockPort(context.Background(), "/dev/ttyTEST0", true)
+		if err == nil {
+			unlock()
+		}
+		locked <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	unlock()
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting lock not taken")
+	}
+}
+
+func TestLockPortAliases(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks not supported")
+	}
+	tempPortLockDir(t)
+	dir := t.TempDir()
+	device := filepath.Join(dir, "ttyACM0")
+	alias := filepath.Join(dir, "usb-Arduino_Leonardo-if00")
+	if err := os.WriteFile(device, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(device, alias); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := LockPort(context.Background(), device, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if _, err := LockPort(context.Background(), alias, false); !errors.Is(err, ErrResetInProgress) {
+		t.Fatalf("got %v, want ErrResetInProgress", err)
+	}
+}
+
+func TestLockPortOtherProcess(t *testing.T) {
+	dir := tempPortLockDir(t)
+	// A lock file locked through another file descriptor, like another
+	// process would do
+	f, err := os.OpenFile(filepath.Join(dir, portLockFileName(canonicalPortName("/dev/ttyTEST0"))), os.O_CREATE|os.O_RDWR, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if locked, err := tryLockFile(f); err != nil || !locked {
+		t.Fatalf("locking the file: %v, %v", locked, err)
+	}
+	if _, err := LockPort(context.Background(), "/dev/ttyTEST0", false); !errors.Is(err, ErrResetInProgress) || !strings.Contains(err.Error(), "another process") {
+		t.Fatalf("got %v, want ErrResetInProgress by another process", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := LockPort(ctx, "/dev/ttyTEST0", true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	_ = unlockFile(f)
+	unlock, err := LockPort(context.Background(), "/dev/ttyTEST0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
+
+func TestLockPortFileFailureReported(t *testing.T) {
+	// The lock directory can't be created under a regular file
+	dir := tempPortLockDir(t)
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	PortLockDir = filepath.Join(file, "locks")
+	var messages []string
+	unlock, err := lockPort(context.Background(), "/dev/ttyTEST0", false, func(msg string) { messages = append(messages, msg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The lock in the process is taken anyway
+	if _, err := LockPort(context.Background(), "/dev/ttyTEST0", false); !errors.Is(err, ErrResetInProgress) {
+		t.Errorf("got %v, want ErrResetInProgress", err)
+	}
+	unlock()
+	if len(messages) != 1 || !strings.Contains(messages[0], "lock file") {
+		t.Fatalf("lock file failure not reported: %v", messages)
+	}
+}
@@ -17,23 +17,110 @@ package serialutils
 
 import (
 	"fmt"
-
-	"go.bug.st/serial"
 )
 
 // PortsMapper is a function that returns a map of available serial ports.
 type PortsMapper func() (map[string]bool, error)
 
 // DefaultPortMapper returns a PortsMapper that lists the available serial ports
-// using the go.bug.st/serial library enumerator.
+// using the go.bug.st/serial library enumerator. The enumerations are shared
+// by all the callers in the process, see MinEnumerationInterval.
+//
+// Under WSL2, if no USB serial ports are found, ErrWSLNoUSBDevices is returned.
 func DefaultPortMapper() (map[string]bool, error) {
-	ports, err := serial.GetPortsList()
+	ports, err := guardedGetPortsList()
 	if err != nil {
 		return nil, fmt.Errorf("listing serial ports: %w", err)
 	}
+	if err := checkWSLPorts(ports); err != nil {
+		return nil, err
+	}
 	res := map[string]bool{}
 	for _, port := range ports {
-		res[port] = true
+		res[NormalizePortName(port)] = true
+	}
+	return res, nil
+}
+
+// PortDetails contains the details of a serial port as reported by the OS.
+type PortDetails struct {
+	Name         string `json:"name"`
+	IsUSB        bool   `json:"isUSB"`
+	VID          string `json:"vid,omitempty"`
+	PID          string `json:"pid,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	Product      string `json:"product,omitempty"`
+	// Manufacturer and InterfaceName are the USB string descriptors of the
+	// device manufacturer and of the interface of the port, if available
+	// (only on Linux).
+	Manufacturer  string `json:"manufacturer,omitempty"`
+	InterfaceName string `json:"interfaceName,omitempty"`
+	// InterfaceNumber is the number of the USB interface of the port, in
+	// hexadecimal (e.g. "02"), if available (on Linux, and on Windows for
+	// the composite devices). It tells apart the ports of a composite
+	// device exposing many CDC interfaces.
+	InterfaceNumber string `json:"interfaceNumber,omitempty"`
+	// FriendlyName is the name of the port as displayed by the OS, if
+	// available (e.g. "Arduino Uno (COM7)" on Windows).
+	FriendlyName string `json:"friendlyName,omitempty"`
+	// Driver is the name of the OS driver handling the port, if available
+	// (e.g. "usbser", "CH341SER_A64", "FTDIBUS" on Windows or "cdc_acm",
+	// "ch341", "ftdi_sio" on Linux).
+	Driver string `json:"driver,omitempty"`
+}
+
+// DetailedPortsMapper is a function that returns the details of the available
+// serial ports, keyed by port name.
+type DetailedPortsMapper func() (map[string]*PortDetails, error)
+
+// DefaultDetailedPortMapper returns the details of the available serial ports
+// using the go.bug.st/serial library enumerator. The enumerations are shared
+// by all the callers in the process, see MinEnumerationInterval.
+//
+// Under WSL2, if no USB serial ports are found, ErrWSLNoUSBDevices is returned.
+func DefaultDetailedPortMapper() (map[string]*PortDetails, error) {
+	ports, err := guardedGetDetailedPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("listing serial ports details: %w", err)
+	}
+	names := []string{}
+	for _, port := range ports {
+		names = append(names, port.Name)
+	}
+	if err := checkWSLPorts(names); err != nil {
+		return nil, err
+	}
+	res := map[string]*PortDetails{}
+	for _, port := range ports {
+		name := NormalizePortName(port.Name)
+		res[name] = &PortDetails{
+			Name:         name,
+			IsUSB:        port.IsUSB,
+			VID:          port.VID,
+			PID:          port.PID,
+			SerialNumber: port.SerialNumber,
+			Product:      port.Product,
+		}
+		if port.IsUSB {
+			nativeUSBDescriptors(res[name])
+		}
 	}
 	return res, nil
 }
+
+// DisplayName returns the name of the port to show in the UIs: the USB
+// product and interface names if available (e.g. "Arduino Nano 33 IoT —
+// Bootloader"), otherwise the friendly name or the port name.
+func (p *PortDetails) DisplayName() string {
+	name := p.Product
+	if name == "" {
+		name = p.FriendlyName
+	}
+	if name == "" {
+		return p.Name
+	}
+	if p.InterfaceName != "" && p.InterfaceName != name {
+		name += " — " + p.InterfaceName
+	}
+	return name
+}
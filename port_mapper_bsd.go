@@ -0,0 +1,77 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+//go:build freebsd || openbsd
+
+package serialutils
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// bsdPortRegexp matches the USB serial devices, both the dial-in (ttyU) and
+// the callout (cuaU) ones, excluding the .init and .lock control devices.
+var bsdPortRegexp = regexp.MustCompile(`^(?:cua|tty)U[0-9]+(?:\.[0-9]+)?$`)
+
+// nativeGetPortsList lists the USB serial ports. The serial library on BSD
+// looks for macOS-style device names, so the /dev folder is scanned here.
+// Every device is reported once, using its callout device.
+func nativeGetPortsList() ([]string, error) {
+	entries, err := os.ReadDir("/dev")
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, entry := range entries {
+		if bsdPortRegexp.MatchString(entry.Name()) {
+			found[nativeNormalizePortName("/dev/"+entry.Name())] = true
+		}
+	}
+	res := []string{}
+	for port := range found {
+		res = append(res, port)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// nativeGetDetailedPortsList lists the USB serial ports, the serial library
+// doesn't provide the USB details on BSD so only the names are reported.
+func nativeGetDetailedPortsList() ([]*enumerator.PortDetails, error) {
+	ports, err := nativeGetPortsList()
+	if err != nil {
+		return nil, err
+	}
+	res := []*enumerator.PortDetails{}
+	for _, port := range ports {
+		res = append(res, &enumerator.PortDetails{Name: port, IsUSB: true})
+	}
+	return res, nil
+}
+
+// nativeNormalizePortName converts a dial-in USB serial device into the
+// corresponding callout device, that can be opened without waiting for the
+// carrier detect.
+func nativeNormalizePortName(port string) string {
+	if strings.HasPrefix(port, "/dev/ttyU") {
+		return "/dev/cuaU" + strings.TrimPrefix(port, "/dev/ttyU")
+	}
+	return port
+}
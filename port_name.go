@@ -0,0 +1,37 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var windowsCOMPortRegexp = regexp.MustCompile(`(?i)^(?:\\\\[.?]\\)?(COM[0-9]+)$`)
+
+// NormalizePortName returns the canonical form of a port name. Windows COM
+// ports may be written using the device namespace syntax required for the
+// ports above COM9 (`\\.\COM10`) or in lowercase: they are converted to the
+// plain uppercase form (`COM10`) used by the enumerator. On FreeBSD and
+// OpenBSD the USB serial devices are reported using their callout device
+// (`/dev/cuaU0`), so the dial-in device (`/dev/ttyU0`) is converted to it.
+// Other port names are returned unchanged.
+func NormalizePortName(port string) string {
+	if m := windowsCOMPortRegexp.FindStringSubmatch(port); m != nil {
+		return strings.ToUpper(m[1])
+	}
+	return nativeNormalizePortName(port)
+}
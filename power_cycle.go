@@ -0,0 +1,109 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// USBPowerSwitch switches the power of the ports of the USB hubs, for the
+// automated farms whose hubs support per-port power switching.
+type USBPowerSwitch interface {
+	// PowerCycle turns off and on the port of the hub at the given location
+	// (in the Linux sysfs format, e.g. "1-2" for the hub on the port 2 of
+	// the bus 1).
+	PowerCycle(hub string, port int) error
+}
+
+// UhubctlPowerSwitch is the USBPowerSwitch running uhubctl, for the hubs
+// it supports.
+type UhubctlPowerSwitch struct {
+	// Command is the path of the uhubctl executable, if empty it's looked up
+	// in the PATH.
+	Command string
+	// OffTime is the time the port is kept off, 2 seconds if zero.
+	OffTime time.Duration
+}
+
+// PowerCycle implements USBPowerSwitch.
+func (s *UhubctlPowerSwitch) PowerCycle(hub string, port int) error {
+	command := s.Command
+	if command == "" {
+		command = "uhubctl"
+	}
+	offTime := s.OffTime
+	if offTime == 0 {
+		offTime = 2 * time.Second
+	}
+	cmd := exec.Command(command, "-l", hub, "-p", strconv.Itoa(port), "-a", "cycle", "-d", strconv.FormatFloat(offTime.Seconds(), 'f', -1, 64))
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s: %w: %s", command, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// PowerCycleUSBPort power-cycles the port of the USB hub the device of the
+// serial port is connected to, using the given USBPowerSwitch (an
+// UhubctlPowerSwitch if nil). The hub port is found from the USB location of
+// the device, that is known only on Linux.
+func PowerCycleUSBPort(port string, sw USBPowerSwitch) error {
+	if sw == nil {
+		sw = &UhubctlPowerSwitch{}
+	}
+	hub, hubPort, err := usbHubPort(usbLocation(NormalizePortName(port)))
+	if err != nil {
+		return fmt.Errorf("power-cycling %s: %w", port, err)
+	}
+	if err := sw.PowerCycle(hub, hubPort); err != nil {
+		return fmt.Errorf("power-cycling port %d of hub %s: %w", hubPort, hub, err)
+	}
+	return nil
+}
+
+// usbHubPort splits the location of a USB device in the location of its
+// hub and the number of the hub port, for example "1-2.3" in "1-2" and 3.
+func usbHubPort(location string) (string, int, error) {
+	hub := usbHubPath(location)
+	if hub == "" {
+		return "", 0, fmt.Errorf("unknown USB location")
+	}
+	port, err := strconv.Atoi(location[len(hub)+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid USB location %s", location)
+	}
+	return hub, port, nil
+}
+
+// PowerCycleStrategy returns the RecoveryStrategy power-cycling the hub
+// port of the board with the given USBPowerSwitch (see PowerCycleUSBPort)
+// and touching it once back. It's the last resort of the automated farms,
+// to be appended to the strategies of RecoverPortWithOptions.
+func PowerCycleStrategy(sw USBPowerSwitch) RecoveryStrategy {
+	return RecoveryStrategy{
+		Name: "power-cycle",
+		Resetter: ResetterFunc(func(port string) error {
+			return touchAfterReplug(port, func(port string) error { return PowerCycleUSBPort(port, sw) })
+		}),
+	}
+}
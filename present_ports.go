@@ -0,0 +1,71 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PresentPortsMapper returns the details of the serial ports of the devices
+// currently present in the system, with their friendly names (for example
+// "Arduino Uno (COM7)") and drivers. On Windows it queries SetupAPI for the
+// present devices only, avoiding the stale registry entries that the default
+// enumerator sometimes reports. On the other OS it is equivalent to the
+// DefaultDetailedPortMapper, with the drivers added on Linux.
+func PresentPortsMapper() (map[string]*PortDetails, error) {
+	res, err := nativeListPresentPorts()
+	if err != nil {
+		return nil, fmt.Errorf("listing present serial ports: %w", err)
+	}
+	return res, nil
+}
+
+// PortsMapperFromDetailed returns a PortsMapper listing the names of the
+// ports reported by the given DetailedPortsMapper.
+func PortsMapperFromDetailed(detailed DetailedPortsMapper) PortsMapper {
+	return func() (map[string]bool, error) {
+		ports, err := detailed()
+		if err != nil {
+			return nil, err
+		}
+		res := map[string]bool{}
+		for name := range ports {
+			res[name] = true
+		}
+		return res, nil
+	}
+}
+
+var windowsUSBInstanceIDRegexp = regexp.MustCompile(`(?i)^USB\\VID_([0-9A-F]{4})&PID_([0-9A-F]{4})(?:&MI_([0-9A-F]{2}))?(?:&[^\\]*)?\\(.*)$`)
+
+// parseWindowsInstanceID fills the USB details of the port from a Windows
+// device instance ID, like "USB\VID_2341&PID_0043\85735313233351D0F1C1".
+func parseWindowsInstanceID(id string, port *PortDetails) {
+	m := windowsUSBInstanceIDRegexp.FindStringSubmatch(id)
+	if m == nil {
+		return
+	}
+	port.IsUSB = true
+	port.VID = strings.ToUpper(m[1])
+	port.PID = strings.ToUpper(m[2])
+	port.InterfaceNumber = strings.ToUpper(m[3])
+	// Interfaces of composite devices have a generated ID containing "&"
+	if !strings.Contains(m[4], "&") {
+		port.SerialNumber = m[4]
+	}
+}
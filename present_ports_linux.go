@@ -0,0 +1,35 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func nativeListPresentPorts() (map[string]*PortDetails, error) {
+	ports, err := DefaultDetailedPortMapper()
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range ports {
+		driver, err := os.Readlink(filepath.Join("/sys/class/tty", filepath.Base(port.Name), "device", "driver"))
+		if err == nil {
+			port.Driver = filepath.Base(driver)
+		}
+	}
+	return ports, nil
+}
@@ -0,0 +1,86 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// guidDevClassPorts is the setup class of the COM and LPT ports
+var guidDevClassPorts = windows.GUID{
+	Data1: 0x4D36E978,
+	Data2: 0xE325,
+	Data3: 0x11CE,
+	Data4: [8]byte{0xBF, 0xC1, 0x08, 0x00, 0x2B, 0xE1, 0x03, 0x18},
+}
+
+func nativeListPresentPorts() (map[string]*PortDetails, error) {
+	devs, err := windows.SetupDiGetClassDevsEx(&guidDevClassPorts, "", 0, windows.DIGCF_PRESENT, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	defer devs.Close()
+
+	res := map[string]*PortDetails{}
+	for i := 0; ; i++ {
+		data, err := devs.EnumDeviceInfo(i)
+		if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := portNameOf(devs, data)
+		if err != nil {
+			continue
+		}
+		if len(name) < 3 || name[:3] != "COM" {
+			// Skip the LPT ports
+			continue
+		}
+
+		port := &PortDetails{Name: name}
+		if friendlyName, err := devs.DeviceRegistryProperty(data, windows.SPDRP_FRIENDLYNAME); err == nil {
+			port.FriendlyName, _ = friendlyName.(string)
+		}
+		if service, err := devs.DeviceRegistryProperty(data, windows.SPDRP_SERVICE); err == nil {
+			port.Driver, _ = service.(string)
+		}
+		if id, err := devs.DeviceInstanceID(data); err == nil {
+			parseWindowsInstanceID(id, port)
+		}
+		res[name] = port
+	}
+	return res, nil
+}
+
+// portNameOf returns the (normalized) name of the port of the device.
+func portNameOf(devs windows.DevInfo, data *windows.DevInfoData) (string, error) {
+	key, err := devs.OpenDevRegKey(data, windows.DICS_FLAG_GLOBAL, 0, windows.DIREG_DEV, windows.KEY_READ)
+	if err != nil {
+		return "", err
+	}
+	defer registry.Key(key).Close()
+	name, _, err := registry.Key(key).GetStringValue("PortName")
+	if err != nil {
+		return "", err
+	}
+	return NormalizePortName(name), nil
+}
@@ -0,0 +1,137 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// DefaultProbeTimeout is the time given to a Probe to complete if no other
+// timeout is specified.
+const DefaultProbeTimeout = 2 * time.Second
+
+// ErrProbeTimeout is returned by RunProbe if the probe did not complete in
+// time.
+var ErrProbeTimeout = errors.New("probe timed out")
+
+// Probe identifies the device connected to a serial port by exchanging
+// some data with it.
+type Probe interface {
+	// Mode returns the mode used to open the port.
+	Mode() *serial.Mode
+	// Probe exchanges data with the device through the open port, until the
+	// deadline expires. It returns a description of the device and true if
+	// the device has been recognized.
+	Probe(port serial.Port, deadline time.Time) (string, bool, error)
+}
+
+// RunProbe opens the port, runs the probe on it and closes the port. If the
+// probe does not complete within the timeout (DefaultProbeTimeout if zero)
+// the port is closed and ErrProbeTimeout is returned.
+func RunProbe(port string, probe Probe, timeout time.Duration) (string, bool, error) {
+	if timeout == 0 {
+		timeout = DefaultProbeTimeout
+	}
+	p, err := openPortWithTimeout(port, probe.Mode(), timeout)
+	if err != nil {
+		return "", false, fmt.Errorf("opening port: %w", err)
+	}
+
+	type probeResult struct {
+		description string
+		ok          bool
+		err         error
+	}
+	done := make(chan probeResult, 1)
+	go func() {
+		description, ok, err := probe.Probe(p, time.Now().Add(timeout))
+		done <- probeResult{description, ok, err}
+	}()
+	select {
+	case res := <-done:
+		p.Close()
+		return res.description, res.ok, res.err
+	case <-time.After(timeout + 100*time.Millisecond):
+		// Closing the port unblocks the pending operations of the probe
+		p.Close()
+		return "", false, ErrProbeTimeout
+	}
+}
+
+// ExchangeProbe is a Probe that sends a request to the device and checks
+// the response. The request is repeated periodically, since some devices
+// need some time to start after the port is opened, until the response
+// matches or the deadline expires.
+type ExchangeProbe struct {
+	// BaudRate is the speed used to open the port, 115200 if zero.
+	BaudRate int
+	// Request is the data sent to the device.
+	Request []byte
+	// RequestInterval is the interval between the repetitions of the
+	// request, 250 ms if zero.
+	RequestInterval time.Duration
+	// Match checks the data received so far, it returns a description of
+	// the device and true if the response is complete and valid.
+	Match func(response []byte) (string, bool)
+}
+
+// Mode implements Probe.
+func (e *ExchangeProbe) Mode() *serial.Mode {
+	baudRate := e.BaudRate
+	if baudRate == 0 {
+		baudRate = 115200
+	}
+	return &serial.Mode{BaudRate: baudRate}
+}
+
+// Probe implements Probe.
+func (e *ExchangeProbe) Probe(p serial.Port, deadline time.Time) (string, bool, error) {
+	requestInterval := e.RequestInterval
+	if requestInterval == 0 {
+		requestInterval = 250 * time.Millisecond
+	}
+	if err := p.ResetInputBuffer(); err != nil {
+		return "", false, fmt.Errorf("resetting input buffer: %w", err)
+	}
+	if err := p.SetReadTimeout(50 * time.Millisecond); err != nil {
+		return "", false, fmt.Errorf("setting read timeout: %w", err)
+	}
+
+	response := []byte{}
+	buff := make([]byte, 64)
+	nextRequest := time.Now()
+	for time.Now().Before(deadline) {
+		if !time.Now().Before(nextRequest) {
+			if _, err := p.Write(e.Request); err != nil {
+				return "", false, fmt.Errorf("writing to port: %w", err)
+			}
+			nextRequest = time.Now().Add(requestInterval)
+		}
+		n, err := p.Read(buff)
+		if err != nil {
+			return "", false, fmt.Errorf("reading from port: %w", err)
+		}
+		response = append(response, buff[:n]...)
+		if description, ok := e.Match(response); ok {
+			return description, true, nil
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,209 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResetProfile describes how to reset a kind of board: the strategy to use,
+// what to wait for after the reset and the timeouts. The profiles are kept
+// in a registry and can be looked up by USB VID/PID or by board FQBN.
+type ResetProfile struct {
+	// Name is a human readable name of the profile.
+	Name string
+	// USBIDs are the USB IDs of the boards (in application mode) using this
+	// profile.
+	USBIDs []USBID
+	// FQBNs are the fully qualified board names of the boards using this
+	// profile. A partial FQBN (e.g. "arduino:samd") matches all the boards
+	// whose FQBN starts with it.
+	FQBNs []string
+	// Resetter is the strategy used to reset the board, nil for the 1200-bps
+	// touch.
+	Resetter Resetter
+	// NoReset tells that the boards don't need any reset before the upload,
+	// like the boards programmed by a dedicated programmer or with an
+	// always-on bootloader: the reset is skipped and reported as such.
+	NoReset bool
+	// Wait tells if a new bootloader target appears after the reset.
+	Wait bool
+	// WaitForMassStorage tells if the bootloader may appear as a removable
+	// volume (UF2).
+	WaitForMassStorage bool
+	// Timeout is the maximum time to wait for the bootloader, zero for the
+	// default timeout.
+	Timeout time.Duration
+	// BootloaderIDs are the USB IDs of the bootloader ports of the boards.
+	BootloaderIDs []USBID
+	// TargetDiscoverers detect the bootloaders that are not serial ports
+	// nor removable volumes, like the HID bootloaders.
+	TargetDiscoverers []TargetDiscoverer
+}
+
+// Apply configures the ResetOptions with the settings of the profile. The
+// reset strategy of the board (Resetter, NoReset, Wait and
+// WaitForMassStorage) always replaces the one of the options, while the
+// Timeout, the BootloaderIDs and the TargetDiscoverers replace the ones of
+// the options only if set in the profile.
+func (p *ResetProfile) Apply(opts *ResetOptions) {
+	opts.Resetter = p.Resetter
+	opts.SkipReset = p.NoReset
+	opts.Wait = p.Wait
+	opts.WaitForMassStorage = p.WaitForMassStorage
+	opts.RequireUF2 = p.WaitForMassStorage
+	if p.Timeout != 0 {
+		opts.Timeout = p.Timeout
+	}
+	if len(p.BootloaderIDs) > 0 {
+		opts.BootloaderIDs = p.BootloaderIDs
+	}
+	if len(p.TargetDiscoverers) > 0 {
+		opts.TargetDiscoverers = p.TargetDiscoverers
+	}
+}
+
+// MatchesPort returns true if the profile applies to the port.
+func (p *ResetProfile) MatchesPort(port *PortDetails) bool {
+	return MatchesAny(p.USBIDs, port)
+}
+
+// MatchesFQBN returns true if the profile applies to the board with the
+// given FQBN. The board options, if any, are ignored.
+func (p *ResetProfile) MatchesFQBN(fqbn string) bool {
+	// Strip the board options, like in "arduino:samd:mkr1000:opt=value"
+	if parts := strings.SplitN(fqbn, ":", 4); len(parts) == 4 {
+		fqbn = strings.Join(parts[:3], ":")
+	}
+	for _, pattern := range p.FQBNs {
+		if fqbn == pattern || strings.HasPrefix(fqbn, pattern+":") {
+			return true
+		}
+	}
+	return false
+}
+
+var profilesMux sync.RWMutex
+var profiles = builtinResetProfiles()
+
+// RegisterResetProfile adds a profile to the registry. The profiles
+// registered later take precedence over the earlier ones (and over the
+// built-in profiles) when looking them up.
+func RegisterResetProfile(profile *ResetProfile) {
+	profilesMux.Lock()
+	defer profilesMux.Unlock()
+	profiles = append(profiles, profile)
+}
+
+// ResetProfiles returns all the registered profiles, in order of precedence.
+func ResetProfiles() []*ResetProfile {
+	profilesMux.RLock()
+	defer profilesMux.RUnlock()
+	res := make([]*ResetProfile, 0, len(profiles))
+	for i := len(profiles) - 1; i >= 0; i-- {
+		res = append(res, profiles[i])
+	}
+	return res
+}
+
+// FindResetProfileForPort returns the profile applying to the port, or nil
+// if there are none.
+func FindResetProfileForPort(port *PortDetails) *ResetProfile {
+	for _, profile := range ResetProfiles() {
+		if profile.MatchesPort(port) {
+			return profile
+		}
+	}
+	return nil
+}
+
+// FindResetProfileForFQBN returns the profile applying to the board with the
+// given FQBN, or nil if there are none.
+func FindResetProfileForFQBN(fqbn string) *ResetProfile {
+	for _, profile := range ResetProfiles() {
+		if profile.MatchesFQBN(fqbn) {
+			return profile
+		}
+	}
+	return nil
+}
+
+func builtinResetProfiles() []*ResetProfile {
+	return []*ResetProfile{
+		{
+			Name:          "Arduino AVR native USB",
+			USBIDs:        []USBID{{"2341", "8036"}, {"2341", "8037"}, {"2341", "8041"}},
+			FQBNs:         []string{"arduino:avr:leonardo", "arduino:avr:micro", "arduino:avr:yun"},
+			Wait:          true,
+			BootloaderIDs: KnownBootloaderIDs,
+		},
+		{
+			Name: "Arduino SAMD",
+			USBIDs: []USBID{
+				{"2341", "804D"}, {"2341", "804E"}, {"2341", "804F"}, {"2341", "8050"},
+				{"2341", "8052"}, {"2341", "8053"}, {"2341", "8054"}, {"2341", "8055"},
+				{"2341", "8057"},
+			},
+			FQBNs:         []string{"arduino:samd"},
+			Wait:          true,
+			BootloaderIDs: KnownBootloaderIDs,
+		},
+		{
+			Name:          "Arduino Mbed",
+			USBIDs:        []USBID{{"2341", "805A"}, {"2341", "025B"}},
+			FQBNs:         []string{"arduino:mbed_nano:nano33ble", "arduino:mbed_portenta"},
+			Wait:          true,
+			BootloaderIDs: KnownBootloaderIDs,
+		},
+		{
+			Name:               "RP2040",
+			USBIDs:             []USBID{{"2E8A", "000A"}, {"2341", "005E"}, {"2341", "805E"}},
+			FQBNs:              []string{"arduino:mbed_nano:nanorp2040connect", "arduino:mbed_rp2040", "rp2040:rp2040"},
+			Wait:               true,
+			WaitForMassStorage: true,
+		},
+		{
+			// The USB-serial boards are reset by the uploader itself, through
+			// the DTR line
+			Name:    "Arduino AVR USB-serial",
+			USBIDs:  []USBID{{"2341", "0043"}, {"2341", "0001"}, {"2A03", "0043"}, {"2341", "0010"}, {"2341", "0042"}},
+			FQBNs:   []string{"arduino:avr:uno", "arduino:avr:mega", "arduino:avr:nano"},
+			NoReset: true,
+		},
+		{
+			Name:     "Espressif ESP8266/ESP32",
+			FQBNs:    []string{"esp8266:esp8266", "esp32:esp32"},
+			Resetter: &ESPResetter{},
+		},
+		{
+			Name:     "STM32",
+			FQBNs:    []string{"STMicroelectronics:stm32"},
+			Resetter: &STM32Resetter{},
+		},
+		{
+			Name:     "Teensy",
+			USBIDs:   []USBID{{"16C0", "0483"}},
+			FQBNs:    []string{"teensy:avr"},
+			Resetter: TeensyResetter,
+			// The HalfKay bootloader can be detected only where the USB
+			// devices can be listed
+			Wait:              usbDevicesSupported,
+			TargetDiscoverers: []TargetDiscoverer{HIDDiscoverer(USBID{"16C0", "0478"})},
+		},
+	}
+}
@@ -0,0 +1,176 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// resetProfilesConfig is the format of the reset profiles configuration
+// files, for example:
+//
+//	{
+//	  "profiles": [
+//	    {
+//	      "name": "My board",
+//	      "usbIDs": ["1234:0001"],
+//	      "fqbns": ["myvendor:myarch:myboard"],
+//	      "strategy": "1200bps-touch",
+//	      "wait": true,
+//	      "timeout": "15s",
+//	      "bootloaderIDs": ["1234:0002"]
+//	    }
+//	  ]
+//	}
+type resetProfilesConfig struct {
+	Profiles []resetProfileConfig `json:"profiles"`
+}
+
+type resetProfileConfig struct {
+	Name               string   `json:"name"`
+	USBIDs             []USBID  `json:"usbIDs"`
+	FQBNs              []string `json:"fqbns"`
+	Strategy           string   `json:"strategy"`
+	Command            []string `json:"command"`
+	Wait               bool     `json:"wait"`
+	WaitForMassStorage bool     `json:"waitForMassStorage"`
+	Timeout            string   `json:"timeout"`
+	BootloaderIDs      []USBID  `json:"bootloaderIDs"`
+	// HIDBootloaderIDs are the USB IDs of the HID bootloaders of the boards.
+	HIDBootloaderIDs []USBID `json:"hidBootloaderIDs"`
+	// ControlInterface and ControlCommand configure the "control-port"
+	// strategy.
+	ControlInterface string `json:"controlInterface"`
+	ControlCommand   string `json:"controlCommand"`
+}
+
+// optionalStrategies are the strategies of the resetters built only with
+// their build tag, registered by the tagged files.
+var optionalStrategies = map[string]func() Resetter{}
+
+// ParseResetProfiles parses a reset profiles configuration in JSON format.
+// The "strategy" of each profile is one of:
+//   - "1200bps-touch" (the default)
+//   - "none", for the boards that don't need any reset (see
+//     ResetProfile.NoReset)
+//   - "134bps-touch" (Teensy)
+//   - "esp" (ESPResetter)
+//   - "stm32" (STM32Resetter)
+//   - "signal" (SignalResetter)
+//   - "control-port" (ControlPortResetter), sending "controlCommand" to the
+//     interface named "controlInterface" (or the first sibling interface)
+//   - "cmsis-dap" (CMSISDAPResetter), available only when built with the
+//     serialutils_cmsisdap tag
+//   - "command", running the external command given in "command" (with
+//     "{port}" replaced by the port name)
+func ParseResetProfiles(data []byte) ([]*ResetProfile, error) {
+	var config resetProfilesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("decoding reset profiles: %w", err)
+	}
+	res := []*ResetProfile{}
+	for i, c := range config.Profiles {
+		profile, err := c.toResetProfile()
+		if err != nil {
+			return nil, fmt.Errorf("reset profile %d (%s): %w", i, c.Name, err)
+		}
+		res = append(res, profile)
+	}
+	return res, nil
+}
+
+func (c *resetProfileConfig) toResetProfile() (*ResetProfile, error) {
+	profile := &ResetProfile{
+		Name:               c.Name,
+		USBIDs:             c.USBIDs,
+		FQBNs:              c.FQBNs,
+		Wait:               c.Wait,
+		WaitForMassStorage: c.WaitForMassStorage,
+		BootloaderIDs:      c.BootloaderIDs,
+	}
+	if len(c.HIDBootloaderIDs) > 0 {
+		profile.TargetDiscoverers = []TargetDiscoverer{HIDDiscoverer(c.HIDBootloaderIDs...)}
+	}
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		profile.Timeout = timeout
+	}
+	switch c.Strategy {
+	case "", "1200bps-touch":
+		profile.Resetter = nil
+	case "none":
+		profile.NoReset = true
+	case "134bps-touch":
+		profile.Resetter = TeensyResetter
+	case "esp":
+		profile.Resetter = &ESPResetter{}
+	case "stm32":
+		profile.Resetter = &STM32Resetter{}
+	case "signal":
+		profile.Resetter = &SignalResetter{}
+	case "control-port":
+		if c.ControlCommand == "" {
+			return nil, fmt.Errorf("missing control command")
+		}
+		resetter := &ControlPortResetter{Command: c.ControlCommand}
+		if c.ControlInterface != "" {
+			resetter.Interface = InterfaceNamed(c.ControlInterface)
+		}
+		profile.Resetter = resetter
+	case "command":
+		if len(c.Command) == 0 {
+			return nil, fmt.Errorf("missing command")
+		}
+		profile.Resetter = ResetterFunc(CommandHook(c.Command[0], c.Command[1:]...))
+	default:
+		newResetter, ok := optionalStrategies[c.Strategy]
+		if !ok {
+			return nil, fmt.Errorf("invalid strategy: %s", c.Strategy)
+		}
+		profile.Resetter = newResetter()
+	}
+	return profile, nil
+}
+
+// LoadResetProfiles reads the reset profiles from a JSON configuration file
+// (see ParseResetProfiles for the format).
+func LoadResetProfiles(path string) ([]*ResetProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading reset profiles: %w", err)
+	}
+	return ParseResetProfiles(data)
+}
+
+// RegisterResetProfilesFromFile loads the reset profiles from a JSON
+// configuration file and adds them to the registry, where they take
+// precedence over the built-in profiles.
+func RegisterResetProfilesFromFile(path string) error {
+	profiles, err := LoadResetProfiles(path)
+	if err != nil {
+		return err
+	}
+	for _, profile := range profiles {
+		RegisterResetProfile(profile)
+	}
+	return nil
+}
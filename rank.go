@@ -0,0 +1,93 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "sort"
+
+// candidateRanking contains the information used to rank the candidate
+// bootloader ports, when many of them appear at the same time.
+type candidateRanking struct {
+	// preferredPort is the bootloader port recalled from the PortStore.
+	preferredPort string
+	// serialNumber is the USB serial number of the touched board.
+	serialNumber string
+	// location is the USB location of the touched board.
+	location string
+	// hubPath is the location of the USB hub of the touched board.
+	hubPath string
+	// bootloaderIDs are the USB IDs of the known bootloaders.
+	bootloaderIDs []USBID
+	// iface selects the preferred interface of a composite device.
+	iface InterfaceSelector
+}
+
+// score returns a score of the likelihood of the port being the bootloader
+// of the touched board: the port recalled from the PortStore first, then
+// the ports with the same serial number, the known bootloader VID/PID and
+// the ports on the same USB location or hub.
+func (r *candidateRanking) score(port string, details *PortDetails) int {
+	score := 0
+	if port == r.preferredPort {
+		score += 16
+	}
+	if r.sameSerialNumber(details) {
+		score += 8
+	}
+	if MatchesAny(r.bootloaderIDs, details) {
+		score += 4
+	}
+	if location := usbLocation(port); r.location != "" && location == r.location {
+		score += 2
+	} else if r.hubPath != "" && usbHubPath(location) == r.hubPath {
+		score++
+	}
+	return score
+}
+
+// sameSerialNumber returns true if the port has the serial number of the
+// touched board.
+func (r *candidateRanking) sameSerialNumber(details *PortDetails) bool {
+	return details != nil && r.serialNumber != "" && details.SerialNumber == r.serialNumber
+}
+
+// sameBoard returns true if the port belongs to the touched board, having
+// the same serial number or being on the same USB location.
+func (r *candidateRanking) sameBoard(port string, details *PortDetails) bool {
+	return r.sameSerialNumber(details) || (r.location != "" && usbLocation(port) == r.location)
+}
+
+// sort sorts the candidates from the most to the least likely. The ports
+// with the same score, like the interfaces of a composite device, are sorted
+// putting first the interface chosen by the InterfaceSelector and then in
+// lexical order, to make the choice deterministic.
+func (r *candidateRanking) sort(candidates []string, details map[string]*PortDetails) {
+	scores := map[string]int{}
+	preferred := map[string]bool{}
+	for _, port := range candidates {
+		scores[port] = r.score(port, details[port])
+		preferred[port] = r.iface != nil && r.iface(details[port])
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if preferred[a] != preferred[b] {
+			return preferred[a]
+		}
+		return a < b
+	})
+}
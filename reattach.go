@@ -0,0 +1,207 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ReattachOptions contains the parameters of the ReattachAfterResetWithOptions
+// and WaitForSketchPort calls.
+type ReattachOptions struct {
+	// PreviousPort is the port of the sketch before the reset, it is the
+	// preferred sketch port if it comes back. It is used by WaitForSketchPort
+	// only, ReattachAfterReset takes it as a parameter.
+	PreviousPort string
+	// Timeout is the maximum time to wait for the sketch port, if zero the
+	// default of 10 seconds is used.
+	Timeout time.Duration
+	// PollBackoff defines the interval between the polls of the port list,
+	// if nil the DefaultPollBackoff is used.
+	PollBackoff *PollBackoff
+	// DetailedPortsMapper is used to obtain the port list, if nil the
+	// DefaultDetailedPortMapper is used.
+	DetailedPortsMapper DetailedPortsMapper
+	// Clock is used to measure the timeout and to sleep between the polls,
+	// if nil the SystemClock is used.
+	Clock Clock
+	// Debug, if not nil, reports messages useful for debugging purposes.
+	Debug func(msg string)
+}
+
+// ReattachAfterReset waits for the application sketch port to come back
+// after an upload, so that a serial monitor can reconnect to it. The port
+// often differs from both oldPort, the port touched by the reset, and the
+// bootloader port reported in result: see ReattachAfterResetWithOptions.
+// The empty string is returned if the port does not appear before the
+// timeout.
+func ReattachAfterReset(oldPort string, result *ResetResult) (string, error) {
+	return ReattachAfterResetWithOptions(oldPort, result, nil)
+}
+
+// ReattachAfterResetWithOptions is like ReattachAfterReset but takes its
+// parameters from a ReattachOptions struct. Once the bootloader port is
+// gone, the sketch port is, in order of preference, oldPort if present, the
+// port on the same USB location of the bootloader (known on Linux only), a
+// new port with the same serial number of the bootloader or any new port.
+// If the bootloader port is the same as oldPort, the port is expected to
+// disappear and come back: at the timeout it is returned anyway if present.
+func ReattachAfterResetWithOptions(oldPort string, result *ResetResult, opts *ReattachOptions) (string, error) {
+	return waitForSketchPort(context.Background(), oldPort, bootloaderPortID(result), opts)
+}
+
+// bootloaderPortID returns the identity of the bootloader port found by a
+// reset, the zero PortID if none.
+func bootloaderPortID(result *ResetResult) PortID {
+	if result == nil || result.Target.Kind != SerialPort {
+		return PortID{}
+	}
+	if result.Target.ID != nil {
+		return *result.Target.ID
+	}
+	return PortID{Name: result.Target.Path}
+}
+
+// WaitForSketchPort is the mirror image of the wait for the bootloader: it
+// waits for the bootloader port to disappear after the upload and for the
+// application sketch port to enumerate, returning the port to use (see
+// ReattachAfterResetWithOptions for how it is chosen). The empty string is
+// returned if the port does not appear before the timeout. The wait is
+// interrupted if the context is canceled.
+func WaitForSketchPort(ctx context.Context, bootloaderPort string, opts *ReattachOptions) (string, error) {
+	oldPort := ""
+	if opts != nil {
+		oldPort = opts.PreviousPort
+	}
+	return waitForSketchPort(ctx, oldPort, PortID{Name: bootloaderPort}, opts)
+}
+
+// waitForSketchPort waits for the sketch port after the upload on the
+// bootloader port, see ReattachAfterResetWithOptions.
+func waitForSketchPort(ctx context.Context, oldPort string, bootloader PortID, opts *ReattachOptions) (string, error) {
+	if opts == nil {
+		opts = &ReattachOptions{}
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	mapper := opts.DetailedPortsMapper
+	if mapper == nil {
+		mapper = DefaultDetailedPortMapper
+	}
+	backoff := opts.PollBackoff
+	if backoff == nil {
+		backoff = &DefaultPollBackoff
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	debug := opts.Debug
+
+	baseline, err := mapper()
+	if err != nil {
+		return "", fmt.Errorf("listing serial ports: %w", err)
+	}
+	if bootloader.Name != "" && bootloader.Location == "" && baseline[bootloader.Name] != nil {
+		bootloader.Location = usbLocation(bootloader.Name)
+	}
+	// If the bootloader port has the same name of the sketch port, it must
+	// disappear before the sketch port can be recognized
+	bootloaderGone := bootloader.Name == "" || baseline[bootloader.Name] == nil
+
+	deadline := clock.Now().Add(timeout)
+	interval := backoff.initial()
+	var now map[string]*PortDetails
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		now, err = mapper()
+		if err != nil {
+			return "", fmt.Errorf("listing serial ports: %w", err)
+		}
+		if bootloader.Name != "" && now[bootloader.Name] == nil && !bootloaderGone {
+			if debug != nil {
+				debug(fmt.Sprintf("Bootloader port %s gone", bootloader.Name))
+			}
+			bootloaderGone = true
+		}
+		if bootloaderGone {
+			if port := findSketchPort(oldPort, bootloader, baseline, now); port != "" {
+				if debug != nil {
+					debug(fmt.Sprintf("Sketch port found: %s", port))
+				}
+				return port, nil
+			}
+		}
+		if !clock.Now().Add(interval).Before(deadline) {
+			break
+		}
+		sleepContext(ctx, clock, interval)
+		interval = backoff.next(interval)
+	}
+	if oldPort != "" && now[oldPort] != nil {
+		return oldPort, nil
+	}
+	if debug != nil {
+		debug("Sketch port not found")
+	}
+	return "", nil
+}
+
+// findSketchPort returns the sketch port among the available ports, or the
+// empty string if not found yet.
+func findSketchPort(oldPort string, bootloader PortID, baseline, now map[string]*PortDetails) string {
+	if oldPort != "" && now[oldPort] != nil {
+		return oldPort
+	}
+	candidates := []string{}
+	for port := range now {
+		if port != bootloader.Name {
+			candidates = append(candidates, port)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return NaturalLess(candidates[i], candidates[j]) })
+	if bootloader.Location != "" {
+		for _, port := range candidates {
+			if usbLocation(port) == bootloader.Location {
+				return port
+			}
+		}
+	}
+	newPorts := []string{}
+	for _, port := range candidates {
+		if baseline[port] == nil {
+			newPorts = append(newPorts, port)
+		}
+	}
+	if bootloader.SerialNumber != "" {
+		for _, port := range newPorts {
+			if now[port].SerialNumber == bootloader.SerialNumber {
+				return port
+			}
+		}
+	}
+	if len(newPorts) > 0 {
+		return newPorts[0]
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRecoveryFailed is returned by RecoverPort if no strategy brought the
+// board in bootloader mode.
+var ErrRecoveryFailed = errors.New("no recovery strategy succeeded")
+
+// RecoveryStrategy is a reset strategy tried by RecoverPort.
+type RecoveryStrategy struct {
+	// Name identifies the strategy in the RecoveryReport.
+	Name string
+	// Resetter is the strategy used to reset the board, nil for the
+	// 1200-bps touch.
+	Resetter Resetter
+	// Timeout is the maximum time to wait for the bootloader, zero for the
+	// timeout of the reset options.
+	Timeout time.Duration
+}
+
+// DefaultRecoveryStrategies are the strategies tried by RecoverPort, from
+// the least to the most invasive: the plain 1200-bps touch, two touches in
+// a row emulating the double-tap of the reset button, the DTR/RTS pulse
+// without the 1200-bps open, the touch with an extended wait, for the
+// bootloaders slow to enumerate, and, where supported, the touch after the
+// re-enumeration of the USB device (see ReenumerateUSBDevice), before
+// asking the user to physically replug the board.
+var DefaultRecoveryStrategies = defaultRecoveryStrategies()
+
+func defaultRecoveryStrategies() []RecoveryStrategy {
+	res := []RecoveryStrategy{
+		{Name: "touch"},
+		{Name: "double-tap", Resetter: ResetterFunc(doubleTouch)},
+		{Name: "signal", Resetter: &SignalResetter{UseRTS: true}},
+		{Name: "extended-wait", Timeout: time.Minute},
+	}
+	if reenumerationSupported {
+		res = append(res, RecoveryStrategy{Name: "soft-replug", Resetter: ResetterFunc(softReplug)})
+	}
+	return res
+}
+
+// doubleTouch performs the 1200-bps touch twice in a row. The second touch
+// reaches the board only if its port is still there.
+func doubleTouch(port string) error {
+	if err := Touch1200bps(port); err != nil {
+		return err
+	}
+	_ = Touch1200bps(port)
+	return nil
+}
+
+// RecoveryOptions contains the parameters of RecoverPortWithOptions.
+type RecoveryOptions struct {
+	// Reset are the options of the resets, the Wait option is always
+	// enabled and the Resetter is set by the strategies.
+	Reset *ResetOptions
+	// Strategies are the strategies tried in order, if empty the
+	// DefaultRecoveryStrategies are used.
+	Strategies []RecoveryStrategy
+	// ReturnTimeout is the maximum time to wait for the port to come back
+	// after a failed attempt, before trying the next strategy, 5 seconds if
+	// zero.
+	ReturnTimeout time.Duration
+}
+
+// RecoveryAttempt is the outcome of a strategy tried by RecoverPort.
+type RecoveryAttempt struct {
+	Strategy string        `json:"strategy"`
+	Target   ResetTarget   `json:"target"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// RecoveryReport is the result of RecoverPort.
+type RecoveryReport struct {
+	Attempts []RecoveryAttempt `json:"attempts"`
+	// Strategy is the name of the strategy that succeeded, empty if none
+	// did.
+	Strategy string `json:"strategy,omitempty"`
+	// Result is the result of the successful reset, nil if none.
+	Result *ResetResult `json:"result,omitempty"`
+}
+
+// RecoverPort tries to bring the board on the port in bootloader mode, for
+// the boards that stopped responding to the standard touch (or whose port
+// is a ghost left by a crashed sketch), see RecoverPortWithOptions.
+func RecoverPort(port string) (*RecoveryReport, error) {
+	return RecoverPortWithOptions(port, nil)
+}
+
+// RecoverPortWithOptions cycles through escalating reset strategies until
+// one of them brings the board in bootloader mode, and reports which one
+// succeeded along with the outcome of every attempt. ErrRecoveryFailed is
+// returned, with the report, if none succeeded.
+func RecoverPortWithOptions(port string, opts *RecoveryOptions) (*RecoveryReport, error) {
+	if opts == nil {
+		opts = &RecoveryOptions{}
+	}
+	resetOpts := ResetOptions{}
+	if opts.Reset != nil {
+		resetOpts = *opts.Reset
+	}
+	resetOpts.Wait = true
+	strategies := opts.Strategies
+	if len(strategies) == 0 {
+		strategies = DefaultRecoveryStrategies
+	}
+	returnTimeout := opts.ReturnTimeout
+	if returnTimeout == 0 {
+		returnTimeout = 5 * time.Second
+	}
+	clock := resetOpts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	portsMapper := resetOpts.PortsMapper
+	if portsMapper == nil {
+		portsMapper = DefaultPortMapper
+	}
+	debug := func(msg string) {
+		if cb := resetOpts.Callbacks; cb != nil && cb.Debug != nil {
+			cb.Debug(msg)
+		}
+	}
+	port = NormalizePortName(port)
+
+	report := &RecoveryReport{}
+	for i, strategy := range strategies {
+		if i > 0 {
+			// The board may still be re-enumerating after the previous attempt
+			if back, err := waitForPorts(portsMapper, clock, returnTimeout, 100*time.Millisecond, func(ports map[string]bool) bool { return ports[port] }); err == nil && !back {
+				debug(fmt.Sprintf("RECOVER: %s not back, trying %s anyway", port, strategy.Name))
+			}
+		}
+		attemptOpts := resetOpts
+		attemptOpts.Resetter = strategy.Resetter
+		if strategy.Timeout != 0 {
+			attemptOpts.Timeout = strategy.Timeout
+		}
+		debug(fmt.Sprintf("RECOVER: trying %s", strategy.Name))
+		start := clock.Now()
+		res, err := ResetWithOptions(port, &attemptOpts)
+		attempt := RecoveryAttempt{Strategy: strategy.Name, Duration: clock.Now().Sub(start)}
+		if res != nil {
+			attempt.Target = res.Target
+		}
+		if err == nil && attempt.Target.Kind == NoTarget {
+			err = fmt.Errorf("no bootloader target found")
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+			report.Attempts = append(report.Attempts, attempt)
+			continue
+		}
+		report.Attempts = append(report.Attempts, attempt)
+		report.Strategy, report.Result = strategy.Name, res
+		debug(fmt.Sprintf("RECOVER: %s succeeded", strategy.Name))
+		return report, nil
+	}
+	return report, ErrRecoveryFailed
+}
@@ -0,0 +1,61 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrReenumerationNotSupported is returned by ReenumerateUSBDevice on the
+// OS where the USB devices can't be re-enumerated.
+var ErrReenumerationNotSupported = errors.New("USB device re-enumeration is supported only on Windows")
+
+// ReenumerateUSBDevice forces the re-enumeration of the USB device of the
+// port, like a physical replug, for the ports wedged in a bad state. On
+// Windows the device node is disabled and re-enabled through CfgMgr32,
+// that requires administrator rights. On the other OS it returns
+// ErrReenumerationNotSupported.
+func ReenumerateUSBDevice(port string) error {
+	if err := nativeReenumerateUSBDevice(NormalizePortName(port)); err != nil {
+		return fmt.Errorf("re-enumerating the USB device of %s: %w", port, err)
+	}
+	return nil
+}
+
+// softReplug re-enumerates the USB device of the port and touches it once
+// back, see touchAfterReplug.
+func softReplug(port string) error {
+	return touchAfterReplug(port, ReenumerateUSBDevice)
+}
+
+// touchAfterReplug replugs the board on the port with the given function
+// and, once the port is back, performs the 1200-bps touch, since the board
+// restarts in the application after the replug.
+func touchAfterReplug(port string, replug func(port string) error) error {
+	if err := replug(port); err != nil {
+		return err
+	}
+	back, err := waitForPorts(DefaultPortMapper, SystemClock, 10*time.Second, 100*time.Millisecond, func(ports map[string]bool) bool { return ports[port] })
+	if err != nil {
+		return err
+	}
+	if !back {
+		return fmt.Errorf("port %s not back after the replug", port)
+	}
+	return Touch1200bps(port)
+}
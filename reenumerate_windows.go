@@ -0,0 +1,95 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const reenumerationSupported = true
+
+var (
+	modcfgmgr32          = windows.NewLazySystemDLL("cfgmgr32.dll")
+	procCMGetParent      = modcfgmgr32.NewProc("CM_Get_Parent")
+	procCMDisableDevNode = modcfgmgr32.NewProc("CM_Disable_DevNode")
+	procCMEnableDevNode  = modcfgmgr32.NewProc("CM_Enable_DevNode")
+)
+
+var errPortDevNodeNotFound = errors.New("device node not found")
+
+// cmDisableUINotOK makes CM_Disable_DevNode fail instead of prompting the
+// user, if the device can't be disabled.
+const cmDisableUINotOK = 0x00000004
+
+// nativeReenumerateUSBDevice disables and re-enables the device node of the
+// USB device of the port: the parent of the port node for the interfaces of
+// the composite devices, the port node itself otherwise.
+func nativeReenumerateUSBDevice(port string) error {
+	devInst, id, err := portDevNode(port)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(strings.ToUpper(id), "&MI_") {
+		var parent windows.DEVINST
+		if ret, _, _ := procCMGetParent.Call(uintptr(unsafe.Pointer(&parent)), uintptr(devInst), 0); windows.CONFIGRET(ret) != windows.CR_SUCCESS {
+			return fmt.Errorf("getting the parent device node: %w", windows.CONFIGRET(ret))
+		}
+		devInst = parent
+	}
+	if ret, _, _ := procCMDisableDevNode.Call(uintptr(devInst), cmDisableUINotOK); windows.CONFIGRET(ret) != windows.CR_SUCCESS {
+		return fmt.Errorf("disabling the device node: %w", windows.CONFIGRET(ret))
+	}
+	// Give the drivers the time to release the device
+	time.Sleep(500 * time.Millisecond)
+	if ret, _, _ := procCMEnableDevNode.Call(uintptr(devInst), 0); windows.CONFIGRET(ret) != windows.CR_SUCCESS {
+		return fmt.Errorf("enabling the device node: %w", windows.CONFIGRET(ret))
+	}
+	return nil
+}
+
+// portDevNode returns the device node of the port and its instance ID.
+func portDevNode(port string) (windows.DEVINST, string, error) {
+	devs, err := windows.SetupDiGetClassDevsEx(&guidDevClassPorts, "", 0, windows.DIGCF_PRESENT, 0, "")
+	if err != nil {
+		return 0, "", err
+	}
+	defer devs.Close()
+
+	for i := 0; ; i++ {
+		data, err := devs.EnumDeviceInfo(i)
+		if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+			break
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if name, err := portNameOf(devs, data); err != nil || name != port {
+			continue
+		}
+		id, err := devs.DeviceInstanceID(data)
+		if err != nil {
+			return 0, "", err
+		}
+		return data.DevInst, id, nil
+	}
+	return 0, "", errPortDevNodeNotFound
+}
@@ -0,0 +1,154 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPortReserved is returned by ReservePort and by ResetWithOptions if the
+// board is reserved by another consumer.
+var ErrPortReserved = errors.New("port reserved by another consumer")
+
+// Reservation marks a board as in use (for example by a job of a test farm)
+// until it is released or it expires. The reset of a reserved board is
+// rejected unless the reservation is given in the ResetOptions, and the
+// reserved boards can be filtered out of the enumeration with
+// UnreservedPortsMapper.
+type Reservation struct {
+	ID      PortID    `json:"id"`
+	Expires time.Time `json:"expires"`
+}
+
+// reservations are the boards reserved in the process.
+var reservations = &reservationSet{}
+
+type reservationSet struct {
+	mux  sync.Mutex
+	list []*Reservation
+}
+
+// ReservePort reserves the board with the given PortID for the given time.
+// It fails with ErrPortReserved if the board is already reserved.
+func ReservePort(id PortID, ttl time.Duration) (*Reservation, error) {
+	reservations.mux.Lock()
+	defer reservations.mux.Unlock()
+	if r := reservations.find(nil, id); r != nil {
+		return nil, fmt.Errorf("%w: %s until %s", ErrPortReserved, r.ID, r.Expires.Format(time.RFC3339))
+	}
+	r := &Reservation{ID: id, Expires: time.Now().Add(ttl)}
+	reservations.list = append(reservations.list, r)
+	return r, nil
+}
+
+// ReleasePort releases the reservation. Releasing a nil or already released
+// reservation does nothing.
+func ReleasePort(r *Reservation) {
+	reservations.mux.Lock()
+	defer reservations.mux.Unlock()
+	for i, other := range reservations.list {
+		if other == r {
+			reservations.list = append(reservations.list[:i], reservations.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// Reservations returns the boards currently reserved.
+func Reservations() []Reservation {
+	reservations.mux.Lock()
+	defer reservations.mux.Unlock()
+	reservations.expire()
+	res := make([]Reservation, 0, len(reservations.list))
+	for _, r := range reservations.list {
+		res = append(res, *r)
+	}
+	return res
+}
+
+// UnreservedPortsMapper returns a DetailedPortsMapper that filters out of
+// the ports returned by the given mapper the boards reserved by others than
+// own (that may be nil).
+func UnreservedPortsMapper(mapper DetailedPortsMapper, own *Reservation) DetailedPortsMapper {
+	return func() (map[string]*PortDetails, error) {
+		ports, err := mapper()
+		if err != nil {
+			return nil, err
+		}
+		if !reservations.active() {
+			return ports, nil
+		}
+		res := map[string]*PortDetails{}
+		for name, details := range ports {
+			if !reservations.reservedByOthers(own, NewPortID(details)) {
+				res[name] = details
+			}
+		}
+		return res, nil
+	}
+}
+
+// active returns true if there is at least a reservation in place.
+func (rs *reservationSet) active() bool {
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+	rs.expire()
+	return len(rs.list) > 0
+}
+
+// reservedByOthers returns true if the board is reserved by others than own.
+func (rs *reservationSet) reservedByOthers(own *Reservation, id PortID) bool {
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+	return rs.find(own, id) != nil
+}
+
+// find returns the reservation of the board other than own, nil if not
+// reserved. It must be called with the lock held.
+func (rs *reservationSet) find(own *Reservation, id PortID) *Reservation {
+	rs.expire()
+	for _, r := range rs.list {
+		if r != own && r.ID.SameDevice(id) {
+			return r
+		}
+	}
+	return nil
+}
+
+// expire removes the expired reservations. It must be called with the lock
+// held.
+func (rs *reservationSet) expire() {
+	now := time.Now()
+	list := rs.list[:0]
+	for _, r := range rs.list {
+		if now.Before(r.Expires) {
+			list = append(list, r)
+		}
+	}
+	rs.list = list
+}
+
+// reservedPortID returns the PortID of the port, with its details if
+// available from the mapper.
+func reservedPortID(port string, mapper DetailedPortsMapper) PortID {
+	if ports, err := mapper(); err == nil && ports[port] != nil {
+		return NewPortID(ports[port])
+	}
+	return PortID{Name: port}
+}
@@ -16,9 +16,8 @@
 package serialutils
 
 import (
+	"context"
 	"fmt"
-	"runtime"
-	"strings"
 	"time"
 
 	"go.bug.st/serial"
@@ -27,23 +26,160 @@ import (
 // Touch1200bps open and close the serial port at 1200 bps. This is used
 // on many Arduino (and compatible) boards as a signal to put the MCU
 // in bootloader mode.
+//
+// RFC 2217 remote ports (see IsRFC2217Port) are touched using TouchRFC2217.
 func Touch1200bps(port string) error {
-	p, err := serial.Open(port, &serial.Mode{BaudRate: 1200})
-	if err != nil {
-		return fmt.Errorf("opening port at 1200bps: %w", err)
+	return Touch1200bpsWithOptions(port, nil)
+}
+
+// DefaultTouchTimeout is the maximum duration of the 1200-bps touch, if no
+// other timeout is specified. It can be overridden with the
+// SERIALUTILS_TOUCH_TIMEOUT environment variable.
+var DefaultTouchTimeout = 5 * time.Second
+
+// DefaultPostTouchDelay is the time waited after the touch, giving the board
+// the time to reset before enumerating the ports. It can be overridden with
+// the SERIALUTILS_POST_TOUCH_DELAY environment variable.
+var DefaultPostTouchDelay = 500 * time.Millisecond
+
+// DefaultWaitTimeout is the maximum time to wait for the bootloader, if no
+// other timeout is specified. It can be overridden with the
+// SERIALUTILS_WAIT_TIMEOUT environment variable.
+var DefaultWaitTimeout = 10 * time.Second
+
+// TouchOptions contains the parameters of a Touch1200bpsWithOptions call.
+type TouchOptions struct {
+	// Clock is used for the delays of the touch, if nil the SystemClock is used.
+	Clock Clock
+	// DTR selects how the DTR line is handled, see DTRMode.
+	DTR DTRMode
+	// RTS selects how the RTS line is handled, see RTSMode.
+	RTS RTSMode
+	// DetailedPortsMapper is used to identify the USB-serial bridges when
+	// needed, if nil the DefaultDetailedPortMapper is used.
+	DetailedPortsMapper DetailedPortsMapper
+	// Timeout is the maximum time given to the opening, the setup and the
+	// closing of the port, that may block for a long time with some wedged
+	// drivers. If zero the DefaultTouchTimeout is used.
+	Timeout time.Duration
+	// DisableQuirks disables the BridgeQuirks of the USB-serial bridge of
+	// the port.
+	DisableQuirks bool
+}
+
+// Touch1200bpsWithOptions is like Touch1200bps but takes its parameters from
+// a TouchOptions struct.
+func Touch1200bpsWithOptions(port string, opts *TouchOptions) error {
+	return Touch1200bpsContext(context.Background(), port, opts)
+}
+
+// Touch1200bpsContext is like Touch1200bpsWithOptions but gives up when the
+// context is canceled or the touch Timeout expires, returning an error
+// wrapping the context error. The port operations blocked meanwhile are
+// completed in background: the port is closed as soon as they return.
+func Touch1200bpsContext(ctx context.Context, port string, opts *TouchOptions) error {
+	if opts == nil {
+		opts = &TouchOptions{}
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTouchTimeout
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	if runtime.GOOS != "windows" {
-		// This is not required on Windows
-		// TODO: Investigate if it can be removed for other OS too
+	done := make(chan error, 1)
+	if IsRFC2217Port(port) {
+		go func() { done <- touchRFC2217(port, clock) }()
+	} else {
+		go func() {
+			// The port details may take long to enumerate too
+			settings := resolveTouchSettings(port, opts, opts.DetailedPortsMapper)
+			p, err := OpenPort(port, &serial.Mode{BaudRate: settings.baudRate})
+			if err != nil {
+				done <- fmt.Errorf("opening port at %dbps: %w", settings.baudRate, err)
+				return
+			}
+			done <- touchOpenPort(p, settings, clock)
+		}()
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("touching port %s: %w", port, ctx.Err())
+	}
+}
 
+// TouchOpenPort performs the 1200-bps touch on an already open port: the
+// port is reconfigured at 1200 bps, DTR is deasserted and the port is
+// closed. This allows a serial monitor to hand off its connection for the
+// reset, without the race of closing and reopening the port.
+func TouchOpenPort(p serial.Port) error {
+	return TouchOpenPortWithOptions(p, nil)
+}
+
+// TouchOpenPortWithOptions is like TouchOpenPort but takes its parameters
+// from a TouchOptions struct. Since the name of the port is not known, the
+// DTR and RTS platform defaults are resolved as for a device that is not a
+// USB-serial bridge.
+func TouchOpenPortWithOptions(p serial.Port, opts *TouchOptions) error {
+	return touchNamedOpenPort(p, "", opts)
+}
+
+// touchNamedOpenPort is TouchOpenPortWithOptions for an open port whose name
+// is known (if not empty), so that the DTR and RTS platform defaults are
+// resolved for it.
+func touchNamedOpenPort(p serial.Port, port string, opts *TouchOptions) error {
+	if opts == nil {
+		opts = &TouchOptions{}
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	settings := resolveTouchSettings(port, opts, opts.DetailedPortsMapper)
+	if err := p.SetMode(&serial.Mode{BaudRate: settings.baudRate}); err != nil {
+		_ = p.Close()
+		return fmt.Errorf("setting port at %dbps: %w", settings.baudRate, err)
+	}
+	return touchOpenPort(p, settings, clock)
+}
+
+// touchOpenPort handles the modem lines of a port opened at the touch baud
+// rate and closes it, completing the touch.
+func touchOpenPort(p serial.Port, settings touchSettings, clock Clock) error {
+	rts := settings.rts
+	if settings.deassertDTR {
 		// Set DTR to false
-		if err = p.SetDTR(false); err != nil {
+		if err := p.SetDTR(false); err != nil {
 			_ = p.Close()
 			return fmt.Errorf("setting DTR to OFF: %w", err)
 		}
 	}
 
+	if rts == RTSToggle {
+		if err := p.SetRTS(true); err != nil {
+			_ = p.Close()
+			return fmt.Errorf("setting RTS to ON: %w", err)
+		}
+		clock.Sleep(50 * time.Millisecond)
+	}
+	if rts == RTSToggle || rts == RTSDeassert {
+		if err := p.SetRTS(false); err != nil {
+			_ = p.Close()
+			return fmt.Errorf("setting RTS to OFF: %w", err)
+		}
+	}
+
+	if settings.holdTime > 0 {
+		clock.Sleep(settings.holdTime)
+	}
+
 	// Close serial port
 	_ = p.Close()
 
@@ -51,7 +187,7 @@ func Touch1200bps(port string) error {
 	// otherwise assert DTR, which would cancel the WDT reset if
 	// it happens within 250 ms. So we wait until the reset should
 	// have already occurred before going on.
-	time.Sleep(500 * time.Millisecond)
+	clock.Sleep(DefaultPostTouchDelay)
 
 	return nil
 }
@@ -67,11 +203,176 @@ type ResetProgressCallbacks struct {
 	// report the port found, or the empty string if no ports have been found and
 	// the wait has timed-out.
 	BootloaderPortFound func(port string)
+	// TouchingPortDetails, if not nil, is called just before TouchingPort
+	// with the details of the port (VID/PID, serial number, etc., or the name
+	// only if not available), for the UIs showing the board being reset.
+	TouchingPortDetails func(port *PortDetails)
+	// BootloaderPortFoundDetails, if not nil, is called just before
+	// BootloaderPortFound with the details of the bootloader port found, or
+	// nil if the wait has timed-out or the bootloader is not a serial port.
+	BootloaderPortFoundDetails func(port *PortDetails)
+	// WaitProgress is called at every poll of the port list during the wait,
+	// reporting the time elapsed since the wait started and the time remaining
+	// before the timeout (the progress is elapsed / (elapsed + remaining)).
+	WaitProgress func(elapsed, remaining time.Duration)
 	// Debug reports messages useful for debugging purposes. In normal conditions
 	// these messages should not be displayed to the user.
 	Debug func(msg string)
 }
 
+// ResetOptions contains the parameters of a ResetWithOptions call. Some of
+// the unset fields are taken from the Config of the process, see SetConfig.
+type ResetOptions struct {
+	// Wait enables the wait for the bootloader port after the reset.
+	Wait bool
+	// SkipReset tells that the board doesn't need any reset (e.g. the
+	// boards programmed by a dedicated programmer or with an always-on
+	// bootloader): the port is only released by its Monitor, if any, and the
+	// ResetResult is marked as Skipped. It's set by the profiles with
+	// NoReset.
+	SkipReset bool
+	// DryRun emulates the reset without touching any port, see Reset.
+	//
+	// Deprecated: the "999" suffix convention of the dry-run mode is limited
+	// and surprising, use a Simulator instead.
+	DryRun bool
+	// Simulator, if not nil, emulates the reset using the Simulator scenario
+	// instead of touching a real port.
+	Simulator *Simulator
+	// PortsMapper is used to obtain the current serial port list. If nil the
+	// DefaultPortMapper is used, or an RFC2217PortsMapper if the port to touch
+	// is an RFC 2217 remote port.
+	PortsMapper PortsMapper
+	// Callbacks is used to provide progress feedback to the caller, may be nil.
+	Callbacks *ResetProgressCallbacks
+	// IgnoreInitialEnumerationError makes the reset proceed if the port
+	// enumeration done before the touch fails, assuming that the port to
+	// touch is present. It has effect only if Wait is false, since the wait
+	// needs the initial port list.
+	IgnoreInitialEnumerationError bool
+	// Timeout is the maximum time to wait for the bootloader port, if zero
+	// the DefaultWaitTimeout is used.
+	Timeout time.Duration
+	// PollBackoff defines the interval between the polls of the port list
+	// during the wait, if nil the DefaultPollBackoff is used.
+	PollBackoff *PollBackoff
+	// Stabilization defines how the port list is checked for stability once
+	// new ports are detected, if nil the DefaultStabilization is used.
+	Stabilization *Stabilization
+	// ErrorTolerance defines how many port enumeration errors are tolerated
+	// during the wait, if nil the DefaultErrorTolerance is used.
+	ErrorTolerance *ErrorTolerance
+	// BootloaderIDs are the USB IDs of the bootloader ports: if a new port
+	// matching one of them is detected, it is returned immediately skipping
+	// the stabilization. If empty, the KnownBootloaderIDs of the common
+	// Arduino boards are used, along with the ones of the boards registered
+	// with RegisterBoardDefinition. The port details are obtained from the
+	// DetailedPortsMapper.
+	BootloaderIDs []USBID
+	// PreferredInterface, if not nil, selects the bootloader port among the
+	// interfaces of a composite device exposing many CDC interfaces (e.g.
+	// InterfaceNamed("Debug")), when they appear at the same time.
+	PreferredInterface InterfaceSelector
+	// PortHistory, if not nil, records the ports listed during the reset and
+	// provides the ports seen before the reset for the GracePeriod.
+	PortHistory *PortHistory
+	// GracePeriod, if not zero, excludes from the new ports detection the
+	// ports seen in the PortHistory during the GracePeriod before the reset,
+	// even if missing when the reset starts. This prevents a port that was
+	// momentarily missing from being reported as the bootloader port, but
+	// also a bootloader port seen during the period from being detected, so
+	// it should be kept short.
+	GracePeriod time.Duration
+	// RequireTouchedPortGone makes the wait ignore the new ports appearing
+	// before the touched port disappears, since they belong to unrelated
+	// devices plugged in meanwhile.
+	RequireTouchedPortGone bool
+	// SinglePortFastPath makes the wait return immediately, skipping the
+	// stabilization, if a new port appears and it is the only port available.
+	// This saves time on single-board machines.
+	SinglePortFastPath bool
+	// VerifyBootloader makes the bootloader port found probed for the known
+	// bootloader protocols (see IdentifyBootloader), the outcome is reported
+	// in ResetResult.Bootloader.
+	VerifyBootloader bool
+	// Tracer, if not nil, is used to trace the phases of the reset.
+	Tracer Tracer
+	// Metrics, if not nil, is used to export the metrics about the resets.
+	Metrics Metrics
+	// Accept, if not nil, is called with the details of every candidate
+	// bootloader port (obtained from the DetailedPortsMapper, only the Name
+	// is set if they are not available): a port is returned only if Accept
+	// returns true.
+	Accept func(port *PortDetails) bool
+	// AcceptProbe, if not nil, is run on every candidate bootloader port: a
+	// port is returned only if the probe recognizes the device on it.
+	AcceptProbe Probe
+	// ProbeTimeout is the time given to the AcceptProbe to complete, if zero
+	// the DefaultProbeTimeout is used.
+	ProbeTimeout time.Duration
+	// Clock is used to measure the timeouts and to sleep during the wait, if
+	// nil the SystemClock is used.
+	Clock Clock
+	// PortStore, if not nil, is used to remember the bootloader port of the
+	// touched board and to prefer it when many new ports appear during the wait.
+	PortStore PortStore
+	// DetailedPortsMapper is used to obtain the details of the ports (for
+	// example the serial number of the touched board when a PortStore is set).
+	// If nil the DefaultDetailedPortMapper is used.
+	DetailedPortsMapper DetailedPortsMapper
+	// WaitForMassStorage makes the wait consider also the new removable volumes,
+	// like the ones mounted by the UF2 bootloaders: if a new volume appears
+	// before a new serial port, it is returned as a MassStorageVolume target.
+	WaitForMassStorage bool
+	// RequireUF2 restricts the volumes considered by WaitForMassStorage to
+	// those containing an INFO_UF2.TXT file.
+	RequireUF2 bool
+	// VolumesMapper is used to obtain the current removable volumes list. If
+	// nil the DefaultVolumesMapper is used (or no volumes at all in dry-run).
+	VolumesMapper VolumesMapper
+	// TargetDiscoverers are used to detect the bootloader targets other than
+	// the serial ports (and the removable volumes of WaitForMassStorage),
+	// like the HID or DFU bootloaders: the first new target appeared after
+	// the reset is returned.
+	TargetDiscoverers []TargetDiscoverer
+	// Resetter is the strategy used to put the board in bootloader mode. If
+	// nil the 1200-bps touch is performed.
+	Resetter Resetter
+	// TouchOptions are the options of the 1200-bps touch, used if no Resetter
+	// is set. If the Clock or the DetailedPortsMapper are not set, the ones
+	// of the ResetOptions are used.
+	TouchOptions *TouchOptions
+	// WaitForConcurrentReset makes the reset wait for the completion of
+	// another reset of the same port in progress, in the process or in
+	// another process, instead of failing with ErrResetInProgress.
+	WaitForConcurrentReset bool
+	// Reservation is the reservation of the board held by the caller (see
+	// ReservePort). The resets of the boards reserved by others fail with
+	// ErrPortReserved, and their ports are never picked as bootloader port.
+	Reservation *Reservation
+	// Trace, if not nil, captures the full trace of the reset (see
+	// ResetTrace).
+	Trace *ResetTrace
+	// Monitors, if not nil, is asked to release the port to touch from the
+	// Monitor using it before the reset. If the monitor hands off its open
+	// port, the 1200-bps touch is performed on it. When a bootloader target
+	// is found the lease is returned in ResetResult.MonitorLease, to be
+	// completed once the board is available again (e.g. after the upload),
+	// otherwise the monitor is signaled to reconnect to the touched port
+	// when the reset completes.
+	Monitors *MonitorCoordinator
+	// PreResetHook, if not nil, is called just before the board reset. If it
+	// returns an error the reset is aborted.
+	PreResetHook ResetHook
+	// PostResetHook, if not nil, is called just after the board reset, before
+	// waiting for the bootloader port. If it returns an error the reset is aborted.
+	PostResetHook ResetHook
+
+	// holdMonitorLease makes the MonitorLease returned in the ResetResult
+	// even if no bootloader target is found, used by PrepareUpload.
+	holdMonitorLease bool
+}
+
 // Reset will reset a board using the 1200 bps port-touch and waits for the bootloader port that is returned.
 // Both reset and wait are optional:
 // - if `portToTouch` is the empty string "" the reset will be skipped
@@ -82,6 +383,7 @@ type ResetProgressCallbacks struct {
 // If `dryRun` is set to `true` this function will only emulate the port reset without actually performing
 // it, this is useful to mockup for unit-testing and CI. In dryRun mode if the `portToTouch` ends with
 // `"999"` and `wait` is `true`, the function will return a new "mocked" bootloader port as `portToTouch+"0"`.
+// The dryRun mode is deprecated, use ResetWithOptions with a Simulator instead.
 //
 // `portMapper` is a method called to obtain the current serial port list. If `portMapper` is `nil` the
 // default internal port mapper will be used.
@@ -89,117 +391,86 @@ type ResetProgressCallbacks struct {
 // `cb` is a struct defining a bunch of callback functions called during the reset operation to provide
 // progress feedback to the caller.
 func Reset(portToTouch string, wait bool, dryRun bool, portsMapper PortsMapper, cb *ResetProgressCallbacks) (string, error) {
-	if portsMapper == nil {
-		portsMapper = DefaultPortMapper // non dry-run default
-	}
-	if dryRun {
-		emulatedPort := portToTouch
-		portsMapper = func() (map[string]bool, error) {
-			res := map[string]bool{}
-			if emulatedPort != "" {
-				res[emulatedPort] = true
-			}
-			if strings.HasSuffix(emulatedPort, "999") {
-				emulatedPort += "0"
-			} else if emulatedPort == "" {
-				emulatedPort = "newport"
-			}
-			return res, nil
-		}
-	}
-
-	last, err := portsMapper()
-	if cb != nil && cb.Debug != nil {
-		cb.Debug(fmt.Sprintf("LAST: %v", last))
-	}
+	res, err := ResetWithOptions(portToTouch, &ResetOptions{
+		Wait:        wait,
+		DryRun:      dryRun,
+		PortsMapper: portsMapper,
+		Callbacks:   cb,
+	})
 	if err != nil {
 		return "", err
 	}
+	return res.Target.Path, nil
+}
 
-	if portToTouch != "" && last[portToTouch] {
-		if cb != nil && cb.Debug != nil {
-			cb.Debug(fmt.Sprintf("TOUCH: %v", portToTouch))
+// ResetWithOptions is like Reset but takes its parameters from a ResetOptions
+// struct, allowing the use of the features that are not available in Reset.
+// The bootloader target found is reported in the returned ResetResult.
+//
+// ResetWithOptions can be called concurrently on different ports: the
+// resets in progress in the process keep track of the ports touched and
+// found by each other, so that a reset doesn't mistake the bootloader port
+// of another board for its own. The concurrent resets of the same port, in
+// the process or in other processes (see LockPort), are rejected with
+// ErrResetInProgress (or serialized, see the WaitForConcurrentReset
+// option). The ResetOptions must not be modified while a reset uses them.
+func ResetWithOptions(portToTouch string, opts *ResetOptions) (*ResetResult, error) {
+	return resetWithContext(context.Background(), portToTouch, opts)
+}
+
+// resetWithContext is ResetWithOptions with a context that interrupts the
+// wait for the bootloader.
+func resetWithContext(ctx context.Context, portToTouch string, opts *ResetOptions) (res *ResetResult, err error) {
+	if opts == nil {
+		opts = &ResetOptions{}
+	}
+	metricsOf(opts).IncCounter(MetricResetAttempts)
+	ctx, span := tracerOf(opts).Start(ctx, "serialutils.Reset")
+	span.SetAttribute("port", portToTouch)
+	defer func() {
+		if res != nil {
+			span.SetAttribute("target.kind", res.Target.Kind.String())
+			span.SetAttribute("target.path", res.Target.Path)
 		}
-		if cb != nil && cb.TouchingPort != nil {
-			cb.TouchingPort(portToTouch)
+		span.End(err)
+		opts.Trace.finish(res, err)
+	}()
+
+	if portToTouch != "" && opts.Simulator == nil && !opts.DryRun {
+		var debug func(string)
+		if cb := opts.Callbacks; cb != nil {
+			debug = cb.Debug
 		}
-		if dryRun {
-			// do nothing!
-		} else {
-			if err := Touch1200bps(portToTouch); err != nil && !wait {
-				return "", fmt.Errorf("1200-bps touch: %w", err)
-			}
+		unlock, err := lockPort(ctx, portToTouch, opts.WaitForConcurrentReset, debug)
+		if err != nil {
+			return nil, err
 		}
+		defer unlock()
 	}
 
-	if !wait {
-		return "", nil
-	}
-	if cb != nil && cb.WaitingForNewSerial != nil {
-		cb.WaitingForNewSerial()
+	session, err := beginReset(ctx, portToTouch, opts, opts.Wait)
+	if err != nil {
+		return nil, err
 	}
-
-	deadline := time.Now().Add(10 * time.Second)
-	if dryRun {
-		// use a much lower timeout in dryRun
-		deadline = time.Now().Add(100 * time.Millisecond)
+	defer session.Close()
+	resetErr, err := session.touch()
+	if err != nil {
+		return nil, err
 	}
-	for time.Now().Before(deadline) {
-		now, err := portsMapper()
-		if err != nil {
-			return "", err
+	if !opts.Wait {
+		session.releasePort()
+		if resetErr != nil || !opts.holdMonitorLease {
+			session.lease.Done("")
+			session.lease = nil
 		}
-		if cb != nil && cb.Debug != nil {
-			cb.Debug(fmt.Sprintf("WAIT: %v", now))
-		}
-		hasNewPorts := false
-		for p := range now {
-			if !last[p] {
-				hasNewPorts = true
-				break
-			}
-		}
-
-		if hasNewPorts {
-			if cb != nil && cb.Debug != nil {
-				cb.Debug("New ports found!")
-			}
-
-			// on OS X, if the port is opened too quickly after it is detected,
-			// a "Resource busy" error occurs, add a delay to workaround.
-			// This apply to other platforms as well.
-			time.Sleep(time.Second)
-
-			// Some boards have a glitch in the bootloader: some user experienced
-			// the USB serial port appearing and disappearing rapidly before
-			// settling.
-			// This check ensure that the port is stable after one second.
-			check, err := portsMapper()
-			if err != nil {
-				return "", err
-			}
-			if cb != nil && cb.Debug != nil {
-				cb.Debug(fmt.Sprintf("CHECK: %v", check))
-			}
-			for p := range check {
-				if !last[p] {
-					if cb != nil && cb.BootloaderPortFound != nil {
-						cb.BootloaderPortFound(p)
-					}
-					return p, nil // Found it!
-				}
-			}
-			if cb != nil && cb.Debug != nil {
-				cb.Debug("Port check failed... still waiting")
-			}
+		if resetErr != nil {
+			return nil, resetErr
 		}
-
-		last = now
-		time.Sleep(250 * time.Millisecond)
-	}
-
-	if cb != nil && cb.BootloaderPortFound != nil {
-		cb.BootloaderPortFound("")
+		res := newResetResult(NoTarget, "")
+		res.Skipped = opts.SkipReset
+		res.MonitorLease = session.lease
+		session.lease = nil
+		return res, nil
 	}
-	return "", nil
+	return session.WaitForBootloader(ctx)
 }
@@ -0,0 +1,191 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// TraceEventType is the type of a TraceEvent.
+type TraceEventType string
+
+const (
+	// TraceSnapshot is a listing of the ports.
+	TraceSnapshot TraceEventType = "snapshot"
+	// TraceTouch is the reset of the port.
+	TraceTouch TraceEventType = "touch"
+	// TraceWait is the start of the wait for the bootloader.
+	TraceWait TraceEventType = "wait"
+	// TraceDecision is a decision taken by the reset, like ignoring or
+	// picking a new port.
+	TraceDecision TraceEventType = "decision"
+	// TraceFound is the bootloader target found (or the end of the wait
+	// without finding it).
+	TraceFound TraceEventType = "found"
+	// TraceResult is the outcome of the reset.
+	TraceResult TraceEventType = "result"
+)
+
+// TraceEvent is an event of a ResetTrace.
+type TraceEvent struct {
+	// Time is the time elapsed since the start of the reset.
+	Time time.Duration  `json:"time"`
+	Type TraceEventType `json:"type"`
+	// Ports are the ports listed, for the TraceSnapshot events (omitted if
+	// no ports are listed).
+	Ports []string `json:"ports,omitempty"`
+	// Port is the port touched or found.
+	Port string `json:"port,omitempty"`
+	// Message describes the decision taken.
+	Message string `json:"message,omitempty"`
+	// Error is the error of the event, if any.
+	Error string `json:"error,omitempty"`
+	// Result is the result of the reset, for the TraceResult events.
+	Result *ResetResult `json:"result,omitempty"`
+}
+
+// ResetTrace captures the full trace of a reset: all the port snapshots
+// with their timestamps, the decisions taken and the errors. It can be
+// serialized in JSON and attached to the bug reports. To capture a trace
+// set ResetOptions.Trace to a new ResetTrace, it is filled by the reset (a
+// ResetTrace reused by another reset is cleared first).
+type ResetTrace struct {
+	mux   sync.Mutex
+	clock Clock
+	done  bool
+	// Port is the port reset.
+	Port string `json:"port"`
+	// Start is the time the reset started.
+	Start  time.Time    `json:"start"`
+	Events []TraceEvent `json:"events"`
+}
+
+// begin clears the trace and returns a copy of the options with the
+// callbacks recording the events in the trace.
+func (t *ResetTrace) begin(port string, opts *ResetOptions, clock Clock) *ResetOptions {
+	t.mux.Lock()
+	t.clock = clock
+	t.done = false
+	t.Port = port
+	t.Start = clock.Now()
+	t.Events = nil
+	t.mux.Unlock()
+
+	traced := *opts
+	cb := opts.Callbacks
+	if cb == nil {
+		cb = &ResetProgressCallbacks{}
+	}
+	traced.Callbacks = &ResetProgressCallbacks{
+		TouchingPort: func(port string) {
+			t.record(TraceEvent{Type: TraceTouch, Port: port})
+			if cb.TouchingPort != nil {
+				cb.TouchingPort(port)
+			}
+		},
+		WaitingForNewSerial: func() {
+			t.record(TraceEvent{Type: TraceWait})
+			if cb.WaitingForNewSerial != nil {
+				cb.WaitingForNewSerial()
+			}
+		},
+		BootloaderPortFound: func(port string) {
+			t.record(TraceEvent{Type: TraceFound, Port: port})
+			if cb.BootloaderPortFound != nil {
+				cb.BootloaderPortFound(port)
+			}
+		},
+		TouchingPortDetails:        cb.TouchingPortDetails,
+		BootloaderPortFoundDetails: cb.BootloaderPortFoundDetails,
+		WaitProgress:               cb.WaitProgress,
+		Debug: func(msg string) {
+			t.record(TraceEvent{Type: TraceDecision, Message: msg})
+			if cb.Debug != nil {
+				cb.Debug(msg)
+			}
+		},
+	}
+	return &traced
+}
+
+// portsMapper returns a PortsMapper recording the snapshots of the given
+// mapper in the trace.
+func (t *ResetTrace) portsMapper(portsMapper PortsMapper) PortsMapper {
+	return func() (map[string]bool, error) {
+		ports, err := portsMapper()
+		ev := TraceEvent{Type: TraceSnapshot, Ports: sortedKeys(ports)}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+		t.record(ev)
+		return ports, err
+	}
+}
+
+// finish records the outcome of the reset, only the first time it is called
+// after the trace began. It does nothing on a nil trace.
+func (t *ResetTrace) finish(res *ResetResult, err error) {
+	if t == nil {
+		return
+	}
+	t.mux.Lock()
+	done := t.done
+	t.done = true
+	t.mux.Unlock()
+	if done {
+		return
+	}
+	ev := TraceEvent{Type: TraceResult, Result: res}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	t.record(ev)
+}
+
+func (t *ResetTrace) record(ev TraceEvent) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.clock == nil {
+		t.clock = SystemClock
+		t.Start = t.clock.Now()
+	}
+	ev.Time = t.clock.Now().Sub(t.Start)
+	t.Events = append(t.Events, ev)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *ResetTrace) MarshalJSON() ([]byte, error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	type trace struct {
+		Port   string       `json:"port"`
+		Start  time.Time    `json:"start"`
+		Events []TraceEvent `json:"events"`
+	}
+	return json.Marshal(trace{Port: t.Port, Start: t.Start, Events: t.Events})
+}
+
+// WriteFile writes the trace in JSON format to the file at the given path.
+func (t *ResetTrace) WriteFile(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
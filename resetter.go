@@ -0,0 +1,35 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+// Resetter is a strategy to put the board connected to a serial port in
+// bootloader mode.
+type Resetter interface {
+	// Reset puts the board connected to the given port in bootloader mode.
+	Reset(port string) error
+}
+
+// ResetterFunc is an adapter to use an ordinary function as a Resetter.
+type ResetterFunc func(port string) error
+
+// Reset calls f(port).
+func (f ResetterFunc) Reset(port string) error {
+	return f(port)
+}
+
+// Touch1200bpsResetter is the Resetter performing the 1200-bps touch, it's
+// the default strategy used by Reset.
+var Touch1200bpsResetter Resetter = ResetterFunc(Touch1200bps)
@@ -0,0 +1,419 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// RFC2217Prefix is the prefix of the names of the ports served by an RFC 2217
+// remote serial server, e.g. "rfc2217://192.168.1.10:4000".
+const RFC2217Prefix = "rfc2217://"
+
+// IsRFC2217Port returns true if the port name refers to an RFC 2217 remote
+// serial port.
+func IsRFC2217Port(port string) bool {
+	return strings.HasPrefix(port, RFC2217Prefix)
+}
+
+// RFC2217PortsMapper returns a PortsMapper that reports the RFC 2217 ports,
+// given as "host:port" addresses, that are currently accepting connections.
+//
+// The servers like ser2net open the serial device, asserting DTR, as soon as
+// a client connects, so the mapper doesn't connect on every poll: it keeps
+// one idle control connection per address, checks that it is still alive and
+// dials again only when the server has closed it. The idle connection is
+// handed over to the next opening of the port (see OpenPort), and the data
+// received on it meanwhile are discarded. Since the servers usually accept a
+// single client per port, the idle connections keep the ports busy for the
+// other processes until CloseIdleRFC2217Connections is called.
+func RFC2217PortsMapper(addresses ...string) PortsMapper {
+	return func() (map[string]bool, error) {
+		res := map[string]bool{}
+		for _, address := range addresses {
+			if rfc2217Conns.check(address) {
+				res[RFC2217Prefix+address] = true
+			}
+		}
+		return res, nil
+	}
+}
+
+// CloseIdleRFC2217Connections closes the idle control connections kept open
+// by the RFC2217PortsMapper, releasing the remote ports.
+func CloseIdleRFC2217Connections() {
+	rfc2217Conns.closeIdle()
+}
+
+// rfc2217Conns keeps track of the connections to the RFC 2217 servers: the
+// idle control connections of the mappers and the ports in use.
+var rfc2217Conns = &rfc2217Pool{idle: map[string]net.Conn{}, inUse: map[string]int{}}
+
+type rfc2217Pool struct {
+	mux   sync.Mutex
+	idle  map[string]net.Conn
+	inUse map[string]int
+}
+
+// check returns true if the server at the address is reachable: the port is
+// in use or its idle control connection is alive, otherwise a new control
+// connection is dialed.
+func (p *rfc2217Pool) check(address string) bool {
+	p.mux.Lock()
+	if p.inUse[address] > 0 {
+		p.mux.Unlock()
+		return true
+	}
+	if conn := p.idle[address]; conn != nil {
+		if rfc2217ConnAlive(conn) {
+			p.mux.Unlock()
+			return true
+		}
+		_ = conn.Close()
+		delete(p.idle, address)
+	}
+	p.mux.Unlock()
+
+	conn, err := net.DialTimeout("tcp", address, time.Second)
+	if err != nil {
+		return false
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.inUse[address] > 0 || p.idle[address] != nil {
+		// The port has been opened, or checked by another mapper, meanwhile
+		_ = conn.Close()
+		return true
+	}
+	p.idle[address] = conn
+	return true
+}
+
+// take returns the idle control connection to the address, if alive, and
+// marks the port as in use until release is called.
+func (p *rfc2217Pool) take(address string) net.Conn {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.inUse[address]++
+	conn := p.idle[address]
+	delete(p.idle, address)
+	if conn != nil && !rfc2217ConnAlive(conn) {
+		_ = conn.Close()
+		return nil
+	}
+	return conn
+}
+
+func (p *rfc2217Pool) release(address string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.inUse[address]--; p.inUse[address] <= 0 {
+		delete(p.inUse, address)
+	}
+}
+
+func (p *rfc2217Pool) closeIdle() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	for address, conn := range p.idle {
+		_ = conn.Close()
+		delete(p.idle, address)
+	}
+}
+
+// rfc2217ConnAlive returns true if the connection has not been closed by the
+// server, discarding the data received.
+func rfc2217ConnAlive(conn net.Conn) bool {
+	buff := make([]byte, 256)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		if _, err := conn.Read(buff); err != nil {
+			var ne net.Error
+			return errors.As(err, &ne) && ne.Timeout()
+		}
+	}
+}
+
+// Telnet and RFC 2217 protocol constants
+const (
+	telnetIAC            = 255
+	telnetWILL           = 251
+	telnetWONT           = 252
+	telnetDO             = 253
+	telnetDONT           = 254
+	telnetSB             = 250
+	telnetSE             = 240
+	rfc2217ComPortOption = 44
+	rfc2217SetBaudrate   = 1
+	rfc2217SetDataSize   = 2
+	rfc2217SetParity     = 3
+	rfc2217SetStopSize   = 4
+	rfc2217SetControl    = 5
+	rfc2217PurgeData     = 12
+	rfc2217BreakOn       = 5
+	rfc2217BreakOff      = 6
+	rfc2217DTROn         = 8
+	rfc2217DTROff        = 9
+	rfc2217RTSOn         = 11
+	rfc2217RTSOff        = 12
+)
+
+// TouchRFC2217 performs the 1200-bps touch on an RFC 2217 remote serial port:
+// the remote port is configured at 1200 bps, DTR is deasserted and the
+// connection is closed. The port is opened with the Transport registered for
+// the RFC2217Prefix.
+func TouchRFC2217(port string) error {
+	return touchRFC2217(port, SystemClock)
+}
+
+func touchRFC2217(port string, clock Clock) error {
+	p, err := OpenPort(port, &serial.Mode{BaudRate: 1200})
+	if err != nil {
+		return fmt.Errorf("opening port at 1200bps: %w", err)
+	}
+	if err := p.SetDTR(false); err != nil {
+		_ = p.Close()
+		return fmt.Errorf("setting DTR to OFF: %w", err)
+	}
+
+	// Give the server the time to apply the settings before closing the
+	// connection, then wait for the reset as in Touch1200bps.
+	clock.Sleep(100 * time.Millisecond)
+	_ = p.Close()
+	clock.Sleep(DefaultPostTouchDelay)
+	return nil
+}
+
+// rfc2217Port is a serial.Port backed by a connection to an RFC 2217 server.
+type rfc2217Port struct {
+	conn        net.Conn
+	address     string
+	readTimeout time.Duration
+	closeOnce   sync.Once
+	// state of the telnet stream parser
+	state byte
+}
+
+func openRFC2217(port string, mode *serial.Mode) (serial.Port, error) {
+	address := strings.TrimPrefix(port, RFC2217Prefix)
+	conn := rfc2217Conns.take(address)
+	if conn == nil {
+		var err error
+		conn, err = net.DialTimeout("tcp", address, 5*time.Second)
+		if err != nil {
+			rfc2217Conns.release(address)
+			return nil, fmt.Errorf("connecting to %s: %w", address, err)
+		}
+	}
+	p := &rfc2217Port{conn: conn, address: address, readTimeout: serial.NoTimeout}
+	if err := p.send([]byte{telnetIAC, telnetWILL, rfc2217ComPortOption}); err != nil {
+		_ = p.Close()
+		return nil, err
+	}
+	if err := p.SetMode(mode); err != nil {
+		_ = p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *rfc2217Port) send(msg []byte) error {
+	_ = p.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := p.conn.Write(msg); err != nil {
+		return fmt.Errorf("sending RFC 2217 command: %w", err)
+	}
+	return nil
+}
+
+func (p *rfc2217Port) SetMode(mode *serial.Mode) error {
+	if mode == nil {
+		return nil
+	}
+	baudrate := make([]byte, 4)
+	binary.BigEndian.PutUint32(baudrate, uint32(mode.BaudRate))
+	msg := rfc2217Command(rfc2217SetBaudrate, baudrate...)
+	if mode.DataBits != 0 {
+		msg = append(msg, rfc2217Command(rfc2217SetDataSize, byte(mode.DataBits))...)
+	}
+	// RFC 2217 parity values are shifted by one from serial.Parity
+	msg = append(msg, rfc2217Command(rfc2217SetParity, byte(mode.Parity)+1)...)
+	switch mode.StopBits {
+	case serial.OneStopBit:
+		msg = append(msg, rfc2217Command(rfc2217SetStopSize, 1)...)
+	case serial.OnePointFiveStopBits:
+		msg = append(msg, rfc2217Command(rfc2217SetStopSize, 3)...)
+	case serial.TwoStopBits:
+		msg = append(msg, rfc2217Command(rfc2217SetStopSize, 2)...)
+	}
+	if mode.InitialStatusBits != nil {
+		msg = append(msg, rfc2217Command(rfc2217SetControl, rfc2217ControlValue(mode.InitialStatusBits.DTR, rfc2217DTROn, rfc2217DTROff))...)
+		msg = append(msg, rfc2217Command(rfc2217SetControl, rfc2217ControlValue(mode.InitialStatusBits.RTS, rfc2217RTSOn, rfc2217RTSOff))...)
+	}
+	return p.send(msg)
+}
+
+func (p *rfc2217Port) Read(buff []byte) (int, error) {
+	for {
+		if p.readTimeout == serial.NoTimeout {
+			_ = p.conn.SetReadDeadline(time.Time{})
+		} else {
+			_ = p.conn.SetReadDeadline(time.Now().Add(p.readTimeout))
+		}
+		n, err := p.conn.Read(buff)
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return 0, nil
+		}
+		n = p.filterTelnet(buff[:n])
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// filterTelnet removes the telnet commands from the data received and
+// returns the number of data bytes left in buff.
+func (p *rfc2217Port) filterTelnet(buff []byte) int {
+	const (
+		stData = iota
+		stIAC
+		stOption
+		stSB
+		stSBIAC
+	)
+	n := 0
+	for _, b := range buff {
+		switch p.state {
+		case stData:
+			if b == telnetIAC {
+				p.state = stIAC
+			} else {
+				buff[n] = b
+				n++
+			}
+		case stIAC:
+			switch b {
+			case telnetIAC:
+				buff[n] = b
+				n++
+				p.state = stData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				p.state = stOption
+			case telnetSB:
+				p.state = stSB
+			default:
+				p.state = stData
+			}
+		case stOption:
+			p.state = stData
+		case stSB:
+			if b == telnetIAC {
+				p.state = stSBIAC
+			}
+		case stSBIAC:
+			if b == telnetSE {
+				p.state = stData
+			} else {
+				p.state = stSB
+			}
+		}
+	}
+	return n
+}
+
+func (p *rfc2217Port) Write(buff []byte) (int, error) {
+	escaped := make([]byte, 0, len(buff))
+	for _, b := range buff {
+		escaped = append(escaped, b)
+		if b == telnetIAC {
+			escaped = append(escaped, telnetIAC)
+		}
+	}
+	_ = p.conn.SetWriteDeadline(time.Time{})
+	if _, err := p.conn.Write(escaped); err != nil {
+		return 0, err
+	}
+	return len(buff), nil
+}
+
+func (p *rfc2217Port) Drain() error {
+	return nil
+}
+
+func (p *rfc2217Port) ResetInputBuffer() error {
+	return p.send(rfc2217Command(rfc2217PurgeData, 1))
+}
+
+func (p *rfc2217Port) ResetOutputBuffer() error {
+	return p.send(rfc2217Command(rfc2217PurgeData, 2))
+}
+
+func (p *rfc2217Port) SetDTR(dtr bool) error {
+	return p.send(rfc2217Command(rfc2217SetControl, rfc2217ControlValue(dtr, rfc2217DTROn, rfc2217DTROff)))
+}
+
+func (p *rfc2217Port) SetRTS(rts bool) error {
+	return p.send(rfc2217Command(rfc2217SetControl, rfc2217ControlValue(rts, rfc2217RTSOn, rfc2217RTSOff)))
+}
+
+func (p *rfc2217Port) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return nil, errors.New("modem status bits not supported on RFC 2217 ports")
+}
+
+func (p *rfc2217Port) SetReadTimeout(t time.Duration) error {
+	p.readTimeout = t
+	return nil
+}
+
+func (p *rfc2217Port) Close() error {
+	err := p.conn.Close()
+	p.closeOnce.Do(func() { rfc2217Conns.release(p.address) })
+	return err
+}
+
+func (p *rfc2217Port) Break(d time.Duration) error {
+	if err := p.send(rfc2217Command(rfc2217SetControl, rfc2217BreakOn)); err != nil {
+		return err
+	}
+	time.Sleep(d)
+	return p.send(rfc2217Command(rfc2217SetControl, rfc2217BreakOff))
+}
+
+func rfc2217ControlValue(on bool, onValue, offValue byte) byte {
+	if on {
+		return onValue
+	}
+	return offValue
+}
+
+// rfc2217Command encodes a COM-PORT-OPTION subnegotiation.
+func rfc2217Command(cmd byte, data ...byte) []byte {
+	res := []byte{telnetIAC, telnetSB, rfc2217ComPortOption, cmd}
+	for _, b := range data {
+		res = append(res, b)
+		if b == telnetIAC {
+			res = append(res, telnetIAC)
+		}
+	}
+	return append(res, telnetIAC, telnetSE)
+}
@@ -0,0 +1,185 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// fakeRFC2217Server accepts the connections on a local listener and sends
+// each one to the conns channel.
+func fakeRFC2217Server(t *testing.T) (string, net.Listener, chan net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		_ = l.Close()
+		CloseIdleRFC2217Connections()
+	})
+	return l.Addr().String(), l, conns
+}
+
+func acceptConn(t *testing.T, conns chan net.Conn) net.Conn {
+	select {
+	case conn := <-conns:
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("no connection received")
+		return nil
+	}
+}
+
+func TestRFC2217Negotiation(t *testing.T) {
+	address, _, conns := fakeRFC2217Server(t)
+	p, err := openRFC2217(RFC2217Prefix+address, &serial.Mode{
+		BaudRate: 1200,
+		DataBits: 8,
+		Parity:   serial.EvenParity,
+		StopBits: serial.TwoStopBits,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := acceptConn(t, conns)
+	if err := p.SetDTR(false); err != nil {
+		t.Fatal(err)
+	}
+	// The 0xFF data bytes are escaped
+	if _, err := p.Write([]byte{'a', telnetIAC}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	received, err := io.ReadAll(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		telnetIAC, telnetWILL, rfc2217ComPortOption,
+		telnetIAC, telnetSB, rfc2217ComPortOption, rfc2217SetBaudrate, 0, 0, 0x04, 0xb0, telnetIAC, telnetSE,
+		telnetIAC, telnetSB, rfc2217ComPortOption, rfc2217SetDataSize, 8, telnetIAC, telnetSE,
+		telnetIAC, telnetSB, rfc2217ComPortOption, rfc2217SetParity, 3, telnetIAC, telnetSE,
+		telnetIAC, telnetSB, rfc2217ComPortOption, rfc2217SetStopSize, 2, telnetIAC, telnetSE,
+		telnetIAC, telnetSB, rfc2217ComPortOption, rfc2217SetControl, rfc2217DTROff, telnetIAC, telnetSE,
+		'a', telnetIAC, telnetIAC,
+	}
+	if !bytes.Equal(received, expected) {
+		t.Fatalf("wrong negotiation\n got: %v\nwant: %v", received, expected)
+	}
+}
+
+func TestRFC2217ReadFiltersTelnet(t *testing.T) {
+	address, _, conns := fakeRFC2217Server(t)
+	p, err := openRFC2217(RFC2217Prefix+address, &serial.Mode{BaudRate: 115200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	server := acceptConn(t, conns)
+	_, err = server.Write([]byte{
+		'a', telnetIAC, telnetIAC,
+		telnetIAC, telnetDO, rfc2217ComPortOption,
+		telnetIAC, telnetSB, rfc2217ComPortOption, 101, 0, 0, 0x04, 0xb0, telnetIAC, telnetSE,
+		'b',
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetReadTimeout(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	buff := make([]byte, 64)
+	for len(data) < 3 {
+		n, err := p.Read(buff)
+		if err != nil || n == 0 {
+			t.Fatalf("reading: %d, %v", n, err)
+		}
+		data = append(data, buff[:n]...)
+	}
+	if !bytes.Equal(data, []byte{'a', telnetIAC, 'b'}) {
+		t.Fatalf("wrong data read: %v", data)
+	}
+}
+
+func TestRFC2217PortsMapperKeepsConnection(t *testing.T) {
+	address, l, conns := fakeRFC2217Server(t)
+	port := RFC2217Prefix + address
+	mapper := RFC2217PortsMapper(address)
+	for i := 0; i < 3; i++ {
+		ports, err := mapper()
+		if err != nil || !ports[port] {
+			t.Fatalf("port not found: %v, %v", ports, err)
+		}
+	}
+	server := acceptConn(t, conns)
+	select {
+	case <-conns:
+		t.Fatal("the mapper connected more than once")
+	default:
+	}
+
+	// The port is opened on the control connection of the mapper
+	p, err := openRFC2217(port, &serial.Mode{BaudRate: 1200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff := make([]byte, 3)
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(server, buff); err != nil || !bytes.Equal(buff, []byte{telnetIAC, telnetWILL, rfc2217ComPortOption}) {
+		t.Fatalf("the port didn't use the control connection: %v, %v", buff, err)
+	}
+	if ports, _ := mapper(); !ports[port] {
+		t.Fatal("port in use not found")
+	}
+	select {
+	case <-conns:
+		t.Fatal("the mapper connected while the port is in use")
+	default:
+	}
+	_ = p.Close()
+
+	// The mapper connects again once the port is closed, and reports the
+	// port as gone when the server drops the connection
+	if ports, _ := mapper(); !ports[port] {
+		t.Fatal("port not found after close")
+	}
+	_ = l.Close()
+	_ = acceptConn(t, conns).Close()
+	if ports, _ := mapper(); ports[port] {
+		t.Fatal("port found after the server went away")
+	}
+}
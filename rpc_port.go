@@ -0,0 +1,145 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RPCPort is the read-only view of the arduino-cli gRPC Port message
+// (cc.arduino.cli.commands.v1.Port), as exposed by its generated getters.
+// It allows converting a *rpc.Port without depending on arduino-cli:
+//
+//	details := serialutils.DiscoveryPortFromRPC(port).PortDetails()
+//
+// The converse conversion is done by DiscoveryPort.ToRPC:
+//
+//	port := &rpc.Port{}
+//	err := serialutils.NewDiscoveryPort(details).ToRPC(port)
+type RPCPort interface {
+	GetAddress() string
+	GetLabel() string
+	GetProtocol() string
+	GetProtocolLabel() string
+	GetHardwareId() string
+	GetProperties() map[string]string
+}
+
+// DiscoveryPortFromRPC converts an arduino-cli gRPC Port message into a
+// DiscoveryPort. It returns nil if port is nil.
+func DiscoveryPortFromRPC(port RPCPort) *DiscoveryPort {
+	if port == nil {
+		return nil
+	}
+	properties := map[string]string{}
+	for k, v := range port.GetProperties() {
+		properties[k] = v
+	}
+	return &DiscoveryPort{
+		Address:       port.GetAddress(),
+		Label:         port.GetLabel(),
+		Protocol:      port.GetProtocol(),
+		ProtocolLabel: port.GetProtocolLabel(),
+		HardwareID:    port.GetHardwareId(),
+		Properties:    properties,
+	}
+}
+
+// RPCPortSetter is the write view of a gRPC Port message generated with the
+// setters (like the messages of the protobuf opaque API).
+type RPCPortSetter interface {
+	SetAddress(string)
+	SetLabel(string)
+	SetProtocol(string)
+	SetProtocolLabel(string)
+	SetHardwareId(string)
+	SetProperties(map[string]string)
+}
+
+// ToRPC fills an arduino-cli gRPC Port message, or any message with the same
+// fields, with the DiscoveryPort: port is either an RPCPortSetter or a
+// pointer to a struct with the Address, Label, Protocol, ProtocolLabel,
+// HardwareId and Properties fields (like a *rpc.Port).
+func (p *DiscoveryPort) ToRPC(port any) error {
+	properties := map[string]string{}
+	for k, v := range p.Properties {
+		properties[k] = v
+	}
+	if setter, ok := port.(RPCPortSetter); ok {
+		setter.SetAddress(p.Address)
+		setter.SetLabel(p.Label)
+		setter.SetProtocol(p.Protocol)
+		setter.SetProtocolLabel(p.ProtocolLabel)
+		setter.SetHardwareId(p.HardwareID)
+		setter.SetProperties(properties)
+		return nil
+	}
+
+	v := reflect.ValueOf(port)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("converting to RPC port: %T is not a pointer to a struct", port)
+	}
+	v = v.Elem()
+	fields := []struct {
+		name  string
+		value any
+	}{
+		{"Address", p.Address},
+		{"Label", p.Label},
+		{"Protocol", p.Protocol},
+		{"ProtocolLabel", p.ProtocolLabel},
+		{"HardwareId", p.HardwareID},
+		{"Properties", properties},
+	}
+	for _, field := range fields {
+		f := v.FieldByName(field.name)
+		value := reflect.ValueOf(field.value)
+		if !f.IsValid() || !f.CanSet() || f.Type() != value.Type() {
+			return fmt.Errorf("converting to RPC port: %T has no %s field of type %s", port, field.name, value.Type())
+		}
+	}
+	for _, field := range fields {
+		v.FieldByName(field.name).Set(reflect.ValueOf(field.value))
+	}
+	return nil
+}
+
+// PortID returns the identity of the DiscoveryPort.
+func (p *DiscoveryPort) PortID() PortID {
+	id := NewPortID(p.PortDetails())
+	if location := p.Properties["usbLocation"]; location != "" {
+		id.Location = location
+	}
+	return id
+}
+
+// NewDiscoveryPortFromID converts a PortID into a DiscoveryPort, like
+// NewDiscoveryPort does for a PortDetails. The USB location, if known, is
+// reported in the "usbLocation" property.
+func NewDiscoveryPortFromID(id PortID) *DiscoveryPort {
+	res := NewDiscoveryPort(&PortDetails{
+		Name:         id.Name,
+		IsUSB:        id.VID != "" && id.PID != "",
+		VID:          id.VID,
+		PID:          id.PID,
+		SerialNumber: id.SerialNumber,
+	})
+	if id.Location != "" {
+		res.Properties["usbLocation"] = id.Location
+	}
+	return res
+}
@@ -0,0 +1,98 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils_test
+
+import (
+	"reflect"
+	"testing"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+// rpcPort mimics the generated arduino-cli gRPC Port message.
+type rpcPort struct {
+	state         int
+	Address       string
+	Label         string
+	Protocol      string
+	ProtocolLabel string
+	Properties    map[string]string
+	HardwareId    string
+}
+
+func (p *rpcPort) GetAddress() string               { return p.Address }
+func (p *rpcPort) GetLabel() string                 { return p.Label }
+func (p *rpcPort) GetProtocol() string              { return p.Protocol }
+func (p *rpcPort) GetProtocolLabel() string         { return p.ProtocolLabel }
+func (p *rpcPort) GetHardwareId() string            { return p.HardwareId }
+func (p *rpcPort) GetProperties() map[string]string { return p.Properties }
+
+// opaqueRPCPort mimics a Port message generated with the setters.
+type opaqueRPCPort struct {
+	rpcPort
+}
+
+func (p *opaqueRPCPort) SetAddress(v string)               { p.Address = v }
+func (p *opaqueRPCPort) SetLabel(v string)                 { p.Label = v }
+func (p *opaqueRPCPort) SetProtocol(v string)              { p.Protocol = v }
+func (p *opaqueRPCPort) SetProtocolLabel(v string)         { p.ProtocolLabel = v }
+func (p *opaqueRPCPort) SetHardwareId(v string)            { p.HardwareId = v }
+func (p *opaqueRPCPort) SetProperties(v map[string]string) { p.Properties = v }
+
+func TestDiscoveryPortRPCRoundTrip(t *testing.T) {
+	port := serialutils.NewDiscoveryPort(&serialutils.PortDetails{
+		Name:         "/dev/ttyACM0",
+		IsUSB:        true,
+		VID:          "2341",
+		PID:          "8036",
+		SerialNumber: "1234",
+	})
+	for _, dst := range []serialutils.RPCPort{&rpcPort{}, &opaqueRPCPort{}} {
+		if err := port.ToRPC(dst); err != nil {
+			t.Fatal(err)
+		}
+		if dst.GetAddress() != "/dev/ttyACM0" || dst.GetProperties()["vid"] != port.Properties["vid"] {
+			t.Fatalf("%T not filled: %+v", dst, dst)
+		}
+		back := serialutils.DiscoveryPortFromRPC(dst)
+		if !reflect.DeepEqual(back, port) {
+			t.Fatalf("%T: got %+v back, want %+v", dst, back, port)
+		}
+		// The properties are copied
+		dst.GetProperties()["vid"] = "0000"
+		if port.Properties["vid"] == "0000" {
+			t.Fatalf("%T shares the properties of the DiscoveryPort", dst)
+		}
+	}
+}
+
+func TestDiscoveryPortToRPCErrors(t *testing.T) {
+	port := &serialutils.DiscoveryPort{Address: "/dev/ttyACM0"}
+	var nilPort *rpcPort
+	for _, dst := range []any{
+		rpcPort{},
+		nilPort,
+		&struct{ Address string }{},
+		&struct {
+			Address, Label, Protocol, ProtocolLabel, HardwareId string
+			Properties                                          []string
+		}{},
+	} {
+		if err := port.ToRPC(dst); err == nil {
+			t.Errorf("%T: expected an error", dst)
+		}
+	}
+}
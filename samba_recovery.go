@@ -0,0 +1,206 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SAMBARecoveryOptions contains the parameters of RecoverSAMBA.
+type SAMBARecoveryOptions struct {
+	// Port is the port of the board, if known. If present when the recovery
+	// starts it's touched like the ports appearing during the recovery.
+	Port string
+	// Timeout is the maximum duration of the recovery, 30 seconds if zero:
+	// the user may need to plug the board or press its reset button
+	// meanwhile.
+	Timeout time.Duration
+	// PollInterval is the interval between two enumerations of the ports,
+	// 10 ms if zero: it must be short enough to catch the ports appearing
+	// only briefly, before the sketch crashes.
+	PollInterval time.Duration
+	// BootloaderIDs are the USB IDs of the bootloader ports, if empty the
+	// KnownBootloaderIDs and the ones of the registered boards are used.
+	BootloaderIDs []USBID
+	// Accept, if not nil, restricts the new ports touched to the ones it
+	// accepts. All the new USB ports are touched otherwise.
+	Accept func(port *PortDetails) bool
+	// Erase erases the sketch once the bootloader is found, so that the
+	// board stays in the bootloader at the next resets. It's supported only
+	// by the Arduino extended SAM-BA bootloader (BOSSABootloader).
+	Erase bool
+	// EraseAddress is the start of the flash area erased, 0x2000 if zero
+	// (the sketch area after the 8 KB bootloader of the SAMD21 boards).
+	EraseAddress uint32
+	// DetailedPortsMapper is used to list the ports, if nil the
+	// DefaultDetailedPortMapper is used.
+	DetailedPortsMapper DetailedPortsMapper
+	// Clock is used for the timing, if nil the SystemClock is used.
+	Clock Clock
+	// Debug, if not nil, is called with debugging messages.
+	Debug func(msg string)
+}
+
+// SAMBARecoveryResult is the outcome of RecoverSAMBA.
+type SAMBARecoveryResult struct {
+	// Port is the port of the SAM-BA bootloader, empty if it was not found
+	// within the timeout.
+	Port string `json:"port,omitempty"`
+	// Bootloader is the outcome of the verification of the bootloader.
+	Bootloader *BootloaderInfo `json:"bootloader,omitempty"`
+	// Touches is the number of 1200-bps touches performed.
+	Touches int `json:"touches"`
+	// Erased tells if the sketch has been erased.
+	Erased bool `json:"erased,omitempty"`
+}
+
+// RecoverSAMBA brings a SAMD board whose sketch is corrupted (and never
+// enumerates, or enumerates only briefly before crashing) in its SAM-BA
+// bootloader, emulating the double-tap of the reset button of the board
+// recovery guides: every new port appearing is touched at 1200 bps, until
+// a bootloader port appears and answers to SAM-BA. The user may have to
+// plug the board or press its reset button during the recovery. The sketch
+// can be erased once the bootloader is found, see SAMBARecoveryOptions.
+func RecoverSAMBA(opts *SAMBARecoveryOptions) (*SAMBARecoveryResult, error) {
+	if opts == nil {
+		opts = &SAMBARecoveryOptions{}
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval == 0 {
+		pollInterval = 10 * time.Millisecond
+	}
+	bootloaderIDs := opts.BootloaderIDs
+	if len(bootloaderIDs) == 0 {
+		bootloaderIDs = defaultBootloaderIDs()
+	}
+	mapper := opts.DetailedPortsMapper
+	if mapper == nil {
+		mapper = DefaultDetailedPortMapper
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	debug := func(msg string) {
+		if opts.Debug != nil {
+			opts.Debug(msg)
+		}
+	}
+
+	baseline, err := mapper()
+	if err != nil {
+		return nil, fmt.Errorf("listing ports: %w", err)
+	}
+	if opts.Port != "" {
+		delete(baseline, opts.Port)
+	}
+	res := &SAMBARecoveryResult{}
+	// The ports already touched or probed in their current appearance
+	touched := map[string]bool{}
+	probed := map[string]bool{}
+	deadline := clock.Now().Add(timeout)
+	for {
+		ports, err := mapper()
+		if err != nil && !errors.Is(err, errTransientEnumeration) {
+			return nil, fmt.Errorf("listing ports: %w", err)
+		}
+		for _, name := range sortedKeys(ports) {
+			details := ports[name]
+			if MatchesAny(bootloaderIDs, details) {
+				if probed[name] {
+					continue
+				}
+				probed[name] = true
+				info := probeBootloader(name, opts.Debug)
+				if info.Kind != SAMBABootloader && info.Kind != BOSSABootloader {
+					continue
+				}
+				debug(fmt.Sprintf("RECOVER: %s bootloader found on %s", info.Kind, name))
+				res.Port, res.Bootloader = name, info
+				if opts.Erase {
+					if err := sambaErase(name, info, opts.EraseAddress); err != nil {
+						return res, fmt.Errorf("erasing sketch: %w", err)
+					}
+					res.Erased = true
+				}
+				return res, nil
+			}
+			if baseline[name] != nil || touched[name] || !details.IsUSB || (opts.Accept != nil && !opts.Accept(details)) {
+				continue
+			}
+			// Likely the sketch appearing before crashing: the touch makes it
+			// jump to the bootloader
+			touched[name] = true
+			res.Touches++
+			debug(fmt.Sprintf("RECOVER: touching %s", name))
+			if err := Touch1200bpsWithOptions(name, &TouchOptions{Clock: clock, DetailedPortsMapper: mapper}); err != nil {
+				debug(fmt.Sprintf("RECOVER: touching %s: %v", name, err))
+			}
+		}
+		if err == nil {
+			// The ports gone are handled again at their next appearance
+			for name := range touched {
+				if ports[name] == nil {
+					delete(touched, name)
+				}
+			}
+			for name := range probed {
+				if ports[name] == nil {
+					delete(probed, name)
+				}
+			}
+		}
+		if !clock.Now().Before(deadline) {
+			debug("RECOVER: no SAM-BA bootloader found")
+			return res, nil
+		}
+		clock.Sleep(pollInterval)
+	}
+}
+
+// sambaErase erases the flash from the given address with the erase command
+// of the Arduino extended SAM-BA, after switching to binary mode.
+func sambaErase(port string, info *BootloaderInfo, address uint32) error {
+	if info.Kind != BOSSABootloader {
+		return fmt.Errorf("not supported by the %s bootloader", info.Kind)
+	}
+	if address == 0 {
+		address = 0x2000
+	}
+	probe := &ExchangeProbe{
+		Request: []byte(fmt.Sprintf("N#X%08X#", address)),
+		// The erase is not repeated
+		RequestInterval: time.Minute,
+		Match: func(response []byte) (string, bool) {
+			return "", bytes.Contains(response, []byte("X\n\r"))
+		},
+	}
+	_, ok, err := RunProbe(port, probe, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("no answer to the erase command")
+	}
+	return nil
+}
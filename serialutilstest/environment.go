@@ -0,0 +1,187 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+// Package serialutilstest provides a scriptable fake port environment to
+// unit-test the reset flows built on serialutils without real hardware.
+package serialutilstest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+// Environment is a fake set of serial ports that changes over time following
+// a script. The time is measured with a FakeClock, starting from the creation
+// of the Environment, so the script is replayed deterministically when the
+// clock is passed to serialutils.ResetOptions.
+type Environment struct {
+	mux     sync.Mutex
+	clock   *FakeClock
+	start   time.Time
+	ports   map[string]*serialutils.PortDetails
+	events  []*event
+	touches []string
+}
+
+type event struct {
+	at     time.Duration
+	add    *serialutils.PortDetails
+	remove string
+	err    error
+	until  time.Duration
+}
+
+// NewEnvironment returns an Environment with the given ports already present.
+func NewEnvironment(ports ...string) *Environment {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := &Environment{
+		clock: NewFakeClock(start),
+		start: start,
+		ports: map[string]*serialutils.PortDetails{},
+	}
+	for _, port := range ports {
+		e.ports[port] = &serialutils.PortDetails{Name: port}
+	}
+	return e
+}
+
+// Clock returns the clock of the Environment.
+func (e *Environment) Clock() *FakeClock {
+	return e.clock
+}
+
+// AddPortAt makes the port appear at the given offset from the start.
+func (e *Environment) AddPortAt(offset time.Duration, port string) *Environment {
+	return e.AddPortDetailsAt(offset, &serialutils.PortDetails{Name: port})
+}
+
+// AddPortDetailsAt makes the port, with the given details, appear at the given
+// offset from the start.
+func (e *Environment) AddPortDetailsAt(offset time.Duration, port *serialutils.PortDetails) *Environment {
+	return e.addEvent(&event{at: offset, add: port})
+}
+
+// RemovePortAt makes the port disappear at the given offset from the start.
+func (e *Environment) RemovePortAt(offset time.Duration, port string) *Environment {
+	return e.addEvent(&event{at: offset, remove: port})
+}
+
+// FailBetween makes the enumeration fail with err from the `from` offset to
+// the `to` offset (excluded).
+func (e *Environment) FailBetween(from, to time.Duration, err error) *Environment {
+	return e.addEvent(&event{at: from, until: to, err: err})
+}
+
+func (e *Environment) addEvent(ev *event) *Environment {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.events = append(e.events, ev)
+	sort.SliceStable(e.events, func(i, j int) bool { return e.events[i].at < e.events[j].at })
+	return e
+}
+
+// update applies the events that happened until now and returns the error
+// that the enumeration should return, if any.
+func (e *Environment) update() error {
+	elapsed := e.clock.Now().Sub(e.start)
+	var err error
+	pending := []*event{}
+	for _, ev := range e.events {
+		if ev.at > elapsed {
+			pending = append(pending, ev)
+			continue
+		}
+		switch {
+		case ev.err != nil:
+			if elapsed < ev.until {
+				err = ev.err
+				pending = append(pending, ev)
+			}
+		case ev.add != nil:
+			e.ports[ev.add.Name] = ev.add
+		default:
+			delete(e.ports, ev.remove)
+		}
+	}
+	e.events = pending
+	return err
+}
+
+// PortsMapper returns a serialutils.PortsMapper listing the ports of the
+// Environment.
+func (e *Environment) PortsMapper() serialutils.PortsMapper {
+	return func() (map[string]bool, error) {
+		e.mux.Lock()
+		defer e.mux.Unlock()
+		if err := e.update(); err != nil {
+			return nil, err
+		}
+		res := map[string]bool{}
+		for name := range e.ports {
+			res[name] = true
+		}
+		return res, nil
+	}
+}
+
+// DetailedPortsMapper returns a serialutils.DetailedPortsMapper listing the
+// ports of the Environment.
+func (e *Environment) DetailedPortsMapper() serialutils.DetailedPortsMapper {
+	return func() (map[string]*serialutils.PortDetails, error) {
+		e.mux.Lock()
+		defer e.mux.Unlock()
+		if err := e.update(); err != nil {
+			return nil, err
+		}
+		res := map[string]*serialutils.PortDetails{}
+		for name, port := range e.ports {
+			details := *port
+			res[name] = &details
+		}
+		return res, nil
+	}
+}
+
+// Resetter returns a serialutils.Resetter that records the touched ports
+// without doing anything else.
+func (e *Environment) Resetter() serialutils.Resetter {
+	return serialutils.ResetterFunc(func(port string) error {
+		e.mux.Lock()
+		defer e.mux.Unlock()
+		e.touches = append(e.touches, port)
+		return nil
+	})
+}
+
+// Touches returns the ports touched through the Resetter.
+func (e *Environment) Touches() []string {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return append([]string{}, e.touches...)
+}
+
+// ResetOptions returns serialutils.ResetOptions wired to the Environment
+// (ports mappers, resetter and clock) to be completed by the caller.
+func (e *Environment) ResetOptions() *serialutils.ResetOptions {
+	return &serialutils.ResetOptions{
+		PortsMapper:         e.PortsMapper(),
+		DetailedPortsMapper: e.DetailedPortsMapper(),
+		Resetter:            e.Resetter(),
+		Clock:               e.clock,
+	}
+}
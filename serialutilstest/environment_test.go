@@ -0,0 +1,94 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutilstest_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"github.com/arduino/go-serial-utils/serialutilstest"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := serialutilstest.NewFakeClock(start)
+	clock.Sleep(time.Second)
+	clock.Advance(500 * time.Millisecond)
+	if elapsed := clock.Now().Sub(start); elapsed != 1500*time.Millisecond {
+		t.Fatalf("clock advanced by %v, want 1.5s", elapsed)
+	}
+}
+
+func TestEnvironmentScript(t *testing.T) {
+	errEnum := errors.New("enumeration failed")
+	env := serialutilstest.NewEnvironment("/dev/ttyACM0").
+		RemovePortAt(time.Second, "/dev/ttyACM0").
+		AddPortDetailsAt(2*time.Second, &serialutils.PortDetails{Name: "/dev/ttyACM1", IsUSB: true, VID: "2341", PID: "0036"}).
+		FailBetween(3*time.Second, 4*time.Second, errEnum)
+	mapper := env.PortsMapper()
+	detailedMapper := env.DetailedPortsMapper()
+
+	for _, step := range []struct {
+		advance time.Duration
+		ports   map[string]bool
+		err     error
+	}{
+		{0, map[string]bool{"/dev/ttyACM0": true}, nil},
+		{time.Second, map[string]bool{}, nil},
+		{time.Second, map[string]bool{"/dev/ttyACM1": true}, nil},
+		{time.Second, nil, errEnum},
+		{500 * time.Millisecond, nil, errEnum},
+		{500 * time.Millisecond, map[string]bool{"/dev/ttyACM1": true}, nil},
+	} {
+		env.Clock().Advance(step.advance)
+		ports, err := mapper()
+		if !errors.Is(err, step.err) || (step.err == nil && !reflect.DeepEqual(ports, step.ports)) {
+			t.Fatalf("after %v: got %v, %v, want %v, %v", step.advance, ports, err, step.ports, step.err)
+		}
+	}
+	details, err := detailedMapper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := details["/dev/ttyACM1"]; p == nil || p.VID != "2341" || p.PID != "0036" {
+		t.Fatalf("wrong details: %v", details)
+	}
+}
+
+func TestEnvironmentReset(t *testing.T) {
+	env := serialutilstest.NewEnvironment("/dev/ttyACM0", "/dev/ttyUSB0").
+		RemovePortAt(500*time.Millisecond, "/dev/ttyACM0").
+		AddPortAt(2*time.Second, "/dev/ttyACM1")
+	opts := env.ResetOptions()
+	opts.Wait = true
+	res, err := serialutils.ResetWithOptions("/dev/ttyACM0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Target.Path != "/dev/ttyACM1" {
+		t.Fatalf("found %q, want /dev/ttyACM1", res.Target.Path)
+	}
+	if touches := env.Touches(); !reflect.DeepEqual(touches, []string{"/dev/ttyACM0"}) {
+		t.Fatalf("touched %v, want [/dev/ttyACM0]", touches)
+	}
+	// The wait runs on the fake clock
+	if elapsed := env.Clock().Now().Sub(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); elapsed < 2*time.Second || elapsed > 5*time.Second {
+		t.Fatalf("reset took %v on the fake clock", elapsed)
+	}
+}
@@ -0,0 +1,120 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutilstest
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+// FlakyOptions are the faults injected by a FlakyMapper. The rates are
+// probabilities between 0 and 1.
+type FlakyOptions struct {
+	// ErrorRate is the probability of an enumeration failing.
+	ErrorRate float64
+	// Err is the error returned by the failing enumerations, if nil a
+	// generic error is used.
+	Err error
+	// MaxDelay is the maximum delay added to every enumeration, the actual
+	// delay is random.
+	MaxDelay time.Duration
+	// DropRate is the probability of every port disappearing from a single
+	// enumeration.
+	DropRate float64
+	// DuplicateRate is the probability of every port being reported also
+	// under an alias differing only by case, as sometimes happens on
+	// Windows and macOS.
+	DuplicateRate float64
+	// Seed is the seed of the random faults, so that a failing run can be
+	// reproduced.
+	Seed int64
+	// Clock is used to apply the delays, if nil the SystemClock is used.
+	Clock serialutils.Clock
+}
+
+// FlakyMapper returns a PortsMapper that wraps base injecting intermittent
+// errors, delays and duplicated or disappearing entries, to test the wait
+// loop against the real-world enumeration flakiness.
+func FlakyMapper(base serialutils.PortsMapper, opts *FlakyOptions) serialutils.PortsMapper {
+	if opts == nil {
+		opts = &FlakyOptions{}
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = serialutils.SystemClock
+	}
+	flakyErr := opts.Err
+	if flakyErr == nil {
+		flakyErr = errors.New("flaky enumeration failure")
+	}
+	var mux sync.Mutex
+	rnd := rand.New(rand.NewSource(opts.Seed))
+	chance := func(rate float64) bool {
+		mux.Lock()
+		defer mux.Unlock()
+		return rnd.Float64() < rate
+	}
+
+	return func() (map[string]bool, error) {
+		if opts.MaxDelay > 0 {
+			mux.Lock()
+			delay := time.Duration(rnd.Int63n(int64(opts.MaxDelay)))
+			mux.Unlock()
+			clock.Sleep(delay)
+		}
+		if chance(opts.ErrorRate) {
+			return nil, flakyErr
+		}
+		ports, err := base()
+		if err != nil {
+			return nil, err
+		}
+		// The ports are visited in order, so that the faults are
+		// reproducible from the seed
+		names := make([]string, 0, len(ports))
+		for port := range ports {
+			names = append(names, port)
+		}
+		sort.Strings(names)
+		res := map[string]bool{}
+		for _, port := range names {
+			if chance(opts.DropRate) {
+				continue
+			}
+			res[port] = true
+			if chance(opts.DuplicateRate) {
+				res[caseAlias(port)] = true
+			}
+		}
+		return res, nil
+	}
+}
+
+// caseAlias returns the port name with the case of its letters swapped.
+func caseAlias(port string) string {
+	return strings.Map(func(r rune) rune {
+		if lower := strings.ToLower(string(r)); lower != string(r) {
+			return []rune(lower)[0]
+		}
+		return []rune(strings.ToUpper(string(r)))[0]
+	}, port)
+}
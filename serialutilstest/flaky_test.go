@@ -0,0 +1,69 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutilstest_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/arduino/go-serial-utils/serialutilstest"
+)
+
+func TestFlakyMapper(t *testing.T) {
+	base := serialutilstest.NewEnvironment("/dev/ttyACM0", "COM3").PortsMapper()
+
+	errFlaky := errors.New("flaky")
+	if _, err := serialutilstest.FlakyMapper(base, &serialutilstest.FlakyOptions{ErrorRate: 1, Err: errFlaky})(); !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	if ports, err := serialutilstest.FlakyMapper(base, &serialutilstest.FlakyOptions{DropRate: 1})(); err != nil || len(ports) != 0 {
+		t.Fatalf("expected all the ports dropped, got %v, %v", ports, err)
+	}
+	ports, err := serialutilstest.FlakyMapper(base, &serialutilstest.FlakyOptions{DuplicateRate: 1})()
+	expected := map[string]bool{"/dev/ttyACM0": true, "/DEV/TTYacm0": true, "COM3": true, "com3": true}
+	if err != nil || !reflect.DeepEqual(ports, expected) {
+		t.Fatalf("got %v, %v, want %v", ports, err, expected)
+	}
+	if ports, err := serialutilstest.FlakyMapper(base, nil)(); err != nil || len(ports) != 2 {
+		t.Fatalf("expected the base ports, got %v, %v", ports, err)
+	}
+}
+
+func TestFlakyMapperSeed(t *testing.T) {
+	base := serialutilstest.NewEnvironment("/dev/ttyACM0", "/dev/ttyACM1").PortsMapper()
+	opts := &serialutilstest.FlakyOptions{ErrorRate: 0.3, DropRate: 0.3, Seed: 42}
+	run := func() []string {
+		mapper := serialutilstest.FlakyMapper(base, opts)
+		var res []string
+		for i := 0; i < 20; i++ {
+			ports, err := mapper()
+			res = append(res, fmtPorts(ports, err))
+		}
+		return res
+	}
+	if first, second := run(), run(); !reflect.DeepEqual(first, second) {
+		t.Fatalf("same seed, different faults:\n%v\n%v", first, second)
+	}
+}
+
+func fmtPorts(ports map[string]bool, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return fmt.Sprint(ports)
+}
@@ -0,0 +1,167 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+//go:build linux || darwin
+
+package serialutilstest
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+// PTYBoard is a fake board connected to a pseudo-terminal. The terminal is
+// exposed as a symlink in a directory, so it appears as a real port that can
+// be opened and touched, allowing end-to-end tests of the touch and of the
+// wait for the bootloader port. Use PTYPortsMapper to list the ports.
+type PTYBoard struct {
+	dir    string
+	mux    sync.Mutex
+	master *os.File
+	port   string
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewPTYBoard creates a fake board exposed as the port `name` in `dir`.
+func NewPTYBoard(dir, name string) (*PTYBoard, error) {
+	b := &PTYBoard{dir: dir}
+	if err := b.Connect(name); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Port returns the path of the port of the board, or the empty string if the
+// board is disconnected.
+func (b *PTYBoard) Port() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.port
+}
+
+// Connect opens a new pseudo-terminal exposed as the port `name`, emulating
+// the board enumeration. If the board is already connected it is disconnected
+// first.
+func (b *PTYBoard) Connect(name string) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.disconnect()
+	master, slave, err := openPTY()
+	if err != nil {
+		return err
+	}
+	port := filepath.Join(b.dir, name)
+	if err := os.Symlink(slave, port); err != nil {
+		_ = master.Close()
+		return err
+	}
+	b.master = master
+	b.port = port
+	return nil
+}
+
+// Disconnect closes the pseudo-terminal and removes the port, emulating a
+// board disappearing from the USB bus.
+func (b *PTYBoard) Disconnect() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.disconnect()
+}
+
+func (b *PTYBoard) disconnect() {
+	if b.master == nil {
+		return
+	}
+	_ = os.Remove(b.port)
+	_ = b.master.Close()
+	b.master = nil
+	b.port = ""
+}
+
+// EmulateBootloader makes the board react to the 1200-bps touch: as soon as
+// the port is configured at 1200 bps the board disconnects and, after
+// `delay`, it reconnects as the port `bootloaderName`.
+func (b *PTYBoard) EmulateBootloader(bootloaderName string, delay time.Duration) {
+	b.mux.Lock()
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	stop, done := b.stop, b.done
+	b.mux.Unlock()
+
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			b.mux.Lock()
+			touched := false
+			if b.master != nil {
+				touched, _ = ptyIs1200bps(b.master)
+			}
+			if touched {
+				b.disconnect()
+			}
+			b.mux.Unlock()
+			if touched {
+				select {
+				case <-stop:
+					return
+				case <-time.After(delay):
+				}
+				_ = b.Connect(bootloaderName)
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the bootloader emulation, if any, and disconnects the board.
+func (b *PTYBoard) Close() {
+	b.mux.Lock()
+	stop, done := b.stop, b.done
+	b.stop = nil
+	b.mux.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	b.Disconnect()
+}
+
+// PTYPortsMapper returns a serialutils.PortsMapper listing the ports of the
+// PTYBoards created in `dir`.
+func PTYPortsMapper(dir string) serialutils.PortsMapper {
+	return func() (map[string]bool, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		res := map[string]bool{}
+		for _, entry := range entries {
+			if entry.Type()&os.ModeSymlink != 0 {
+				res[filepath.Join(dir, entry.Name())] = true
+			}
+		}
+		return res, nil
+	}
+}
@@ -0,0 +1,58 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutilstest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func openPTY() (*os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	fd := master.Fd()
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, unix.TIOCPTYGRANT, 0); errno != 0 {
+		_ = master.Close()
+		return nil, "", fmt.Errorf("granting pty: %w", errno)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, unix.TIOCPTYUNLK, 0); errno != 0 {
+		_ = master.Close()
+		return nil, "", fmt.Errorf("unlocking pty: %w", errno)
+	}
+	name := make([]byte, 128)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, unix.TIOCPTYGNAME, uintptr(unsafe.Pointer(&name[0]))); errno != 0 {
+		_ = master.Close()
+		return nil, "", fmt.Errorf("getting pty name: %w", errno)
+	}
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return master, string(name), nil
+}
+
+func ptyIs1200bps(master *os.File) (bool, error) {
+	termios, err := unix.IoctlGetTermios(int(master.Fd()), unix.TIOCGETA)
+	if err != nil {
+		return false, err
+	}
+	return termios.Ispeed == 1200, nil
+}
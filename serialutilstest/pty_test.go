@@ -0,0 +1,53 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+//go:build linux || darwin
+
+package serialutilstest_test
+
+import (
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"github.com/arduino/go-serial-utils/serialutilstest"
+)
+
+func TestPTYBoardReset(t *testing.T) {
+	dir := t.TempDir()
+	board, err := serialutilstest.NewPTYBoard(dir, "ttyACM0")
+	if err != nil {
+		t.Skipf("pseudo-terminals not available: %v", err)
+	}
+	defer board.Close()
+	board.EmulateBootloader("ttyACM1", 100*time.Millisecond)
+
+	port := board.Port()
+	mapper := serialutilstest.PTYPortsMapper(dir)
+	if ports, err := mapper(); err != nil || !ports[port] {
+		t.Fatalf("board port not listed: %v, %v", ports, err)
+	}
+	res, err := serialutils.ResetWithOptions(port, &serialutils.ResetOptions{
+		Wait:        true,
+		PortsMapper: mapper,
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Target.Path != board.Port() || res.Target.Path == port {
+		t.Fatalf("found %q, want the bootloader port %q", res.Target.Path, board.Port())
+	}
+}
@@ -0,0 +1,838 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ResetSession is a reset split in its phases: BeginReset captures the
+// baseline port list, Touch resets the board and WaitForBootloader waits for
+// the bootloader. This allows the callers to interleave their own steps
+// between the phases, for example closing a serial monitor after the
+// baseline is taken.
+type ResetSession struct {
+	ctx                 context.Context
+	port                string
+	opts                *ResetOptions
+	dryRun              bool
+	sim                 *simulation
+	clock               Clock
+	portsMapper         PortsMapper
+	detailedPortsMapper DetailedPortsMapper
+	last                map[string]bool
+	ranking             *candidateRanking
+	excluded            map[string]bool
+	// targets are the non-serial bootloader targets watched by the wait.
+	targets []*targetWatch
+	// lease is the port released by its Monitor for the touch.
+	lease *MonitorLease
+	// shared tells if the session uses the OS ports, shared with the other
+	// resets in progress (see activeResets).
+	shared bool
+	// owner identifies the claims of the session in activeResets.
+	owner *claimOwner
+	// closed is closed by Close, interrupting the wait in progress.
+	closed    chan struct{}
+	closeOnce sync.Once
+	// waiting is held by the wait in progress, Close waits for it.
+	waiting sync.Mutex
+}
+
+// ErrSessionClosed is returned by the ResetSession methods called after
+// Close.
+var ErrSessionClosed = errors.New("reset session closed")
+
+// BeginReset starts a reset session for the port, capturing the baseline
+// port list.
+func BeginReset(port string) (*ResetSession, error) {
+	return BeginResetWithOptions(port, nil)
+}
+
+// BeginResetWithOptions is like BeginReset but uses the given ResetOptions.
+// The Wait option is ignored: the caller decides whether to call
+// WaitForBootloader.
+func BeginResetWithOptions(port string, opts *ResetOptions) (*ResetSession, error) {
+	return beginReset(context.Background(), port, opts, true)
+}
+
+// beginReset starts a reset session, the baseline needed by the wait is
+// taken only if wait is true. The context is the parent of the tracing spans
+// of the touch.
+func beginReset(ctx context.Context, portToTouch string, opts *ResetOptions, wait bool) (*ResetSession, error) {
+	if opts == nil {
+		opts = &ResetOptions{}
+	}
+	// The PortsMapper of the Config is shared by all the resets of the
+	// process, like the OS enumeration
+	ownMapper := opts.PortsMapper != nil
+	opts = withConfig(opts, portToTouch)
+	portToTouch = NormalizePortName(portToTouch)
+	dryRun := opts.DryRun
+	cb := opts.Callbacks
+	clock := opts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	portsMapper := opts.PortsMapper
+	if portsMapper == nil && IsRFC2217Port(portToTouch) {
+		portsMapper = RFC2217PortsMapper(strings.TrimPrefix(portToTouch, RFC2217Prefix))
+	}
+	if portsMapper == nil {
+		portsMapper = DefaultPortMapper // non dry-run default
+	}
+	var sim *simulation
+	if opts.Simulator != nil {
+		sim = opts.Simulator.start(portToTouch, opts.Clock)
+		clock = sim.clock
+		portsMapper = sim.portsMapper
+		dryRun = false
+	} else if dryRun {
+		emulatedPort := portToTouch
+		portsMapper = func() (map[string]bool, error) {
+			res := map[string]bool{}
+			if emulatedPort != "" {
+				res[emulatedPort] = true
+			}
+			if strings.HasSuffix(emulatedPort, "999") {
+				emulatedPort += "0"
+			} else if emulatedPort == "" {
+				emulatedPort = "newport"
+			}
+			return res, nil
+		}
+	}
+
+	if opts.Trace != nil {
+		opts = opts.Trace.begin(portToTouch, opts, clock)
+		cb = opts.Callbacks
+	}
+
+	portsMapper = canonicalPortsMapper(portsMapper, portToTouch)
+	var recent map[string]bool
+	if opts.PortHistory != nil {
+		if opts.GracePeriod > 0 {
+			recent = opts.PortHistory.SeenWithin(opts.GracePeriod)
+		}
+		portsMapper = opts.PortHistory.PortsMapper(portsMapper)
+	}
+
+	if opts.Trace != nil {
+		portsMapper = opts.Trace.portsMapper(portsMapper)
+	}
+
+	last, err := portsMapper()
+	if cb != nil && cb.Debug != nil {
+		cb.Debug(fmt.Sprintf("LAST: %v", last))
+	}
+	if err != nil && (wait || !opts.IgnoreInitialEnumerationError) {
+		return nil, err
+	}
+	if err != nil {
+		// Degrade gracefully: touch the port anyway, assuming it's present
+		if cb != nil && cb.Debug != nil {
+			cb.Debug(fmt.Sprintf("Could not enumerate the ports, assuming %s is present: %v", portToTouch, err))
+		}
+		last = map[string]bool{}
+		if portToTouch != "" {
+			last[portToTouch] = true
+		}
+	}
+	portsMapper = ignoreWSLError(portsMapper)
+
+	// The ports seen during the grace period but missing now are excluded
+	// from the new ports detection
+	excluded := map[string]bool{}
+	for port := range recent {
+		if !last[port] {
+			excluded[port] = true
+		}
+	}
+	if len(excluded) > 0 && cb != nil && cb.Debug != nil {
+		cb.Debug(fmt.Sprintf("EXCLUDED: %v", excluded))
+	}
+
+	detailedPortsMapper := opts.DetailedPortsMapper
+	switch {
+	case sim != nil:
+		detailedPortsMapper = sim.detailedPortsMapper
+	case dryRun:
+		// The emulated ports have no details
+		detailedPortsMapper = func() (map[string]*PortDetails, error) {
+			return map[string]*PortDetails{}, nil
+		}
+	case detailedPortsMapper == nil:
+		detailedPortsMapper = DefaultDetailedPortMapper
+	}
+
+	if portToTouch != "" && !dryRun && sim == nil && reservations.active() {
+		if id := reservedPortID(portToTouch, detailedPortsMapper); reservations.reservedByOthers(opts.Reservation, id) {
+			return nil, fmt.Errorf("%w: %s", ErrPortReserved, id)
+		}
+	}
+
+	// Lookup the touched board, to recall its bootloader port and to rank
+	// the candidate bootloader ports
+	ranking := &candidateRanking{bootloaderIDs: opts.BootloaderIDs, iface: opts.PreferredInterface}
+	if len(ranking.bootloaderIDs) == 0 {
+		ranking.bootloaderIDs = defaultBootloaderIDs()
+	}
+	if wait && portToTouch != "" && !dryRun && sim == nil {
+		if details, err := detailedPortsMapper(); err != nil {
+			if cb != nil && cb.Debug != nil {
+				cb.Debug(fmt.Sprintf("Could not get port details: %v", err))
+			}
+		} else if d := details[portToTouch]; d != nil {
+			ranking.serialNumber = d.SerialNumber
+		}
+		ranking.location = usbLocation(portToTouch)
+		ranking.hubPath = usbHubPath(ranking.location)
+	}
+	serialNumber := ranking.serialNumber
+	preferredPort := ""
+	if opts.PortStore != nil && serialNumber != "" {
+		preferredPort, err = opts.PortStore.BootloaderPort(serialNumber)
+		if err != nil && cb != nil && cb.Debug != nil {
+			cb.Debug(fmt.Sprintf("Could not read port store: %v", err))
+		}
+		if preferredPort != "" && cb != nil && cb.Debug != nil {
+			cb.Debug(fmt.Sprintf("STORE: %s was last seen as %s", serialNumber, preferredPort))
+		}
+		ranking.preferredPort = preferredPort
+	}
+
+	var targets []*targetWatch
+	if wait {
+		discoverers := opts.TargetDiscoverers
+		if opts.WaitForMassStorage {
+			volumesMapper := opts.VolumesMapper
+			if volumesMapper == nil && (dryRun || sim != nil) {
+				volumesMapper = func() (map[string]bool, error) { return map[string]bool{}, nil }
+			}
+			discoverers = append([]TargetDiscoverer{VolumeDiscoverer(volumesMapper, opts.RequireUF2)}, discoverers...)
+		}
+		var debug func(string)
+		if cb != nil {
+			debug = cb.Debug
+		}
+		if targets, err = watchTargets(discoverers, debug); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &ResetSession{
+		ctx:                 ctx,
+		port:                portToTouch,
+		opts:                opts,
+		dryRun:              dryRun,
+		sim:                 sim,
+		clock:               clock,
+		portsMapper:         portsMapper,
+		detailedPortsMapper: detailedPortsMapper,
+		last:                last,
+		targets:             targets,
+		ranking:             ranking,
+		excluded:            excluded,
+		shared:              !ownMapper && sim == nil && !dryRun,
+		owner:               &claimOwner{},
+		closed:              make(chan struct{}),
+	}
+	// Safety net for the sessions abandoned without calling Close
+	runtime.SetFinalizer(s, (*ResetSession).release)
+	return s, nil
+}
+
+// Port returns the (normalized) name of the port reset by the session.
+func (s *ResetSession) Port() string {
+	return s.port
+}
+
+// Touch resets the board, using the Resetter (or the 1200-bps touch) and the
+// hooks of the ResetOptions. The reset is skipped if the port was not
+// present when the session began.
+func (s *ResetSession) Touch() error {
+	if s.isClosed() {
+		return ErrSessionClosed
+	}
+	resetErr, err := s.touch()
+	if err != nil {
+		return err
+	}
+	return resetErr
+}
+
+// touch performs the reset. The errors of the reset itself, that may be
+// ignored when waiting for the bootloader (the board may disappear during
+// the reset), are returned separately from the errors of the hooks, that
+// always abort the reset.
+func (s *ResetSession) touch() (resetErr error, err error) {
+	_, span := tracerOf(s.opts).Start(s.ctx, "serialutils.Touch")
+	span.SetAttribute("port", s.port)
+	defer func() {
+		if err != nil {
+			span.End(err)
+		} else {
+			span.End(resetErr)
+		}
+		if resetErr != nil {
+			metricsOf(s.opts).IncCounter(MetricTouchFailures)
+		}
+		if err != nil {
+			s.lease.Done("")
+			s.lease = nil
+			s.releasePort()
+		}
+	}()
+
+	portToTouch, opts, cb := s.port, s.opts, s.opts.Callbacks
+	if portToTouch == "" || !s.last[portToTouch] {
+		return nil, nil
+	}
+	if opts.SkipReset {
+		if cb != nil && cb.Debug != nil {
+			cb.Debug(fmt.Sprintf("SKIP: %v doesn't need a reset", portToTouch))
+		}
+		// The board is programmed on the same port, its Monitor must release
+		// it anyway
+		if opts.Monitors != nil {
+			lease, err := opts.Monitors.Acquire(portToTouch)
+			if err != nil {
+				return nil, err
+			}
+			s.lease = lease
+			if lease != nil && lease.Port != nil {
+				_ = lease.Port.Close()
+				lease.Port = nil
+			}
+		}
+		return nil, nil
+	}
+	if cb != nil && cb.Debug != nil {
+		cb.Debug(fmt.Sprintf("TOUCH: %v", portToTouch))
+	}
+	if cb != nil && cb.TouchingPortDetails != nil {
+		details := s.lookupPortDetails(portToTouch)
+		if details == nil {
+			details = &PortDetails{Name: portToTouch}
+		}
+		cb.TouchingPortDetails(details)
+	}
+	if cb != nil && cb.TouchingPort != nil {
+		cb.TouchingPort(portToTouch)
+	}
+	var handedOffPort serial.Port
+	if opts.Monitors != nil {
+		lease, err := opts.Monitors.Acquire(portToTouch)
+		if err != nil {
+			return nil, err
+		}
+		s.lease = lease
+		if lease != nil && lease.Port != nil {
+			handedOffPort, lease.Port = lease.Port, nil
+			// The open port can be used only by the 1200-bps touch
+			if s.dryRun || s.sim != nil || opts.Resetter != nil {
+				_ = handedOffPort.Close()
+				handedOffPort = nil
+			}
+		}
+	}
+	if s.dryRun {
+		// do nothing!
+	} else if s.sim != nil {
+		if err := s.sim.touch(); err != nil {
+			resetErr = fmt.Errorf("resetting board: %w", err)
+		}
+	} else {
+		if s.shared {
+			// The touched port may disappear and come back, the other
+			// resets must not take it for their bootloader port
+			activeResets.claim(s.owner, portToTouch, s.waitTimeout()+claimDuration)
+		}
+		if opts.PreResetHook != nil {
+			if err := opts.PreResetHook(portToTouch); err != nil {
+				if handedOffPort != nil {
+					_ = handedOffPort.Close()
+				}
+				return nil, fmt.Errorf("running pre-reset hook: %w", err)
+			}
+		}
+		if opts.Resetter != nil {
+			if err := opts.Resetter.Reset(portToTouch); err != nil {
+				resetErr = fmt.Errorf("resetting board: %w", err)
+			}
+		} else {
+			touchOpts := TouchOptions{}
+			if opts.TouchOptions != nil {
+				touchOpts = *opts.TouchOptions
+			}
+			if touchOpts.Clock == nil {
+				touchOpts.Clock = s.clock
+			}
+			if touchOpts.DetailedPortsMapper == nil {
+				touchOpts.DetailedPortsMapper = opts.DetailedPortsMapper
+			}
+			if handedOffPort != nil {
+				if err := touchNamedOpenPort(handedOffPort, portToTouch, &touchOpts); err != nil {
+					resetErr = fmt.Errorf("1200-bps touch: %w", err)
+				}
+			} else if err := Touch1200bpsContext(s.ctx, portToTouch, &touchOpts); err != nil {
+				resetErr = fmt.Errorf("1200-bps touch: %w", err)
+			}
+		}
+		if opts.PostResetHook != nil {
+			if err := opts.PostResetHook(portToTouch); err != nil {
+				return nil, fmt.Errorf("running post-reset hook: %w", err)
+			}
+		}
+	}
+	return resetErr, nil
+}
+
+// WaitForBootloader waits for the bootloader port (or volume) to appear,
+// comparing the ports available with the ones present when the session
+// began. The wait is interrupted if the context is canceled or the session
+// is closed.
+func (s *ResetSession) WaitForBootloader(ctx context.Context) (res *ResetResult, err error) {
+	if s.isClosed() {
+		return nil, ErrSessionClosed
+	}
+	s.waiting.Lock()
+	defer s.waiting.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := ctx.Done()
+	go func() {
+		select {
+		case <-s.closed:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	ctx, span := tracerOf(s.opts).Start(ctx, "serialutils.WaitForBootloader")
+	defer func() { span.End(err) }()
+	start := s.clock.Now()
+	res, err = s.waitForBootloader(ctx)
+	if err != nil && s.isClosed() {
+		err = ErrSessionClosed
+	}
+	s.releasePort()
+	if err == nil && (res.Target.Kind != NoTarget || s.opts.holdMonitorLease) {
+		res.MonitorLease = s.lease
+	} else {
+		s.lease.Done("")
+	}
+	s.lease = nil
+	s.opts.Trace.finish(res, err)
+	if err == nil {
+		metrics := metricsOf(s.opts)
+		metrics.ObserveDuration(MetricWaitDuration, s.clock.Now().Sub(start))
+		if res.Target.Kind == NoTarget && !res.Skipped {
+			metrics.IncCounter(MetricWaitTimeouts)
+		}
+	}
+	return res, err
+}
+
+func (s *ResetSession) waitForBootloader(ctx context.Context) (*ResetResult, error) {
+	tracer := tracerOf(s.opts)
+	portToTouch, opts, cb := s.port, s.opts, s.opts.Callbacks
+	if opts.SkipReset {
+		// No bootloader to wait for
+		res := newResetResult(NoTarget, "")
+		res.Skipped = true
+		return res, nil
+	}
+	dryRun, sim, clock := s.dryRun, s.sim, s.clock
+	portsMapper, detailedPortsMapper := s.portsMapper, s.detailedPortsMapper
+	last := s.last
+	ranking := s.ranking
+	serialNumber, preferredPort := ranking.serialNumber, ranking.preferredPort
+
+	if cb != nil && cb.WaitingForNewSerial != nil {
+		cb.WaitingForNewSerial()
+	}
+
+	timeout := s.waitTimeout()
+	start := clock.Now()
+	deadline := start.Add(timeout)
+	if dryRun {
+		// use a much lower timeout in dryRun
+		deadline = start.Add(100 * time.Millisecond)
+	}
+	backoff := opts.PollBackoff
+	if backoff == nil {
+		backoff = &DefaultPollBackoff
+	}
+	pollInterval := backoff.initial()
+	stabilization := opts.Stabilization
+	if stabilization == nil {
+		stabilization = &DefaultStabilization
+	}
+	var debug func(string)
+	if cb != nil {
+		debug = cb.Debug
+	}
+	errorTolerance := opts.ErrorTolerance
+	if errorTolerance == nil {
+		errorTolerance = &DefaultErrorTolerance
+	}
+	portsMapper = errorTolerance.wrap(portsMapper, debug)
+	portFound := func(port string) *ResetResult {
+		if opts.PortStore != nil && serialNumber != "" && port != preferredPort {
+			if err := opts.PortStore.SetBootloaderPort(serialNumber, port); err != nil && cb != nil && cb.Debug != nil {
+				cb.Debug(fmt.Sprintf("Could not update port store: %v", err))
+			}
+		}
+		details := s.lookupPortDetails(port)
+		if cb != nil && cb.BootloaderPortFoundDetails != nil {
+			if details == nil {
+				cb.BootloaderPortFoundDetails(&PortDetails{Name: port})
+			} else {
+				cb.BootloaderPortFoundDetails(details)
+			}
+		}
+		if cb != nil && cb.BootloaderPortFound != nil {
+			cb.BootloaderPortFound(port)
+		}
+		if s.shared && port != portToTouch {
+			activeResets.claim(s.owner, port, claimDuration)
+		}
+		res := newResetResult(SerialPort, port)
+		if details != nil {
+			id := NewPortID(details)
+			res.Target.ID = &id
+			res.Target.Board = GuessBoard(*details)
+		}
+		if opts.VerifyBootloader && sim == nil && !dryRun {
+			res.Bootloader = probeBootloader(port, debug)
+		}
+		return res
+	}
+	accept := func(port string) bool {
+		if opts.Accept != nil {
+			details := &PortDetails{Name: port}
+			if ports, err := detailedPortsMapper(); err != nil {
+				if debug != nil {
+					debug(fmt.Sprintf("Could not get port details: %v", err))
+				}
+			} else if d := ports[port]; d != nil {
+				details = d
+			}
+			if !opts.Accept(details) {
+				if debug != nil {
+					debug(fmt.Sprintf("ACCEPT %s: rejected", port))
+				}
+				return false
+			}
+		}
+		if opts.AcceptProbe == nil || sim != nil || dryRun {
+			return true
+		}
+		description, ok, err := RunProbe(port, opts.AcceptProbe, opts.ProbeTimeout)
+		if debug != nil {
+			if err != nil {
+				debug(fmt.Sprintf("PROBE %s: %v", port, err))
+			} else if ok {
+				debug(fmt.Sprintf("PROBE %s: accepted (%s)", port, description))
+			} else {
+				debug(fmt.Sprintf("PROBE %s: rejected", port))
+			}
+		}
+		return err == nil && ok
+	}
+	touchedPortGone := !opts.RequireTouchedPortGone || portToTouch == "" || !last[portToTouch]
+	for clock.Now().Before(deadline) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if cb != nil && cb.WaitProgress != nil {
+			t := clock.Now()
+			cb.WaitProgress(t.Sub(start), deadline.Sub(t))
+		}
+		_, pollSpan := tracer.Start(ctx, "serialutils.Poll")
+		pollStart := time.Now()
+		now, err := portsMapper()
+		pollDuration := time.Since(pollStart)
+		pollSpan.SetAttribute("ports", len(now))
+		pollSpan.End(err)
+		metricsOf(opts).ObserveDuration(MetricPollDuration, pollDuration)
+		if pollDuration > pollBudget && cb != nil && cb.Debug != nil {
+			cb.Debug(fmt.Sprintf("SLOW: listing the ports took %v, over the budget of %v", pollDuration, pollBudget))
+		}
+		if errors.Is(err, errTransientEnumeration) {
+			sleepContext(ctx, clock, pollInterval)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if cb != nil && cb.Debug != nil {
+			cb.Debug(fmt.Sprintf("WAIT: %v", now))
+		}
+		if len(s.targets) > 0 {
+			target, err := findNewTarget(s.targets)
+			if err != nil {
+				return nil, err
+			}
+			if target.Path != "" {
+				if cb != nil && cb.BootloaderPortFoundDetails != nil {
+					cb.BootloaderPortFoundDetails(nil)
+				}
+				if cb != nil && cb.BootloaderPortFound != nil {
+					cb.BootloaderPortFound(target.Path)
+				}
+				return &ResetResult{Target: target}, nil
+			}
+		}
+		newPorts := s.newPorts(last, now)
+		// The ports still glitching are checked again at the next poll
+		glitching := []string{}
+		if !touchedPortGone {
+			if !now[portToTouch] {
+				touchedPortGone = true
+			} else if len(newPorts) > 0 {
+				// The touched port is still there: the new ports belong to
+				// unrelated devices
+				if cb != nil && cb.Debug != nil {
+					cb.Debug(fmt.Sprintf("Ignoring %v, %s not disappeared yet", newPorts, portToTouch))
+				}
+				newPorts = nil
+			}
+		}
+
+		if len(newPorts) > 0 {
+			if cb != nil && cb.Debug != nil {
+				cb.Debug("New ports found!")
+			}
+
+			// If the new port is the only port available there is no doubt
+			if opts.SinglePortFastPath && len(now) == 1 && newPorts[0] != portToTouch && accept(newPorts[0]) {
+				if cb != nil && cb.Debug != nil {
+					cb.Debug(fmt.Sprintf("Single port found: %s", newPorts[0]))
+				}
+				return portFound(newPorts[0]), nil
+			}
+
+			// If the board has been unplugged and plugged back (instead of
+			// being reset by the touch), the port re-added with the same
+			// serial number or on the same USB location is the one
+			if sim == nil && !now[portToTouch] && (ranking.serialNumber != "" || ranking.location != "") {
+				if details, err := detailedPortsMapper(); err != nil {
+					if cb != nil && cb.Debug != nil {
+						cb.Debug(fmt.Sprintf("Could not get port details: %v", err))
+					}
+				} else {
+					candidates := append([]string{}, newPorts...)
+					ranking.sort(candidates, details)
+					for _, p := range candidates {
+						if ranking.sameBoard(p, details[p]) && accept(p) {
+							if cb != nil && cb.Debug != nil {
+								cb.Debug(fmt.Sprintf("HOTPLUG: %s re-added as %s", portToTouch, p))
+							}
+							return portFound(p), nil
+						}
+					}
+				}
+			}
+
+			// If the new port is a known bootloader there is no need to wait
+			if len(ranking.bootloaderIDs) > 0 && sim == nil {
+				if details, err := detailedPortsMapper(); err != nil {
+					if cb != nil && cb.Debug != nil {
+						cb.Debug(fmt.Sprintf("Could not get port details: %v", err))
+					}
+				} else {
+					candidates := []string{}
+					for _, p := range newPorts {
+						if d := details[p]; d != nil && MatchesAny(ranking.bootloaderIDs, d) {
+							candidates = append(candidates, p)
+						}
+					}
+					ranking.sort(candidates, details)
+					for _, p := range candidates {
+						if accept(p) {
+							if cb != nil && cb.Debug != nil {
+								cb.Debug(fmt.Sprintf("Known bootloader %s:%s found on %s", details[p].VID, details[p].PID, p))
+							}
+							return portFound(p), nil
+						}
+					}
+				}
+			}
+
+			// Wait for the port list to settle before picking the new port
+			_, stabilizationSpan := tracer.Start(ctx, "serialutils.Stabilization")
+			check, err := stabilization.wait(portsMapper, clock, debug)
+			stabilizationSpan.End(err)
+			if err != nil {
+				return nil, err
+			}
+			if cb != nil && cb.Debug != nil {
+				for _, p := range sortedKeys(check.glitches) {
+					cb.Debug(fmt.Sprintf("GLITCH: %s disappeared %d times during the stabilization", p, check.glitches[p]))
+				}
+			}
+			newPorts := s.newPorts(last, check.ports)
+			candidates := []string{}
+			for _, p := range newPorts {
+				if !check.persistent[p] {
+					if cb != nil && cb.Debug != nil {
+						cb.Debug(fmt.Sprintf("Ignoring %s, not present in every sample", p))
+					}
+					glitching = append(glitching, p)
+					continue
+				}
+				candidates = append(candidates, p)
+			}
+			if len(candidates) > 1 {
+				var details map[string]*PortDetails
+				if sim == nil {
+					if details, err = detailedPortsMapper(); err != nil && cb != nil && cb.Debug != nil {
+						cb.Debug(fmt.Sprintf("Could not get port details: %v", err))
+					}
+				}
+				ranking.sort(candidates, details)
+				if cb != nil && cb.Debug != nil {
+					cb.Debug(fmt.Sprintf("Candidates: %v", candidates))
+				}
+			}
+			for _, p := range candidates {
+				if accept(p) {
+					return portFound(p), nil // Found it!
+				}
+			}
+			if cb != nil && cb.Debug != nil {
+				cb.Debug("Port check failed... still waiting")
+			}
+		}
+
+		if !samePortList(now, last) {
+			// Something is happening, go back polling quickly
+			pollInterval = backoff.initial()
+		}
+		last = now
+		for _, p := range glitching {
+			delete(last, p)
+		}
+		sleepContext(ctx, clock, pollInterval)
+		pollInterval = backoff.next(pollInterval)
+	}
+
+	if cb != nil && cb.BootloaderPortFoundDetails != nil {
+		cb.BootloaderPortFoundDetails(nil)
+	}
+	if cb != nil && cb.BootloaderPortFound != nil {
+		cb.BootloaderPortFound("")
+	}
+	return newResetResult(NoTarget, ""), nil
+}
+
+// newPorts returns the ports added in now with respect to last, except the
+// ones excluded by the grace period.
+func (s *ResetSession) newPorts(last, now map[string]bool) []string {
+	added, _ := DiffPorts(last, now)
+	res := []string{}
+	for _, port := range added {
+		if s.excluded[port] {
+			continue
+		}
+		if s.shared && port != s.port && activeResets.claimedByOthers(s.owner, port) {
+			if cb := s.opts.Callbacks; cb != nil && cb.Debug != nil {
+				cb.Debug(fmt.Sprintf("EXCLUDED: %s is used by another reset in progress", port))
+			}
+			continue
+		}
+		if s.shared && port != s.port && reservations.active() &&
+			reservations.reservedByOthers(s.opts.Reservation, reservedPortID(port, s.detailedPortsMapper)) {
+			if cb := s.opts.Callbacks; cb != nil && cb.Debug != nil {
+				cb.Debug(fmt.Sprintf("EXCLUDED: %s is reserved by another consumer", port))
+			}
+			continue
+		}
+		res = append(res, port)
+	}
+	return res
+}
+
+// lookupPortDetails returns the details of the port, or nil if they are not
+// available.
+func (s *ResetSession) lookupPortDetails(port string) *PortDetails {
+	if ports, err := s.detailedPortsMapper(); err == nil {
+		return ports[port]
+	}
+	return nil
+}
+
+// waitTimeout returns the maximum time to wait for the bootloader.
+func (s *ResetSession) waitTimeout() time.Duration {
+	if s.opts.Timeout == 0 {
+		return DefaultWaitTimeout
+	}
+	return s.opts.Timeout
+}
+
+// Close releases the resources held by the session: the claim on the touched
+// port and the port released by its Monitor, if not handed over to the
+// ResetResult. A wait in progress is interrupted, and Close returns after it
+// terminates. The sessions must be closed if abandoned before the end of
+// the wait; closing a completed session is harmless.
+func (s *ResetSession) Close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+	s.waiting.Lock()
+	defer s.waiting.Unlock()
+	s.release()
+}
+
+func (s *ResetSession) isClosed() bool {
+	select {
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// release releases the claim on the touched port and the Monitor lease.
+func (s *ResetSession) release() {
+	s.releasePort()
+	s.lease.Done("")
+	s.lease = nil
+}
+
+// releasePort releases the claim on the touched port, once the board is
+// no more expected to disappear and come back.
+func (s *ResetSession) releasePort() {
+	if s.shared {
+		activeResets.release(s.owner, s.port)
+	}
+}
+
+// tracerOf returns the Tracer of the options, or a no-op Tracer if not set.
+func tracerOf(opts *ResetOptions) Tracer {
+	if opts.Tracer == nil {
+		return noopTracer{}
+	}
+	return opts.Tracer
+}
@@ -0,0 +1,76 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// SignalResetter is a Resetter that resets the board by pulsing the DTR
+// (and optionally RTS) line, the same way avrdude does for the boards with
+// the DTR auto-reset circuit, without opening the port at 1200 bps. This is
+// useful for the boards whose sketches misinterpret the 1200-bps open but
+// honor a DTR pulse.
+type SignalResetter struct {
+	// BaudRate is the speed used to open the port, 115200 if zero.
+	BaudRate int
+	// UseRTS pulses the RTS line together with DTR.
+	UseRTS bool
+	// Pulse is the time the lines are kept deasserted, 250 ms if zero.
+	Pulse time.Duration
+}
+
+// Reset implements Resetter.
+func (r *SignalResetter) Reset(port string) error {
+	baudRate := r.BaudRate
+	if baudRate == 0 {
+		baudRate = 115200
+	}
+	pulse := r.Pulse
+	if pulse == 0 {
+		pulse = 250 * time.Millisecond
+	}
+
+	p, err := openPortWithTimeout(port, &serial.Mode{BaudRate: baudRate}, 0)
+	if err != nil {
+		return fmt.Errorf("opening port: %w", err)
+	}
+	defer p.Close()
+
+	setLines := func(value bool) error {
+		if err := p.SetDTR(value); err != nil {
+			return fmt.Errorf("setting DTR: %w", err)
+		}
+		if r.UseRTS {
+			if err := p.SetRTS(value); err != nil {
+				return fmt.Errorf("setting RTS: %w", err)
+			}
+		}
+		return nil
+	}
+	if err := setLines(false); err != nil {
+		return err
+	}
+	time.Sleep(pulse)
+	if err := setLines(true); err != nil {
+		return err
+	}
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
@@ -0,0 +1,256 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SimulatorScenario is the behavior of the board emulated by a Simulator.
+type SimulatorScenario int
+
+const (
+	// ScenarioNewPort emulates a board whose port disappears after the touch
+	// and that re-enumerates as a new bootloader port.
+	ScenarioNewPort SimulatorScenario = iota
+	// ScenarioNoPort emulates a board whose port disappears after the touch
+	// and never comes back.
+	ScenarioNoPort
+	// ScenarioSamePort emulates a board whose port disappears after the touch
+	// and comes back with the same name.
+	ScenarioSamePort
+	// ScenarioEnumerationFailure emulates a port enumeration failing during
+	// the wait for the bootloader port.
+	ScenarioEnumerationFailure
+	// ScenarioScripted emulates the sequence of port lists and enumeration
+	// errors given in the Simulator Steps.
+	ScenarioScripted
+)
+
+// Simulator emulates a board during a reset, without touching any real port.
+// It replaces the dry-run mode of Reset with explicit scenarios. If no Clock
+// is set in the ResetOptions, the Simulator uses a simulated clock, so that
+// the reset completes immediately whatever the timeouts involved.
+type Simulator struct {
+	// Scenario is the behavior emulated.
+	Scenario SimulatorScenario
+	// BootloaderPort is the name of the new port in ScenarioNewPort, if empty
+	// the name of the touched port with a "-bootloader" suffix is used.
+	BootloaderPort string
+	// BootDelay is the time the emulated board takes to re-enumerate after the
+	// touch, 500 ms if zero.
+	BootDelay time.Duration
+	// Err is the error returned in ScenarioEnumerationFailure, if nil a
+	// generic error is used.
+	Err error
+	// TouchErr, if not nil, makes the touch fail with this error.
+	TouchErr error
+	// InitialPorts is the port list before the touch in ScenarioScripted, if
+	// nil only the touched port is present.
+	InitialPorts []string
+	// Steps are the states of the port list after the touch in
+	// ScenarioScripted. Before the first step the port list is empty.
+	Steps []SimulatorStep
+}
+
+// SimulatorStep is a state of the port list in ScenarioScripted.
+type SimulatorStep struct {
+	// At is the time since the touch when the step begins.
+	At time.Duration
+	// Ports is the port list from At until the next step.
+	Ports []string
+	// Err, if not nil, makes the enumeration fail with this error from At
+	// until the next step.
+	Err error
+}
+
+// simulatorScript is the JSON format of a scripted scenario, for example:
+//
+//	{
+//	  "initialPorts": ["/dev/ttyACM0"],
+//	  "steps": [
+//	    { "at": "1s", "error": "enumeration failed" },
+//	    { "at": "1100ms", "ports": [] },
+//	    { "at": "2s", "ports": ["/dev/ttyACM1"] }
+//	  ]
+//	}
+type simulatorScript struct {
+	InitialPorts []string `json:"initialPorts"`
+	TouchError   string   `json:"touchError"`
+	Steps        []struct {
+		At    string   `json:"at"`
+		Ports []string `json:"ports"`
+		Error string   `json:"error"`
+	} `json:"steps"`
+}
+
+// ParseSimulatorScript returns a Simulator running the ScenarioScripted
+// described in JSON format by the script, so that the CLI tests can
+// simulate flaky enumerations, late bootloaders and touch failures.
+func ParseSimulatorScript(data []byte) (*Simulator, error) {
+	var script simulatorScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("decoding simulator script: %w", err)
+	}
+	sim := &Simulator{
+		Scenario:     ScenarioScripted,
+		InitialPorts: script.InitialPorts,
+	}
+	if script.TouchError != "" {
+		sim.TouchErr = errors.New(script.TouchError)
+	}
+	for i, step := range script.Steps {
+		at, err := time.ParseDuration(step.At)
+		if err != nil {
+			return nil, fmt.Errorf("simulator script step %d: invalid time: %w", i, err)
+		}
+		s := SimulatorStep{At: at, Ports: step.Ports}
+		if step.Error != "" {
+			s.Err = errors.New(step.Error)
+		}
+		sim.Steps = append(sim.Steps, s)
+	}
+	return sim, nil
+}
+
+// LoadSimulatorScript reads a scripted scenario from a JSON file, see
+// ParseSimulatorScript.
+func LoadSimulatorScript(path string) (*Simulator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading simulator script: %w", err)
+	}
+	return ParseSimulatorScript(data)
+}
+
+// simulation is a single run of a Simulator.
+type simulation struct {
+	sim       *Simulator
+	clock     Clock
+	port      string
+	mux       sync.Mutex
+	touchedAt time.Time
+}
+
+// start begins a simulated reset of the given port.
+func (s *Simulator) start(port string, clock Clock) *simulation {
+	if clock == nil {
+		clock = &simulatedClock{now: time.Now()}
+	}
+	return &simulation{sim: s, clock: clock, port: port}
+}
+
+func (s *simulation) touch() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.touchedAt = s.clock.Now()
+	return s.sim.TouchErr
+}
+
+func (s *simulation) portsMapper() (map[string]bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	res := map[string]bool{}
+	if s.touchedAt.IsZero() {
+		if s.sim.Scenario == ScenarioScripted && s.sim.InitialPorts != nil {
+			for _, port := range s.sim.InitialPorts {
+				res[port] = true
+			}
+		} else if s.port != "" {
+			res[s.port] = true
+		}
+		return res, nil
+	}
+	if s.sim.Scenario == ScenarioScripted {
+		elapsed := s.clock.Now().Sub(s.touchedAt)
+		var current *SimulatorStep
+		for i, step := range s.sim.Steps {
+			if step.At <= elapsed {
+				current = &s.sim.Steps[i]
+			}
+		}
+		if current == nil {
+			return res, nil
+		}
+		if current.Err != nil {
+			return nil, current.Err
+		}
+		for _, port := range current.Ports {
+			res[port] = true
+		}
+		return res, nil
+	}
+
+	bootDelay := s.sim.BootDelay
+	if bootDelay == 0 {
+		bootDelay = 500 * time.Millisecond
+	}
+	if s.clock.Now().Sub(s.touchedAt) < bootDelay {
+		return res, nil
+	}
+	switch s.sim.Scenario {
+	case ScenarioNewPort:
+		bootloaderPort := s.sim.BootloaderPort
+		if bootloaderPort == "" {
+			bootloaderPort = s.port + "-bootloader"
+		}
+		res[bootloaderPort] = true
+	case ScenarioSamePort:
+		res[s.port] = true
+	case ScenarioEnumerationFailure:
+		if s.sim.Err != nil {
+			return nil, s.sim.Err
+		}
+		return nil, errors.New("simulated enumeration failure")
+	}
+	return res, nil
+}
+
+// detailedPortsMapper reports the emulated ports, with their names only.
+func (s *simulation) detailedPortsMapper() (map[string]*PortDetails, error) {
+	ports, err := s.portsMapper()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*PortDetails, len(ports))
+	for port := range ports {
+		res[port] = &PortDetails{Name: port}
+	}
+	return res, nil
+}
+
+// simulatedClock is a Clock that advances only when sleeping.
+type simulatedClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+func (c *simulatedClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+func (c *simulatedClock) Sleep(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.now = c.now.Add(d)
+}
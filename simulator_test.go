@@ -0,0 +1,121 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils_test
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+)
+
+func TestSimulatorScenarios(t *testing.T) {
+	errEnum := errors.New("enumeration failed")
+	for _, test := range []struct {
+		name   string
+		sim    *serialutils.Simulator
+		kind   serialutils.TargetKind
+		target string
+		err    error
+	}{
+		{"new port", &serialutils.Simulator{Scenario: serialutils.ScenarioNewPort}, serialutils.SerialPort, "/dev/ttyACM0-bootloader", nil},
+		{"named new port", &serialutils.Simulator{Scenario: serialutils.ScenarioNewPort, BootloaderPort: "/dev/ttyACM1"}, serialutils.SerialPort, "/dev/ttyACM1", nil},
+		{"no port", &serialutils.Simulator{Scenario: serialutils.ScenarioNoPort}, serialutils.NoTarget, "", nil},
+		{"same port", &serialutils.Simulator{Scenario: serialutils.ScenarioSamePort}, serialutils.SerialPort, "/dev/ttyACM0", nil},
+		{"enumeration failure", &serialutils.Simulator{Scenario: serialutils.ScenarioEnumerationFailure, Err: errEnum}, serialutils.NoTarget, "", errEnum},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := serialutils.ResetWithOptions("/dev/ttyACM0", &serialutils.ResetOptions{Wait: true, Simulator: test.sim})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+			if res.Target.Kind != test.kind || res.Target.Path != test.target {
+				t.Fatalf("got target %v %q, want %v %q", res.Target.Kind, res.Target.Path, test.kind, test.target)
+			}
+		})
+	}
+}
+
+func TestSimulatorTouchError(t *testing.T) {
+	errTouch := errors.New("touch failed")
+	sim := &serialutils.Simulator{Scenario: serialutils.ScenarioNewPort, TouchErr: errTouch}
+	if _, err := serialutils.ResetWithOptions("/dev/ttyACM0", &serialutils.ResetOptions{Simulator: sim}); !errors.Is(err, errTouch) {
+		t.Fatalf("got error %v, want %v", err, errTouch)
+	}
+	// The touch errors are ignored while waiting for the bootloader, the
+	// board may disappear during the touch
+	res, err := serialutils.ResetWithOptions("/dev/ttyACM0", &serialutils.ResetOptions{Wait: true, Simulator: sim})
+	if err != nil || res.Target.Path != "/dev/ttyACM0-bootloader" {
+		t.Fatalf("got %v, %v", res, err)
+	}
+}
+
+func TestSimulatorScript(t *testing.T) {
+	sim, err := serialutils.ParseSimulatorScript([]byte(`{
+		"initialPorts": ["/dev/ttyACM0", "/dev/ttyUSB0"],
+		"steps": [
+			{ "at": "0s", "ports": ["/dev/ttyUSB0"] },
+			{ "at": "1s", "error": "enumeration failed" },
+			{ "at": "1500ms", "ports": ["/dev/ttyUSB0"] },
+			{ "at": "2s", "ports": ["/dev/ttyUSB0", "/dev/ttyACM1"] }
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.Scenario != serialutils.ScenarioScripted || len(sim.Steps) != 4 || sim.Steps[1].Err == nil || sim.Steps[2].At != 1500*time.Millisecond {
+		t.Fatalf("wrong simulator parsed: %+v", sim)
+	}
+	res, err := serialutils.ResetWithOptions("/dev/ttyACM0", &serialutils.ResetOptions{Wait: true, Simulator: sim})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Target.Path != "/dev/ttyACM1" {
+		t.Fatalf("found %q, want /dev/ttyACM1", res.Target.Path)
+	}
+}
+
+func TestSimulatorScriptTouchError(t *testing.T) {
+	sim, err := serialutils.ParseSimulatorScript([]byte(`{"touchError": "port busy"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := serialutils.ResetWithOptions("/dev/ttyACM0", &serialutils.ResetOptions{Simulator: sim}); err == nil || !strings.Contains(err.Error(), "port busy") {
+		t.Fatalf("expected the touch error, got %v", err)
+	}
+}
+
+func TestParseSimulatorScriptErrors(t *testing.T) {
+	for script, expected := range map[string]string{
+		`{"steps": [`:                               "decoding simulator script",
+		`{"initialPorts": "/dev/ttyACM0"}`:          "decoding simulator script",
+		`{"steps": [{"at": "1s"}, {"at": "soon"}]}`: "simulator script step 1: invalid time",
+	} {
+		if _, err := serialutils.ParseSimulatorScript([]byte(script)); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: got error %v, want %q", script, err, expected)
+		}
+	}
+	if _, err := serialutils.LoadSimulatorScript(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Stabilization defines how the port list is checked for stability once new
+// ports are detected during the wait. Some boards have a glitch in the
+// bootloader: the USB serial port appears and disappears rapidly before
+// settling, so the list is re-sampled until it stays unchanged for a number
+// of consecutive samples.
+type Stabilization struct {
+	// Interval is the time between two samples.
+	Interval time.Duration
+	// Samples is the number of consecutive identical samples required to
+	// consider the port list stable.
+	Samples int
+	// MinDelay is the minimum time spent in the stabilization. On macOS, if
+	// the port is opened too quickly after it is detected, a "Resource busy"
+	// error occurs: this delay works around it (on all platforms).
+	MinDelay time.Duration
+	// MaxDelay is the maximum time spent in the stabilization, when it expires
+	// the last sample is used even if the list is not stable yet.
+	MaxDelay time.Duration
+}
+
+// DefaultStabilization is the Stabilization used if none is specified.
+var DefaultStabilization = Stabilization{
+	Interval: 250 * time.Millisecond,
+	Samples:  3,
+	MinDelay: 500 * time.Millisecond,
+	MaxDelay: 3 * time.Second,
+}
+
+// stabilizationResult is the outcome of the stabilization.
+type stabilizationResult struct {
+	// ports is the last sample of the port list.
+	ports map[string]bool
+	// persistent are the ports present in every sample.
+	persistent map[string]bool
+	// glitches counts, for every port, how many times it disappeared
+	// between two samples.
+	glitches map[string]int
+}
+
+// wait samples the port list until it is stable and returns the last sample,
+// along with the ports present in all the samples.
+func (s *Stabilization) wait(portsMapper PortsMapper, clock Clock, debug func(string)) (*stabilizationResult, error) {
+	start := clock.Now()
+	var last map[string]bool
+	var res *stabilizationResult
+	stableSamples := 0
+	for {
+		clock.Sleep(s.Interval)
+		sample, err := portsMapper()
+		if errors.Is(err, errTransientEnumeration) {
+			// Start counting the stable samples again
+			last = nil
+			stableSamples = 0
+			if clock.Now().Sub(start) >= s.MaxDelay && res != nil {
+				return res, nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if debug != nil {
+			debug(fmt.Sprintf("CHECK: %v", sample))
+		}
+		if res == nil {
+			res = &stabilizationResult{persistent: sample, glitches: map[string]int{}}
+		} else {
+			for port := range res.ports {
+				if !sample[port] {
+					res.glitches[port]++
+				}
+			}
+			persistent := map[string]bool{}
+			for port := range res.persistent {
+				if sample[port] {
+					persistent[port] = true
+				}
+			}
+			res.persistent = persistent
+		}
+		res.ports = sample
+		if last != nil && samePortList(last, sample) {
+			stableSamples++
+		} else {
+			stableSamples = 1
+		}
+		last = sample
+
+		elapsed := clock.Now().Sub(start)
+		if stableSamples >= s.Samples && elapsed >= s.MinDelay {
+			return res, nil
+		}
+		if elapsed >= s.MaxDelay {
+			if debug != nil {
+				debug("Port list not stable, using the last sample")
+			}
+			return res, nil
+		}
+	}
+}
+
+// samePortList returns true if the two port lists contain the same ports.
+func samePortList(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for port := range a {
+		if !b[port] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sampleMapper returns a PortsMapper returning the given samples, then the
+// last one forever. A nil sample is a transient enumeration error.
+func sampleMapper(samples ...[]string) PortsMapper {
+	i := 0
+	return func() (map[string]bool, error) {
+		sample := samples[i]
+		if i < len(samples)-1 {
+			i++
+		}
+		if sample == nil {
+			return nil, errTransientEnumeration
+		}
+		res := map[string]bool{}
+		for _, port := range sample {
+			res[port] = true
+		}
+		return res, nil
+	}
+}
+
+func TestStabilization(t *testing.T) {
+	s := &Stabilization{
+		Interval: 100 * time.Millisecond,
+		Samples:  3,
+		MinDelay: 200 * time.Millisecond,
+		MaxDelay: time.Second,
+	}
+	for _, test := range []struct {
+		name       string
+		samples    [][]string
+		elapsed    time.Duration
+		ports      map[string]bool
+		persistent map[string]bool
+		glitches   map[string]int
+	}{
+		{
+			name:       "stable",
+			samples:    [][]string{{"A"}},
+			elapsed:    300 * time.Millisecond,
+			ports:      map[string]bool{"A": true},
+			persistent: map[string]bool{"A": true},
+			glitches:   map[string]int{},
+		},
+		{
+			name:       "glitch",
+			samples:    [][]string{{"A", "B"}, {"A"}, {"A", "B"}},
+			elapsed:    500 * time.Millisecond,
+			ports:      map[string]bool{"A": true, "B": true},
+			persistent: map[string]bool{"A": true},
+			glitches:   map[string]int{"B": 1},
+		},
+		{
+			name:       "transient error",
+			samples:    [][]string{{"A"}, {"A"}, nil, {"A"}},
+			elapsed:    600 * time.Millisecond,
+			ports:      map[string]bool{"A": true},
+			persistent: map[string]bool{"A": true},
+			glitches:   map[string]int{},
+		},
+		{
+			name:       "never stable",
+			samples:    [][]string{{"A"}, {"A", "B"}, {"A"}, {"A", "B"}, {"A"}, {"A", "B"}, {"A"}, {"A", "B"}, {"A"}, {"A", "B"}},
+			elapsed:    time.Second,
+			ports:      map[string]bool{"A": true, "B": true},
+			persistent: map[string]bool{"A": true},
+			glitches:   map[string]int{"B": 4},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &simulatedClock{now: time.Now()}
+			start := clock.Now()
+			res, err := s.wait(sampleMapper(test.samples...), clock, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := clock.Now().Sub(start); elapsed != test.elapsed {
+				t.Errorf("stabilization took %v, want %v", elapsed, test.elapsed)
+			}
+			if !reflect.DeepEqual(res.ports, test.ports) {
+				t.Errorf("got last sample %v, want %v", res.ports, test.ports)
+			}
+			if !reflect.DeepEqual(res.persistent, test.persistent) {
+				t.Errorf("got persistent ports %v, want %v", res.persistent, test.persistent)
+			}
+			if !reflect.DeepEqual(res.glitches, test.glitches) {
+				t.Errorf("got glitches %v, want %v", res.glitches, test.glitches)
+			}
+		})
+	}
+}
+
+func TestResetIgnoresGlitchingPorts(t *testing.T) {
+	// The bootloader port of another board glitches while the touched board
+	// re-enumerates
+	sim := &Simulator{
+		Scenario:     ScenarioScripted,
+		InitialPorts: []string{"/dev/ttyACM0"},
+		Steps: []SimulatorStep{
+			{At: time.Second, Ports: []string{"/dev/ttyACM1", "/dev/ttyACM2"}},
+			{At: 1300 * time.Millisecond, Ports: []string{"/dev/ttyACM1"}},
+			{At: 1600 * time.Millisecond, Ports: []string{"/dev/ttyACM1", "/dev/ttyACM2"}},
+		},
+	}
+	res, err := ResetWithOptions("/dev/ttyACM0", &ResetOptions{Wait: true, Simulator: sim})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Target.Path != "/dev/ttyACM1" {
+		t.Fatalf("found %q, want /dev/ttyACM1", res.Target.Path)
+	}
+}
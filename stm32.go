@@ -0,0 +1,87 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// STM32Resetter is a Resetter that puts an STM32 MCU in its ROM serial
+// bootloader using the "RTS→BOOT0, DTR→NRST" wiring convention of the
+// stm32flash-style adapters: BOOT0 is raised, NRST is pulsed and then BOOT0
+// is released again once the MCU has started the bootloader.
+//
+// The board does not change port after the reset, so this Resetter is
+// usually used without waiting for a new port.
+type STM32Resetter struct {
+	// InvertBOOT0 inverts the polarity of the RTS line (asserted RTS drives
+	// BOOT0 low).
+	InvertBOOT0 bool
+	// InvertNRST inverts the polarity of the DTR line (asserted DTR releases
+	// NRST).
+	InvertNRST bool
+	// ResetPulse is the duration of the NRST pulse, 100 ms if zero.
+	ResetPulse time.Duration
+	// BootDelay is the time the MCU is given to sample BOOT0 and start the
+	// bootloader before BOOT0 is released, 100 ms if zero.
+	BootDelay time.Duration
+}
+
+// Reset implements Resetter.
+func (r *STM32Resetter) Reset(port string) error {
+	boot0 := func(high bool) bool { return high != r.InvertBOOT0 }
+	nrst := func(low bool) bool { return low != r.InvertNRST }
+	resetPulse := r.ResetPulse
+	if resetPulse == 0 {
+		resetPulse = 100 * time.Millisecond
+	}
+	bootDelay := r.BootDelay
+	if bootDelay == 0 {
+		bootDelay = 100 * time.Millisecond
+	}
+
+	p, err := openPortWithTimeout(port, &serial.Mode{
+		BaudRate: 115200,
+		Parity:   serial.EvenParity,
+		InitialStatusBits: &serial.ModemOutputBits{
+			RTS: boot0(false),
+			DTR: nrst(false),
+		},
+	}, 0)
+	if err != nil {
+		return fmt.Errorf("opening port: %w", err)
+	}
+	defer p.Close()
+
+	if err := p.SetRTS(boot0(true)); err != nil {
+		return fmt.Errorf("setting BOOT0 high: %w", err)
+	}
+	if err := p.SetDTR(nrst(true)); err != nil {
+		return fmt.Errorf("asserting NRST: %w", err)
+	}
+	time.Sleep(resetPulse)
+	if err := p.SetDTR(nrst(false)); err != nil {
+		return fmt.Errorf("releasing NRST: %w", err)
+	}
+	time.Sleep(bootDelay)
+	if err := p.SetRTS(boot0(false)); err != nil {
+		return fmt.Errorf("setting BOOT0 low: %w", err)
+	}
+	return nil
+}
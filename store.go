@@ -0,0 +1,110 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PortStore remembers on which bootloader port a board, identified by its
+// USB serial number, appeared after a reset. Reset uses it as a first guess
+// when more than one new port shows up during the wait.
+type PortStore interface {
+	// BootloaderPort returns the bootloader port last recorded for the given
+	// serial number, or the empty string if unknown.
+	BootloaderPort(serialNumber string) (string, error)
+	// SetBootloaderPort records port as the bootloader port of the board with
+	// the given serial number.
+	SetBootloaderPort(serialNumber, port string) error
+}
+
+// FilePortStore is a PortStore persisted in a JSON file.
+type FilePortStore struct {
+	path string
+	mux  sync.Mutex
+}
+
+// NewFilePortStore returns a PortStore that keeps its data in the JSON file
+// at the given path. The file is created on the first write if missing.
+func NewFilePortStore(path string) *FilePortStore {
+	return &FilePortStore{path: path}
+}
+
+// BootloaderPort implements PortStore.
+func (s *FilePortStore) BootloaderPort(serialNumber string) (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return data[serialNumber], nil
+}
+
+// SetBootloaderPort implements PortStore.
+func (s *FilePortStore) SetBootloaderPort(serialNumber, port string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	if data[serialNumber] == port {
+		return nil
+	}
+	data[serialNumber] = port
+	return s.save(data)
+}
+
+func (s *FilePortStore) load() (map[string]string, error) {
+	data := map[string]string{}
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading port store: %w", err)
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("decoding port store: %w", err)
+	}
+	return data, nil
+}
+
+func (s *FilePortStore) save(data map[string]string) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding port store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("writing port store: %w", err)
+	}
+	// Write to a temporary file first so that a crash can not leave a
+	// truncated store behind.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("writing port store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing port store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"time"
+)
+
+// StressOptions contains the parameters of StressReset.
+type StressOptions struct {
+	// Reset are the options of the resets, the Wait option is enabled if
+	// Reset is nil.
+	Reset *ResetOptions
+	// Recover, if not nil, is called after every cycle with its result, to
+	// bring the board back to the application (for example uploading a
+	// sketch), before waiting for the port to come back.
+	Recover func(res *ResetResult) error
+	// ReturnTimeout is the maximum time to wait for the port to come back
+	// after a cycle, 30 seconds if zero.
+	ReturnTimeout time.Duration
+	// Pause is the time waited after the port came back, before the next
+	// cycle.
+	Pause time.Duration
+}
+
+// StressCycle is the outcome of a single reset+wait cycle of StressReset.
+type StressCycle struct {
+	Target   ResetTarget   `json:"target"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// StressReport is the result of StressReset.
+type StressReport struct {
+	Cycles    []StressCycle `json:"cycles"`
+	Successes int           `json:"successes"`
+	Failures  int           `json:"failures"`
+	// SuccessRate is the ratio of the successful cycles, from 0 to 1.
+	SuccessRate float64 `json:"successRate"`
+	// Duration are the statistics of the duration of the successful cycles.
+	Duration LatencyStats `json:"duration"`
+}
+
+// StressReset performs n reset+wait cycles on the port and reports the
+// success rate and the distribution of the durations, so that the board
+// vendors can validate their bootloaders against this package. A cycle is
+// successful if the reset succeeds and, when waiting, a bootloader target
+// is found. After every cycle it waits for the port to come back (see
+// StressOptions.Recover): if it doesn't the test stops, returning the
+// report of the cycles completed along with the error.
+func StressReset(port string, n int, opts *StressOptions) (*StressReport, error) {
+	if opts == nil {
+		opts = &StressOptions{}
+	}
+	resetOpts := opts.Reset
+	if resetOpts == nil {
+		resetOpts = &ResetOptions{Wait: true}
+	}
+	clock := resetOpts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	portsMapper := resetOpts.PortsMapper
+	if portsMapper == nil {
+		portsMapper = DefaultPortMapper
+	}
+	returnTimeout := opts.ReturnTimeout
+	if returnTimeout == 0 {
+		returnTimeout = 30 * time.Second
+	}
+	port = NormalizePortName(port)
+
+	report := &StressReport{}
+	durations := []time.Duration{}
+	for i := 0; i < n; i++ {
+		start := clock.Now()
+		res, err := ResetWithOptions(port, resetOpts)
+		cycle := StressCycle{Duration: clock.Now().Sub(start)}
+		if res != nil {
+			cycle.Target = res.Target
+		}
+		if err == nil && resetOpts.Wait && cycle.Target.Kind == NoTarget {
+			err = fmt.Errorf("no bootloader target found")
+		}
+		if err != nil {
+			cycle.Error = err.Error()
+			report.Failures++
+		} else {
+			report.Successes++
+			durations = append(durations, cycle.Duration)
+		}
+		report.Cycles = append(report.Cycles, cycle)
+		report.SuccessRate = float64(report.Successes) / float64(len(report.Cycles))
+		report.Duration = newLatencyStats(durations)
+		if i == n-1 {
+			break
+		}
+
+		if opts.Recover != nil && res != nil {
+			if err := opts.Recover(res); err != nil {
+				return report, fmt.Errorf("recovering the board after cycle %d: %w", i+1, err)
+			}
+		}
+		back, err := waitForPorts(portsMapper, clock, returnTimeout, 100*time.Millisecond, func(ports map[string]bool) bool { return ports[port] })
+		if err != nil {
+			return report, err
+		}
+		if !back {
+			return report, fmt.Errorf("port %s not back after cycle %d", port, i+1)
+		}
+		clock.Sleep(opts.Pause)
+	}
+	return report, nil
+}
@@ -0,0 +1,112 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "fmt"
+
+// TargetKind is the kind of bootloader target found after a reset.
+type TargetKind int
+
+const (
+	// NoTarget means that no bootloader target has been found (or waited for).
+	NoTarget TargetKind = iota
+	// SerialPort means that the bootloader exposes a serial port.
+	SerialPort
+	// MassStorageVolume means that the bootloader exposes a removable volume (UF2).
+	MassStorageVolume
+	// DFUDevice means that the bootloader is a USB DFU device.
+	DFUDevice
+	// HIDDevice means that the bootloader is a USB HID device.
+	HIDDevice
+)
+
+func (k TargetKind) String() string {
+	switch k {
+	case NoTarget:
+		return "none"
+	case SerialPort:
+		return "serial-port"
+	case MassStorageVolume:
+		return "mass-storage-volume"
+	case DFUDevice:
+		return "dfu-device"
+	case HIDDevice:
+		return "hid-device"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k TargetKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *TargetKind) UnmarshalText(text []byte) error {
+	for _, kind := range []TargetKind{NoTarget, SerialPort, MassStorageVolume, DFUDevice, HIDDevice} {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid target kind: %s", text)
+}
+
+// ResetTarget is the bootloader target found after a reset.
+type ResetTarget struct {
+	Kind TargetKind `json:"kind"`
+	// Path is the port name, the volume mount path or the "bus:address" of
+	// the USB device, depending on Kind. It is the empty string if Kind is
+	// NoTarget.
+	Path string `json:"path,omitempty"`
+	// ID is the identity of the bootloader port, if Kind is SerialPort and
+	// its details are available.
+	ID *PortID `json:"id,omitempty"`
+	// Board is the board of the bootloader port guessed from its USB
+	// VID/PID, if known (see GuessBoard).
+	Board *BoardInfo `json:"board,omitempty"`
+	// USB is the USB device of the target, if it is found by a USB
+	// discoverer (like the DFUDiscoverer or the HIDDiscoverer).
+	USB *USBDevice `json:"usb,omitempty"`
+}
+
+// ResetResult is the result of a ResetWithOptions call.
+type ResetResult struct {
+	// Target is the bootloader target found after the reset.
+	Target ResetTarget `json:"target"`
+	// Profile is the name of the reset profile used, set by AutoReset.
+	Profile string `json:"profile,omitempty"`
+	// Skipped tells that the reset has been skipped because the board
+	// doesn't need it (see ResetOptions.SkipReset): the port has not been
+	// touched and the board is programmed on the same port.
+	Skipped bool `json:"skipped,omitempty"`
+	// Bootloader is the outcome of the probe of the bootloader port, set
+	// only if ResetOptions.VerifyBootloader is enabled.
+	Bootloader *BootloaderInfo `json:"bootloader,omitempty"`
+	// MonitorLease is the port released by the Monitor using it, if
+	// ResetOptions.Monitors is set and a bootloader target has been found:
+	// its Done method must be called when the monitor may reconnect.
+	MonitorLease *MonitorLease `json:"-"`
+}
+
+// newResetResult returns a ResetResult for a target of the given kind.
+func newResetResult(kind TargetKind, path string) *ResetResult {
+	if path == "" {
+		kind = NoTarget
+	}
+	return &ResetResult{Target: ResetTarget{Kind: kind, Path: path}}
+}
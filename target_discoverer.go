@@ -0,0 +1,109 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "fmt"
+
+// TargetDiscoverer detects the bootloader targets of a kind other than the
+// serial ports, like the removable volumes of the UF2 bootloaders or the
+// USB HID and DFU bootloaders. The wait after a reset takes a snapshot of
+// the targets before touching the port and reports the first target that
+// appears afterwards.
+type TargetDiscoverer interface {
+	// Kind returns the kind of the targets detected.
+	Kind() TargetKind
+	// Targets returns the targets currently present, identified by their
+	// path (the ResetTarget.Path reported when one is found).
+	Targets() (map[string]bool, error)
+}
+
+// VolumeDiscoverer returns a TargetDiscoverer of the removable volumes
+// listed by the given mapper (the DefaultVolumesMapper if nil). If
+// requireUF2 is true only the UF2 bootloader drives are reported.
+func VolumeDiscoverer(volumesMapper VolumesMapper, requireUF2 bool) TargetDiscoverer {
+	if volumesMapper == nil {
+		volumesMapper = DefaultVolumesMapper
+	}
+	return &volumeDiscoverer{volumesMapper: volumesMapper, requireUF2: requireUF2}
+}
+
+type volumeDiscoverer struct {
+	volumesMapper VolumesMapper
+	requireUF2    bool
+}
+
+func (d *volumeDiscoverer) Kind() TargetKind {
+	return MassStorageVolume
+}
+
+func (d *volumeDiscoverer) Targets() (map[string]bool, error) {
+	volumes, err := d.volumesMapper()
+	if err != nil || !d.requireUF2 {
+		return volumes, err
+	}
+	res := map[string]bool{}
+	for volume := range volumes {
+		if IsUF2Volume(volume) {
+			res[volume] = true
+		}
+	}
+	return res, nil
+}
+
+// targetWatch is a TargetDiscoverer along with the targets present before
+// the reset.
+type targetWatch struct {
+	discoverer TargetDiscoverer
+	last       map[string]bool
+}
+
+// watchTargets takes the snapshot of the targets of the discoverers.
+func watchTargets(discoverers []TargetDiscoverer, debug func(string)) ([]*targetWatch, error) {
+	res := []*targetWatch{}
+	for _, d := range discoverers {
+		last, err := d.Targets()
+		if err != nil {
+			return nil, err
+		}
+		if debug != nil {
+			debug(fmt.Sprintf("LAST %s: %v", d.Kind(), last))
+		}
+		res = append(res, &targetWatch{discoverer: d, last: last})
+	}
+	return res, nil
+}
+
+// findNewTarget returns the first target appeared since the snapshot, the
+// zero ResetTarget if there are none.
+func findNewTarget(watches []*targetWatch) (ResetTarget, error) {
+	for _, w := range watches {
+		now, err := w.discoverer.Targets()
+		if err != nil {
+			return ResetTarget{}, err
+		}
+		for _, target := range sortedKeys(now) {
+			if w.last[target] {
+				continue
+			}
+			res := ResetTarget{Kind: w.discoverer.Kind(), Path: target}
+			if d, ok := w.discoverer.(*usbDeviceDiscoverer); ok {
+				res.USB = d.usbDevice(target)
+			}
+			return res, nil
+		}
+	}
+	return ResetTarget{}, nil
+}
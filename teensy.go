@@ -0,0 +1,48 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// TeensyResetter is a Resetter that sends the soft-reboot request understood
+// by the Teensy USB serial firmware: opening the port at 134 bps makes the
+// board reboot into the HalfKay bootloader, the same way teensy_loader and
+// teensy_reboot do.
+//
+// HalfKay is a HID bootloader, so no new serial port appears after the reset:
+// it can be detected with the HIDDiscoverer.
+var TeensyResetter Resetter = ResetterFunc(Touch134bps)
+
+// Touch134bps open and close the serial port at 134 bps. This is used on
+// Teensy boards as a signal to reboot into the HalfKay bootloader.
+func Touch134bps(port string) error {
+	p, err := openPortWithTimeout(port, &serial.Mode{BaudRate: 134}, 0)
+	if err != nil {
+		return fmt.Errorf("opening port at 134bps: %w", err)
+	}
+	_ = p.Close()
+
+	// Give the board the time to process the request and to disconnect
+	// before going on.
+	time.Sleep(DefaultPostTouchDelay)
+
+	return nil
+}
@@ -0,0 +1,127 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// DefaultOpenTimeout is the maximum time the operations of the package wait
+// for a port to open: some wedged drivers make the open block for a long
+// time (or forever), hanging the whole operation. It can be overridden with
+// the SERIALUTILS_OPEN_TIMEOUT environment variable.
+var DefaultOpenTimeout = 5 * time.Second
+
+// ErrEnumerationTimeout is returned by the mappers obtained with
+// PortsMapperWithTimeout and DetailedPortsMapperWithTimeout if the
+// enumeration did not complete in time.
+var ErrEnumerationTimeout = errors.New("port enumeration timed out")
+
+// OpenPortContext is like OpenPort but gives up when the context is
+// canceled, returning an error wrapping the context error. If the open
+// completes afterwards the port is closed.
+func OpenPortContext(ctx context.Context, port string, mode *serial.Mode) (serial.Port, error) {
+	type openResult struct {
+		port serial.Port
+		err  error
+	}
+	done := make(chan openResult, 1)
+	go func() {
+		p, err := OpenPort(port, mode)
+		done <- openResult{p, err}
+	}()
+	select {
+	case res := <-done:
+		return res.port, res.err
+	case <-ctx.Done():
+		go func() {
+			if res := <-done; res.err == nil {
+				_ = res.port.Close()
+			}
+		}()
+		return nil, fmt.Errorf("%s: %w", port, ctx.Err())
+	}
+}
+
+// openPortWithTimeout opens the port giving up after the timeout, or the
+// DefaultOpenTimeout if zero.
+func openPortWithTimeout(port string, mode *serial.Mode, timeout time.Duration) (serial.Port, error) {
+	if timeout == 0 {
+		timeout = DefaultOpenTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return OpenPortContext(ctx, port, mode)
+}
+
+// PortsMapperWithTimeout returns a PortsMapper that fails with
+// ErrEnumerationTimeout if the mapper does not complete within the timeout,
+// so that a single dead USB bridge can't hang the callers. The enumeration
+// blocked meanwhile keeps running in background: the calls made until it
+// completes wait for it, instead of starting a new one.
+func PortsMapperWithTimeout(mapper PortsMapper, timeout time.Duration) PortsMapper {
+	return withTimeout(mapper, timeout)
+}
+
+// DetailedPortsMapperWithTimeout is like PortsMapperWithTimeout for a
+// DetailedPortsMapper.
+func DetailedPortsMapperWithTimeout(mapper DetailedPortsMapper, timeout time.Duration) DetailedPortsMapper {
+	return withTimeout(mapper, timeout)
+}
+
+// mapperCall is an enumeration in progress.
+type mapperCall[T any] struct {
+	done chan struct{}
+	res  T
+	err  error
+}
+
+func withTimeout[T any](mapper func() (T, error), timeout time.Duration) func() (T, error) {
+	var mux sync.Mutex
+	var pending *mapperCall[T]
+	return func() (T, error) {
+		mux.Lock()
+		call := pending
+		if call == nil {
+			call = &mapperCall[T]{done: make(chan struct{})}
+			pending = call
+			go func() {
+				call.res, call.err = mapper()
+				mux.Lock()
+				pending = nil
+				mux.Unlock()
+				close(call.done)
+			}()
+		}
+		mux.Unlock()
+
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		select {
+		case <-call.done:
+			return call.res, call.err
+		case <-t.C:
+			var zero T
+			return zero, ErrEnumerationTimeout
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	serialutils "github.com/arduino/go-serial-utils"
+	"go.bug.st/serial"
+)
+
+// recordingPort is a serial.Port recording the operations performed on it.
+type recordingPort struct {
+	mux sync.Mutex
+	ops []string
+}
+
+func (p *recordingPort) record(op string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.ops = append(p.ops, op)
+	return nil
+}
+
+func (p *recordingPort) operations() []string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return append([]string{}, p.ops...)
+}
+
+func (p *recordingPort) SetMode(mode *serial.Mode) error {
+	if mode.BaudRate == 1200 {
+		return p.record("mode 1200")
+	}
+	return p.record("mode")
+}
+func (p *recordingPort) Read(buff []byte) (int, error)      { return 0, nil }
+func (p *recordingPort) Write(buff []byte) (int, error)     { return len(buff), nil }
+func (p *recordingPort) Drain() error                       { return nil }
+func (p *recordingPort) ResetInputBuffer() error            { return nil }
+func (p *recordingPort) ResetOutputBuffer() error           { return nil }
+func (p *recordingPort) SetReadTimeout(time.Duration) error { return nil }
+func (p *recordingPort) Break(time.Duration) error          { return nil }
+func (p *recordingPort) Close() error                       { return p.record("close") }
+func (p *recordingPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return nil, errors.New("not supported")
+}
+func (p *recordingPort) SetDTR(dtr bool) error {
+	if dtr {
+		return p.record("dtr on")
+	}
+	return p.record("dtr off")
+}
+func (p *recordingPort) SetRTS(rts bool) error {
+	if rts {
+		return p.record("rts on")
+	}
+	return p.record("rts off")
+}
+
+// recordingClock is a Clock recording the sleeps, without sleeping.
+type recordingClock struct {
+	mux    sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *recordingClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+func (c *recordingClock) Sleep(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.now = c.now.Add(d)
+	c.sleeps = append(c.sleeps, d)
+}
+
+func TestTouchClock(t *testing.T) {
+	port := &recordingPort{}
+	serialutils.RegisterTransport("test-touch://", serialutils.TransportFunc(func(name string, mode *serial.Mode) (serial.Port, error) {
+		_ = port.SetMode(mode)
+		return port, nil
+	}))
+	defer serialutils.RegisterTransport("test-touch://", nil)
+
+	clock := &recordingClock{}
+	start := time.Now()
+	err := serialutils.Touch1200bpsWithOptions("test-touch://board", &serialutils.TouchOptions{
+		Clock: clock,
+		DTR:   serialutils.DTRDeassert,
+		RTS:   serialutils.RTSToggle,
+		DetailedPortsMapper: func() (map[string]*serialutils.PortDetails, error) {
+			return map[string]*serialutils.PortDetails{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The delays of the touch elapse on the injected clock only
+	if elapsed := time.Since(start); elapsed >= serialutils.DefaultPostTouchDelay {
+		t.Fatalf("the touch slept on the system clock for %v", elapsed)
+	}
+	if expected := []time.Duration{50 * time.Millisecond, serialutils.DefaultPostTouchDelay}; !reflect.DeepEqual(clock.sleeps, expected) {
+		t.Fatalf("slept %v, want %v", clock.sleeps, expected)
+	}
+	if ops, expected := port.operations(), []string{"mode 1200", "dtr off", "rts on", "rts off", "close"}; !reflect.DeepEqual(ops, expected) {
+		t.Fatalf("got port operations %v, want %v", ops, expected)
+	}
+}
+
+func TestTouchWCHRTS(t *testing.T) {
+	var port *recordingPort
+	serialutils.RegisterTransport("test-touch://", serialutils.TransportFunc(func(name string, mode *serial.Mode) (serial.Port, error) {
+		port = &recordingPort{}
+		_ = port.SetMode(mode)
+		return port, nil
+	}))
+	defer serialutils.RegisterTransport("test-touch://", nil)
+	mapper := func() (map[string]*serialutils.PortDetails, error) {
+		return map[string]*serialutils.PortDetails{
+			"test-touch://ch340": {Name: "test-touch://ch340", IsUSB: true, VID: "1A86", PID: "7523"},
+			"test-touch://other": {Name: "test-touch://other", IsUSB: true, VID: "2341", PID: "0043"},
+		}, nil
+	}
+
+	// RTS is deasserted for the WCH bridges only if RTSDeassertWCH is selected
+	for _, test := range []struct {
+		port     string
+		rts      serialutils.RTSMode
+		deassert bool
+	}{
+		{"test-touch://ch340", serialutils.RTSPlatformDefault, false},
+		{"test-touch://ch340", serialutils.RTSDeassertWCH, true},
+		{"test-touch://other", serialutils.RTSDeassertWCH, false},
+	} {
+		err := serialutils.Touch1200bpsWithOptions(test.port, &serialutils.TouchOptions{
+			Clock:               &recordingClock{},
+			DTR:                 serialutils.DTRDeassert,
+			RTS:                 test.rts,
+			DetailedPortsMapper: mapper,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deasserted := false
+		for _, op := range port.operations() {
+			deasserted = deasserted || op == "rts off"
+		}
+		if deasserted != test.deassert {
+			t.Errorf("%s with RTS mode %d: RTS deasserted %v, want %v", test.port, test.rts, deasserted, test.deassert)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Simulator returns a Simulator replaying the port snapshots of the trace:
+// the ports listed before the touch are the initial ports, and the
+// snapshots taken during the wait are replayed at the same times since the
+// start of the wait (the simulated touch takes no time). The enumeration
+// errors are replayed with the same messages.
+func (t *ResetTrace) Simulator() *Simulator {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	sim := &Simulator{Scenario: ScenarioScripted, InitialPorts: []string{}}
+	touched, waiting := false, false
+	var origin TraceEvent
+	for _, ev := range t.Events {
+		switch ev.Type {
+		case TraceTouch:
+			touched, origin = true, ev
+		case TraceWait:
+			waiting, origin = true, ev
+		case TraceSnapshot:
+			if !touched {
+				if ev.Error == "" {
+					sim.InitialPorts = append([]string{}, ev.Ports...)
+				}
+				continue
+			}
+			if !waiting {
+				continue
+			}
+			step := SimulatorStep{At: ev.Time - origin.Time, Ports: ev.Ports}
+			if ev.Error != "" {
+				step.Err = errors.New(ev.Error)
+			}
+			sim.Steps = append(sim.Steps, step)
+		}
+	}
+	return sim
+}
+
+// ReplayResetTrace replays the trace through the reset logic offline, using
+// the Simulator of the trace, to reproduce exactly the detection of the
+// bootloader port reported by a user. The ResetOptions (that may be nil)
+// should match the ones of the traced reset, since they affect the timing
+// of the polls and the choices: only their Simulator and Trace are
+// replaced, and the wait is enabled.
+func ReplayResetTrace(trace *ResetTrace, opts *ResetOptions) (*ResetResult, *ResetTrace, error) {
+	replay := ResetOptions{}
+	if opts != nil {
+		replay = *opts
+	}
+	replay.Wait = true
+	replay.DryRun = false
+	replay.Simulator = trace.Simulator()
+	replay.Trace = &ResetTrace{}
+	res, err := ResetWithOptions(trace.Port, &replay)
+	return res, replay.Trace, err
+}
+
+// ParseResetTrace parses a ResetTrace in JSON format.
+func ParseResetTrace(data []byte) (*ResetTrace, error) {
+	trace := &ResetTrace{}
+	if err := json.Unmarshal(data, trace); err != nil {
+		return nil, fmt.Errorf("decoding reset trace: %w", err)
+	}
+	return trace, nil
+}
+
+// LoadResetTrace reads a ResetTrace from a JSON file, as written by
+// ResetTrace.WriteFile.
+func LoadResetTrace(path string) (*ResetTrace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading reset trace: %w", err)
+	}
+	return ParseResetTrace(data)
+}
@@ -0,0 +1,53 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "context"
+
+// Tracer is the interface used to instrument the reset with tracing spans,
+// it can be easily adapted to OpenTelemetry or other tracing systems. The
+// spans created are:
+//   - "serialutils.Reset": the whole ResetWithOptions call
+//   - "serialutils.Touch": the reset of the board
+//   - "serialutils.WaitForBootloader": the wait for the bootloader
+//   - "serialutils.Poll": every poll of the port list during the wait
+//   - "serialutils.Stabilization": the wait for the port list to settle
+type Tracer interface {
+	// Start starts a span with the given name, as a child of the span in the
+	// context (if any). The returned context contains the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a tracing span created by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute of the span.
+	SetAttribute(key string, value any)
+	// End ends the span, err is the outcome of the traced operation (nil if
+	// successful).
+	End(err error)
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value any) {}
+
+func (noopSpan) End(err error) {}
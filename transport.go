@@ -0,0 +1,84 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"strings"
+	"sync"
+
+	"go.bug.st/serial"
+)
+
+// Transport knows how to open a kind of port. Every operation of the package
+// opening a port goes through the Transport registered for the port name,
+// so ports that are not native serial devices (network serial servers, mock
+// ports for testing, etc.) can be reset like any other port.
+type Transport interface {
+	// Open opens the port with the given mode.
+	Open(port string, mode *serial.Mode) (serial.Port, error)
+}
+
+// TransportFunc is an adapter to use an ordinary function as a Transport.
+type TransportFunc func(port string, mode *serial.Mode) (serial.Port, error)
+
+// Open calls f(port, mode).
+func (f TransportFunc) Open(port string, mode *serial.Mode) (serial.Port, error) {
+	return f(port, mode)
+}
+
+// SerialTransport is the Transport of the native serial ports, it is used
+// for all the port names without a registered Transport.
+var SerialTransport Transport = TransportFunc(serial.Open)
+
+var transportsMux sync.RWMutex
+var transports = map[string]Transport{
+	RFC2217Prefix: TransportFunc(openRFC2217),
+	USBHostPrefix: TransportFunc(openUSBHost),
+}
+
+// RegisterTransport registers the Transport used to open the ports whose name
+// starts with the given prefix. If t is nil the Transport is unregistered.
+func RegisterTransport(prefix string, t Transport) {
+	transportsMux.Lock()
+	defer transportsMux.Unlock()
+	if t == nil {
+		delete(transports, prefix)
+	} else {
+		transports[prefix] = t
+	}
+}
+
+// OpenPort opens a port using the Transport registered for its name, or the
+// SerialTransport if there are none. If more prefixes match, the longest wins.
+//
+// The port name is normalized with NormalizePortName before opening it.
+func OpenPort(port string, mode *serial.Mode) (serial.Port, error) {
+	port = NormalizePortName(port)
+	return transportFor(port).Open(port, mode)
+}
+
+func transportFor(port string) Transport {
+	transportsMux.RLock()
+	defer transportsMux.RUnlock()
+	res := SerialTransport
+	matched := ""
+	for prefix, t := range transports {
+		if strings.HasPrefix(port, prefix) && len(prefix) > len(matched) {
+			res, matched = t, prefix
+		}
+	}
+	return res
+}
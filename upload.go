@@ -0,0 +1,102 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"context"
+	"fmt"
+)
+
+// Upload is an upload prepared by PrepareUpload: the board is in bootloader
+// mode and ready to be programmed.
+type Upload struct {
+	// Port is the port of the board before the reset.
+	Port string
+	// Profile is the reset profile used.
+	Profile *ResetProfile
+	// Result is the outcome of the reset, with the bootloader target found.
+	Result *ResetResult
+	opts   ResetOptions
+}
+
+// UploadPort returns the port the uploader must use: the bootloader port if
+// one has been found, the port of the board otherwise (for the boards that
+// are programmed on the same port, or if the bootloader port did not
+// appear).
+func (u *Upload) UploadPort() string {
+	if u.Result.Target.Kind == SerialPort {
+		return u.Result.Target.Path
+	}
+	return u.Port
+}
+
+// PrepareUpload prepares the board on the port for an upload, see
+// PrepareUploadWithOptions.
+func PrepareUpload(port string, profile *ResetProfile) (*Upload, error) {
+	return PrepareUploadWithOptions(port, profile, nil)
+}
+
+// PrepareUploadWithOptions prepares the board on the port for an upload:
+// the serial monitor using the port, if registered in opts.Monitors, is
+// asked to release it, the board is reset as described by the profile and
+// the bootloader target is waited for. If profile is nil, the profile
+// matching the port is looked up in the registry, like AutoReset does. The
+// settings of the profile override the corresponding ResetOptions (see
+// ResetProfile.Apply).
+//
+// Once the upload is completed, FinishUpload must be called to recover the
+// sketch port and signal the monitor to reconnect.
+func PrepareUploadWithOptions(port string, profile *ResetProfile, opts *ResetOptions) (*Upload, error) {
+	var resetOpts ResetOptions
+	if opts != nil {
+		resetOpts = *opts
+	}
+	if profile == nil {
+		profile = FindResetProfileForPort(resetOpts.lookupPortDetails(port))
+	}
+	if profile == nil {
+		profile = DefaultResetProfile
+	}
+	profile.Apply(&resetOpts)
+	resetOpts.holdMonitorLease = true
+	res, err := ResetWithOptions(port, &resetOpts)
+	if err != nil {
+		return nil, fmt.Errorf("resetting board: %w", err)
+	}
+	res.Profile = profile.Name
+	return &Upload{Port: port, Profile: profile, Result: res, opts: resetOpts}, nil
+}
+
+// FinishUpload waits for the sketch port to come back after the upload (see
+// ReattachAfterReset), signals the serial monitor released by PrepareUpload
+// to reconnect to it and returns it. The empty string is returned if the
+// sketch port did not appear, in that case the monitor is signaled to
+// reconnect to the original port.
+func FinishUpload(upload *Upload) (string, error) {
+	opts := &ReattachOptions{
+		DetailedPortsMapper: upload.opts.DetailedPortsMapper,
+		Clock:               upload.opts.Clock,
+	}
+	if cb := upload.opts.Callbacks; cb != nil {
+		opts.Debug = cb.Debug
+	}
+	port, err := upload.Port, error(nil)
+	if upload.opts.Simulator == nil && !upload.opts.DryRun {
+		port, err = waitForSketchPort(context.Background(), upload.Port, bootloaderPortID(upload.Result), opts)
+	}
+	upload.Result.MonitorLease.Done(port)
+	return port, err
+}
@@ -0,0 +1,123 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// USBDevice is a USB device, as listed by ListUSBDevices.
+type USBDevice struct {
+	// Bus and Address are the USB bus number and the device address on the
+	// bus. The address changes every time the device is enumerated.
+	Bus     int    `json:"bus"`
+	Address int    `json:"address"`
+	VID     string `json:"vid"`
+	PID     string `json:"pid"`
+	// SerialNumber and Product are the USB string descriptors of the device,
+	// empty if not available.
+	SerialNumber string `json:"serialNumber,omitempty"`
+	Product      string `json:"product,omitempty"`
+	// Location is the physical USB location of the device, in the Linux
+	// sysfs format (e.g. "1-2.3").
+	Location string `json:"location,omitempty"`
+	// Interfaces are the interfaces of the active configuration.
+	Interfaces []USBInterface `json:"interfaces,omitempty"`
+}
+
+// USBInterface is the class of an interface of a USB device.
+type USBInterface struct {
+	Class    uint8 `json:"class"`
+	SubClass uint8 `json:"subClass"`
+	Protocol uint8 `json:"protocol"`
+}
+
+// Path returns the "bus:address" path identifying the device, used as path
+// of the targets found by the USB discoverers.
+func (d *USBDevice) Path() string {
+	return fmt.Sprintf("%d:%d", d.Bus, d.Address)
+}
+
+// USBID returns the USB VID/PID of the device.
+func (d *USBDevice) USBID() USBID {
+	return USBID{VID: d.VID, PID: d.PID}
+}
+
+// HasInterface returns true if the device has an interface of the given
+// class and subclass.
+func (d *USBDevice) HasInterface(class, subClass uint8) bool {
+	for _, intf := range d.Interfaces {
+		if intf.Class == class && intf.SubClass == subClass {
+			return true
+		}
+	}
+	return false
+}
+
+// ListUSBDevices returns the USB devices connected to the host. It is
+// supported only on Linux.
+func ListUSBDevices() ([]*USBDevice, error) {
+	devices, err := nativeListUSBDevices()
+	if err != nil {
+		return nil, fmt.Errorf("listing USB devices: %w", err)
+	}
+	for _, d := range devices {
+		d.VID = strings.ToUpper(d.VID)
+		d.PID = strings.ToUpper(d.PID)
+	}
+	return devices, nil
+}
+
+// usbDeviceDiscoverer is a TargetDiscoverer of the USB devices accepted by
+// the match function, identified by their path.
+type usbDeviceDiscoverer struct {
+	kind  TargetKind
+	match func(*USBDevice) bool
+	// devices are the devices found by the last listing
+	mux     sync.Mutex
+	devices map[string]*USBDevice
+}
+
+func (d *usbDeviceDiscoverer) Kind() TargetKind {
+	return d.kind
+}
+
+func (d *usbDeviceDiscoverer) Targets() (map[string]bool, error) {
+	list, err := ListUSBDevices()
+	if err != nil {
+		return nil, err
+	}
+	devices := map[string]*USBDevice{}
+	res := map[string]bool{}
+	for _, dev := range list {
+		if d.match(dev) {
+			devices[dev.Path()] = dev
+			res[dev.Path()] = true
+		}
+	}
+	d.mux.Lock()
+	d.devices = devices
+	d.mux.Unlock()
+	return res, nil
+}
+
+func (d *usbDeviceDiscoverer) usbDevice(path string) *USBDevice {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.devices[path]
+}
@@ -0,0 +1,85 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// usbDevicesSupported tells if ListUSBDevices is supported.
+const usbDevicesSupported = true
+
+// nativeListUSBDevices lists the USB devices from sysfs.
+func nativeListUSBDevices() ([]*USBDevice, error) {
+	entries, err := os.ReadDir("/sys/bus/usb/devices")
+	if os.IsNotExist(err) {
+		// No USB bus (e.g. in a container)
+		return []*USBDevice{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := []*USBDevice{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !sysfsUSBDeviceRegexp.MatchString(name) {
+			continue
+		}
+		dir := filepath.Join("/sys/bus/usb/devices", name)
+		read := func(dir, name string) string {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return ""
+			}
+			return strings.TrimSpace(string(data))
+		}
+		bus, err := strconv.Atoi(read(dir, "busnum"))
+		if err != nil {
+			continue
+		}
+		address, err := strconv.Atoi(read(dir, "devnum"))
+		if err != nil {
+			continue
+		}
+		dev := &USBDevice{
+			Bus:          bus,
+			Address:      address,
+			VID:          read(dir, "idVendor"),
+			PID:          read(dir, "idProduct"),
+			SerialNumber: read(dir, "serial"),
+			Product:      read(dir, "product"),
+			Location:     name,
+		}
+		// The interfaces are the "<device>:<config>.<interface>" entries
+		intfs, _ := filepath.Glob(filepath.Join(dir, name+":*"))
+		for _, intf := range intfs {
+			hex := func(attr string) uint8 {
+				v, _ := strconv.ParseUint(read(intf, attr), 16, 8)
+				return uint8(v)
+			}
+			dev.Interfaces = append(dev.Interfaces, USBInterface{
+				Class:    hex("bInterfaceClass"),
+				SubClass: hex("bInterfaceSubClass"),
+				Protocol: hex("bInterfaceProtocol"),
+			})
+		}
+		res = append(res, dev)
+	}
+	return res, nil
+}
@@ -0,0 +1,34 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import "strings"
+
+// usbLocation returns the physical location of the USB device of the port,
+// in the Linux sysfs format ("<bus>-<port>.<port>...", e.g. "1-2.3"), or
+// the empty string if unknown.
+func usbLocation(port string) string {
+	return nativeUSBLocation(port)
+}
+
+// usbHubPath returns the location of the hub a USB device is connected to,
+// for example "1-2" for "1-2.3".
+func usbHubPath(location string) string {
+	if i := strings.LastIndexAny(location, ".-"); i != -1 {
+		return location[:i]
+	}
+	return ""
+}
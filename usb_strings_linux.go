@@ -0,0 +1,56 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var sysfsUSBInterfaceRegexp = regexp.MustCompile(`^[0-9]+-[0-9.]+:[0-9]+\.[0-9]+$`)
+
+// nativeUSBDescriptors fills the manufacturer and the interface name and
+// number of the USB port from sysfs.
+func nativeUSBDescriptors(port *PortDetails) {
+	device, err := filepath.EvalSymlinks(filepath.Join("/sys/class/tty", filepath.Base(port.Name), "device"))
+	if err != nil {
+		return
+	}
+	read := func(dir, name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	// Walk up from the USB interface to the USB device
+	for dir := device; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		base := filepath.Base(dir)
+		if sysfsUSBInterfaceRegexp.MatchString(base) && port.InterfaceNumber == "" {
+			port.InterfaceName = read(dir, "interface")
+			port.InterfaceNumber = strings.ToUpper(read(dir, "bInterfaceNumber"))
+		}
+		if sysfsUSBDeviceRegexp.MatchString(base) {
+			port.Manufacturer = read(dir, "manufacturer")
+			if port.Product == "" {
+				port.Product = read(dir, "product")
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"sync"
+)
+
+// USBHostPrefix is the prefix of the names of the ports registered with
+// RegisterUSBHostDevice, e.g. "usbhost://arduino".
+const USBHostPrefix = "usbhost://"
+
+// USBHostDevice is a USB CDC-ACM device accessed through a file descriptor
+// of the USB host API, for the environments where the /dev serial devices
+// are not accessible (Android apps using UsbDeviceConnection.getFileDescriptor(),
+// Termux using termux-usb, etc.). The CDC-ACM protocol is driven directly
+// through the usbfs interface of the file descriptor.
+type USBHostDevice struct {
+	// FD is the file descriptor of the USB device. It is owned by the caller
+	// and it's never closed by this package.
+	FD int
+	// Interface is the number of the CDC communication (control) interface.
+	Interface uint16
+	// BulkIn and BulkOut are the addresses of the data endpoints, they are
+	// needed only to read and write data (not for the reset).
+	BulkIn  uint8
+	BulkOut uint8
+	// Details are the details of the port reported by the ports mappers.
+	Details PortDetails
+}
+
+var usbHostDevicesMux sync.Mutex
+var usbHostDevices = map[string]*USBHostDevice{}
+
+// RegisterUSBHostDevice makes the device available as a port, with the given
+// name, to the USBHostPortsMapper and to all the functions opening ports. The
+// full port name (USBHostPrefix+name) is returned.
+func RegisterUSBHostDevice(name string, dev *USBHostDevice) string {
+	usbHostDevicesMux.Lock()
+	defer usbHostDevicesMux.Unlock()
+	port := USBHostPrefix + name
+	usbHostDevices[port] = dev
+	return port
+}
+
+// UnregisterUSBHostDevice removes a device registered with RegisterUSBHostDevice.
+func UnregisterUSBHostDevice(name string) {
+	usbHostDevicesMux.Lock()
+	defer usbHostDevicesMux.Unlock()
+	delete(usbHostDevices, USBHostPrefix+name)
+}
+
+// USBHostPortsMapper is a PortsMapper listing the registered USB host devices.
+func USBHostPortsMapper() (map[string]bool, error) {
+	return PortsMapperFromDetailed(USBHostDetailedPortsMapper)()
+}
+
+// USBHostDetailedPortsMapper is a DetailedPortsMapper listing the registered
+// USB host devices.
+func USBHostDetailedPortsMapper() (map[string]*PortDetails, error) {
+	usbHostDevicesMux.Lock()
+	defer usbHostDevicesMux.Unlock()
+	res := map[string]*PortDetails{}
+	for port, dev := range usbHostDevices {
+		details := dev.Details
+		details.Name = port
+		details.IsUSB = true
+		res[port] = &details
+	}
+	return res, nil
+}
+
+func usbHostDevice(port string) *USBHostDevice {
+	usbHostDevicesMux.Lock()
+	defer usbHostDevicesMux.Unlock()
+	return usbHostDevices[port]
+}
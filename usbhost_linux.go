@@ -0,0 +1,219 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"go.bug.st/serial"
+	"golang.org/x/sys/unix"
+)
+
+// The structures below mirror the usbfs ABI defined in linux/usbdevice_fs.h.
+
+type usbdevfsCtrlTransfer struct {
+	RequestType uint8
+	Request     uint8
+	Value       uint16
+	Index       uint16
+	Length      uint16
+	Timeout     uint32
+	Data        unsafe.Pointer
+}
+
+type usbdevfsBulkTransfer struct {
+	Endpoint uint32
+	Length   uint32
+	Timeout  uint32
+	Data     unsafe.Pointer
+}
+
+// usbdevfsIOWR computes the _IOWR('U', nr, size) ioctl request number.
+func usbdevfsIOWR(nr uintptr, size uintptr) uintptr {
+	return 3<<30 | size<<16 | 'U'<<8 | nr
+}
+
+var usbdevfsControl = usbdevfsIOWR(0, unsafe.Sizeof(usbdevfsCtrlTransfer{}))
+var usbdevfsBulk = usbdevfsIOWR(2, unsafe.Sizeof(usbdevfsBulkTransfer{}))
+
+// CDC-ACM class requests
+const (
+	cdcSetLineCoding       = 0x20
+	cdcSetControlLineState = 0x22
+	cdcSendBreak           = 0x23
+	cdcRequestType         = 0x21 // host-to-device, class, interface
+)
+
+// usbHostPort is a serial.Port driving a CDC-ACM device through usbfs.
+type usbHostPort struct {
+	dev         *USBHostDevice
+	dtr, rts    bool
+	readTimeout time.Duration
+}
+
+func openUSBHost(port string, mode *serial.Mode) (serial.Port, error) {
+	dev := usbHostDevice(port)
+	if dev == nil {
+		return nil, fmt.Errorf("USB host device %s not registered", port)
+	}
+	p := &usbHostPort{dev: dev, dtr: true, rts: true, readTimeout: serial.NoTimeout}
+	if mode != nil && mode.InitialStatusBits != nil {
+		p.dtr, p.rts = mode.InitialStatusBits.DTR, mode.InitialStatusBits.RTS
+	}
+	if err := p.SetMode(mode); err != nil {
+		return nil, err
+	}
+	if err := p.setControlLines(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *usbHostPort) control(request uint8, value uint16, data []byte) error {
+	xfer := usbdevfsCtrlTransfer{
+		RequestType: cdcRequestType,
+		Request:     request,
+		Value:       value,
+		Index:       p.dev.Interface,
+		Length:      uint16(len(data)),
+		Timeout:     1000,
+	}
+	if len(data) > 0 {
+		xfer.Data = unsafe.Pointer(&data[0])
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(p.dev.FD), usbdevfsControl, uintptr(unsafe.Pointer(&xfer))); errno != 0 {
+		return fmt.Errorf("USB control transfer: %w", errno)
+	}
+	return nil
+}
+
+func (p *usbHostPort) bulk(endpoint uint8, data []byte, timeout time.Duration) (int, error) {
+	if endpoint == 0 {
+		return 0, errors.New("USB bulk endpoint not configured")
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	ms := uint32(0) // no timeout
+	if timeout >= 0 {
+		ms = uint32(timeout.Milliseconds())
+		if ms == 0 {
+			ms = 1
+		}
+	}
+	xfer := usbdevfsBulkTransfer{
+		Endpoint: uint32(endpoint),
+		Length:   uint32(len(data)),
+		Timeout:  ms,
+		Data:     unsafe.Pointer(&data[0]),
+	}
+	n, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(p.dev.FD), usbdevfsBulk, uintptr(unsafe.Pointer(&xfer)))
+	if errno == unix.ETIMEDOUT {
+		return 0, nil
+	}
+	if errno != 0 {
+		return 0, fmt.Errorf("USB bulk transfer: %w", errno)
+	}
+	return int(n), nil
+}
+
+func (p *usbHostPort) SetMode(mode *serial.Mode) error {
+	if mode == nil {
+		return nil
+	}
+	// Line coding: baud rate, stop bits (0=1, 1=1.5, 2=2), parity, data bits
+	coding := make([]byte, 7)
+	binary.LittleEndian.PutUint32(coding, uint32(mode.BaudRate))
+	switch mode.StopBits {
+	case serial.OnePointFiveStopBits:
+		coding[4] = 1
+	case serial.TwoStopBits:
+		coding[4] = 2
+	}
+	coding[5] = byte(mode.Parity)
+	coding[6] = byte(mode.DataBits)
+	if coding[6] == 0 {
+		coding[6] = 8
+	}
+	return p.control(cdcSetLineCoding, 0, coding)
+}
+
+func (p *usbHostPort) setControlLines() error {
+	value := uint16(0)
+	if p.dtr {
+		value |= 1
+	}
+	if p.rts {
+		value |= 2
+	}
+	return p.control(cdcSetControlLineState, value, nil)
+}
+
+func (p *usbHostPort) SetDTR(dtr bool) error {
+	p.dtr = dtr
+	return p.setControlLines()
+}
+
+func (p *usbHostPort) SetRTS(rts bool) error {
+	p.rts = rts
+	return p.setControlLines()
+}
+
+func (p *usbHostPort) Read(buff []byte) (int, error) {
+	return p.bulk(p.dev.BulkIn|0x80, buff, p.readTimeout)
+}
+
+func (p *usbHostPort) Write(buff []byte) (int, error) {
+	return p.bulk(p.dev.BulkOut&0x7F, buff, serial.NoTimeout)
+}
+
+func (p *usbHostPort) Drain() error {
+	return nil
+}
+
+func (p *usbHostPort) ResetInputBuffer() error {
+	return nil
+}
+
+func (p *usbHostPort) ResetOutputBuffer() error {
+	return nil
+}
+
+func (p *usbHostPort) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return nil, errors.New("modem status bits not supported on USB host devices")
+}
+
+func (p *usbHostPort) SetReadTimeout(t time.Duration) error {
+	p.readTimeout = t
+	return nil
+}
+
+func (p *usbHostPort) Close() error {
+	// The file descriptor is owned by the caller of RegisterUSBHostDevice
+	return nil
+}
+
+func (p *usbHostPort) Break(d time.Duration) error {
+	ms := d.Milliseconds()
+	if ms > 0xFFFE {
+		ms = 0xFFFE
+	}
+	return p.control(cdcSendBreak, uint16(ms), nil)
+}
@@ -0,0 +1,105 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// USBID is a USB VID/PID pair, in the hexadecimal format used in PortDetails
+// (e.g. "2341"). The comparison is case-insensitive.
+type USBID struct {
+	VID string
+	PID string
+}
+
+// ParseUSBID parses a USB ID in the "VID:PID" format (e.g. "2341:8036").
+func ParseUSBID(s string) (USBID, error) {
+	vid, pid, ok := strings.Cut(s, ":")
+	if !ok || !isHexID(vid) || !isHexID(pid) {
+		return USBID{}, fmt.Errorf("invalid USB ID: %s", s)
+	}
+	return USBID{VID: strings.ToUpper(vid), PID: strings.ToUpper(pid)}, nil
+}
+
+func isHexID(s string) bool {
+	if len(s) != 4 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the USB ID in the "VID:PID" format.
+func (id USBID) String() string {
+	return id.VID + ":" + id.PID
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (id USBID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (id *USBID) UnmarshalText(text []byte) error {
+	res, err := ParseUSBID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = res
+	return nil
+}
+
+// Matches returns true if the port has the VID/PID of the USBID.
+func (id USBID) Matches(port *PortDetails) bool {
+	return port != nil && port.IsUSB &&
+		strings.EqualFold(id.VID, port.VID) &&
+		strings.EqualFold(id.PID, port.PID)
+}
+
+// MatchesAny returns true if the port has the VID/PID of any of the USBIDs.
+func MatchesAny(ids []USBID, port *PortDetails) bool {
+	for _, id := range ids {
+		if id.Matches(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownBootloaderIDs is a list of USB IDs used by the bootloaders of some
+// common Arduino boards with native USB.
+var KnownBootloaderIDs = []USBID{
+	{"2341", "0036"}, // Leonardo
+	{"2341", "0037"}, // Micro
+	{"2341", "0041"}, // Yún
+	{"2341", "004D"}, // Zero
+	{"2341", "004E"}, // MKR1000
+	{"2341", "004F"}, // MKR Zero
+	{"2341", "0050"}, // MKR FOX 1200
+	{"2341", "0052"}, // MKR GSM 1400
+	{"2341", "0053"}, // MKR WAN 1300
+	{"2341", "0054"}, // MKR WiFi 1010
+	{"2341", "0055"}, // MKR NB 1500
+	{"2341", "0057"}, // Nano 33 IoT
+	{"2341", "005A"}, // Nano 33 BLE
+	{"2341", "035B"}, // Portenta H7
+}
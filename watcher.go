@@ -0,0 +1,185 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// PortEventType is the type of a PortEvent.
+type PortEventType int
+
+const (
+	// PortAdded is sent when a new port appears.
+	PortAdded PortEventType = iota
+	// PortRemoved is sent when a port disappears.
+	PortRemoved
+	// PortsError is sent when the port enumeration fails.
+	PortsError
+)
+
+func (t PortEventType) String() string {
+	switch t {
+	case PortAdded:
+		return "add"
+	case PortRemoved:
+		return "remove"
+	case PortsError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t PortEventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *PortEventType) UnmarshalText(text []byte) error {
+	for _, eventType := range []PortEventType{PortAdded, PortRemoved, PortsError} {
+		if eventType.String() == string(text) {
+			*t = eventType
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid port event type: %s", text)
+}
+
+// PortEvent is a change in the list of available ports, reported by a
+// PortWatcher.
+type PortEvent struct {
+	Type PortEventType `json:"type"`
+	// Port is the port added or removed (nil for PortsError events).
+	Port *PortDetails `json:"port,omitempty"`
+	// Board is the board of the port guessed from its USB VID/PID, if known
+	// (see GuessBoard).
+	Board *BoardInfo `json:"board,omitempty"`
+	// Err is the enumeration error (only for PortsError events), it is
+	// marshaled in JSON as the "error" message string.
+	Err error `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e PortEvent) MarshalJSON() ([]byte, error) {
+	type event PortEvent
+	res := struct {
+		event
+		Error string `json:"error,omitempty"`
+	}{event: event(e)}
+	if e.Err != nil {
+		res.Error = e.Err.Error()
+	}
+	return json.Marshal(res)
+}
+
+// PortWatcher polls the available ports and reports the changes.
+type PortWatcher struct {
+	poller *portPoller
+}
+
+// portPoller is the polling loop of a PortWatcher. It's separated from the
+// PortWatcher, that the polling goroutine doesn't reference, so that an
+// abandoned watcher can be garbage collected and its polling stopped.
+type portPoller struct {
+	mapper   DetailedPortsMapper
+	interval time.Duration
+	cb       func(PortEvent)
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// WatchPorts starts a PortWatcher that calls `mapper` every `interval` and
+// calls `cb` for every change. All the ports already present are reported
+// as PortAdded when the watcher starts. If `mapper` is nil the mapper of the
+// Config of the process, or the DefaultDetailedPortMapper, is used; if
+// `interval` is zero the WatchInterval of the Config is used. The ports
+// rejected by the PortFilter of the Config are not reported.
+func WatchPorts(mapper DetailedPortsMapper, interval time.Duration, cb func(PortEvent)) *PortWatcher {
+	cfg := CurrentConfig()
+	if mapper == nil {
+		mapper = cfg.DetailedPortsMapper
+	}
+	if mapper == nil {
+		mapper = DefaultDetailedPortMapper
+	}
+	if interval == 0 {
+		interval = cfg.WatchInterval
+	}
+	if interval == 0 {
+		interval = time.Second
+	}
+	if cfg.PortFilter != nil {
+		mapper = filteredDetailedPortsMapper(mapper, cfg.PortFilter)
+	}
+	p := &portPoller{
+		mapper:   mapper,
+		interval: interval,
+		cb:       cb,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	w := &PortWatcher{poller: p}
+	// Safety net for the watchers abandoned without calling Close
+	runtime.SetFinalizer(w, func(w *PortWatcher) { w.poller.signalStop() })
+	return w
+}
+
+// Close stops the watcher and waits for the polling to terminate. No events
+// are reported after Close returns. A watcher abandoned without calling
+// Close is stopped when garbage collected, but the time that takes is
+// unpredictable: the watchers should always be closed.
+func (w *PortWatcher) Close() {
+	w.poller.signalStop()
+	<-w.poller.done
+}
+
+func (p *portPoller) signalStop() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+func (p *portPoller) run() {
+	defer close(p.done)
+	last := map[string]*PortDetails{}
+	for {
+		now, err := p.mapper()
+		if err != nil {
+			p.cb(PortEvent{Type: PortsError, Err: err})
+		} else {
+			added, removed := DiffPortDetails(last, now)
+			for _, port := range removed {
+				p.cb(PortEvent{Type: PortRemoved, Port: port, Board: GuessBoard(*port)})
+			}
+			for _, port := range added {
+				p.cb(PortEvent{Type: PortAdded, Port: port, Board: GuessBoard(*port)})
+			}
+			last = now
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(p.interval):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// This file is part of arduino-serial-utils
+//
+// Copyright 2024 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package serialutils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrWSLNoUSBDevices is returned by the default port mappers when running
+// under WSL2 with no USB serial devices attached. WSL2 doesn't see the USB
+// devices of the Windows host unless they are explicitly attached with usbipd.
+var ErrWSLNoUSBDevices = errors.New("no USB serial devices found: under WSL2 the USB devices must be attached " +
+	"to the Linux VM using usbipd-win (run `usbipd list` and `usbipd attach --wsl --busid <BUSID>` " +
+	"from a Windows terminal, see https://learn.microsoft.com/windows/wsl/connect-usb)")
+
+// WSLVersion returns the version of the Windows Subsystem for Linux the
+// program is running in (1 or 2), or 0 if not running under WSL.
+func WSLVersion() int {
+	return nativeWSLVersion()
+}
+
+// wslVersionFromKernelRelease detects WSL from the kernel release string:
+// WSL1 reports something like "4.4.0-19041-Microsoft" and WSL2 something
+// like "5.15.90.1-microsoft-standard-WSL2".
+func wslVersionFromKernelRelease(release string) int {
+	lower := strings.ToLower(release)
+	if !strings.Contains(lower, "microsoft") {
+		return 0
+	}
+	if strings.Contains(lower, "wsl2") || strings.Contains(lower, "microsoft-standard") {
+		return 2
+	}
+	return 1
+}
+
+// ignoreWSLError wraps a PortsMapper so that ErrWSLNoUSBDevices is reported
+// as an empty port list: this is needed while waiting for the bootloader,
+// when the only USB device attached may be disconnected for a while.
+func ignoreWSLError(portsMapper PortsMapper) PortsMapper {
+	return func() (map[string]bool, error) {
+		res, err := portsMapper()
+		if errors.Is(err, ErrWSLNoUSBDevices) {
+			return map[string]bool{}, nil
+		}
+		return res, err
+	}
+}
+
+// checkWSLPorts returns ErrWSLNoUSBDevices if running under WSL2 and none
+// of the given ports is an USB serial port.
+func checkWSLPorts(ports []string) error {
+	for _, port := range ports {
+		if strings.HasPrefix(port, "/dev/ttyACM") || strings.HasPrefix(port, "/dev/ttyUSB") {
+			return nil
+		}
+	}
+	if WSLVersion() == 2 {
+		return ErrWSLNoUSBDevices
+	}
+	return nil
+}